@@ -1,30 +1,63 @@
 package client
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"math/big"
+	"net/http"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// Chain types supported by the drivers built in clients package
+const (
+	ChainTypeEVM  = "evm"
+	ChainTypeTron = "tron"
+)
+
 // ChainConfiguration is the necessary config for block chain client
 // use to set up connections to RPC nodes
 type ChainConfiguration struct {
-	ChainID        *big.Int
-	ChainName      string
+	ChainID   *big.Int
+	ChainName string
+	// ChainType selects the driver creating the client, such as ChainTypeEVM, ChainTypeTron
+	// or the name of a driver registered by clients.RegisterDriver
+	// If empty, "Tron" chain uses ChainTypeTron and the others use ChainTypeEVM
+	ChainType      string
 	ChainLogo      string
 	Currency       string
 	Endpoints      []string
 	SupportEIP1559 bool
 	APIKey         string // for tron only, trongrid need a api key
+	// Timeout bounds every request sent to the endpoints, zero means no limit
+	Timeout time.Duration
+	// HealthCheckInterval is how often the endpoints are checked when more than one is configured
+	HealthCheckInterval time.Duration
+	// MaxBlockLag is how many blocks an endpoint can fall behind the highest one before it is unhealthy
+	MaxBlockLag uint64
+	// Multicall3Address is the Multicall3 contract used by EthClient.Multicall, empty means the
+	// address deployed on most evm chains, 0xcA11bde05977b3631167028862bE2a173976CA11
+	Multicall3Address string
+	// MinGasTipCap and MaxGasFeeCap bound the fees suggested by the fee tiers, nil means no limit
+	MinGasTipCap *big.Int
+	MaxGasFeeCap *big.Int
+	// Transport sends the http requests to the endpoints, nil means the default transport
+	// It's used to record and replay the traffic in tests, see replay package
+	Transport http.RoundTripper
 }
 
+// EventLog is a log emitted by a contract
+// BlockNumber, TxHash and LogIndex locate the log on chain, they're set by the clients returning them
 type EventLog struct {
-	Address string
-	Topics  [][]byte
-	Data    []byte
-	Removed bool
+	Address     string
+	Topics      [][]byte
+	Data        []byte
+	Removed     bool
+	BlockNumber uint64
+	TxHash      string
+	LogIndex    uint
 }
 
 type TxGasInfo struct {
@@ -40,6 +73,10 @@ type TransactionInfo struct {
 	Status    uint64
 	Gas       *TxGasInfo
 	Error     string
+	// BlockNumber is the block including the transaction, nil while it's pending
+	BlockNumber *big.Int
+	// Revert is the decoded revert of a failed transaction, nil if the reason is unknown
+	Revert *RevertError
 }
 
 const (
@@ -66,8 +103,33 @@ type Transaction struct {
 	// ChainID is used to verify signature, so only needed when need to sign and broadcast a message
 	ChainID *big.Int
 	Data    []byte
+	// FeeTier selects the fee suggested by GetSuggestFee on the chains supporting it
+	FeeTier FeeTier
+	// AccessList is the addresses and storage slots the transaction accesses, see EIP-2930
+	// it's ignored by the chains without access lists
+	AccessList AccessList
+}
+
+// AccessList is the list of addresses and storage slots declared by a transaction, the gas of
+// accessing them is cheaper once declared
+type AccessList []AccessTuple
+
+// AccessTuple is an address and the storage slots of it in hex
+type AccessTuple struct {
+	Address     string
+	StorageKeys []string
 }
 
+// FeeTier is the speed of the suggested fee, FeeTierDefault keeps the fee suggested by the node
+type FeeTier int
+
+const (
+	FeeTierDefault FeeTier = iota
+	FeeTierSlow
+	FeeTierStandard
+	FeeTierFast
+)
+
 // FeeLimit is the fee for executing transactions
 // GasFeeCap is the total price of gas, which is the maximum value of (GasTipCap + BaseFee)
 // GasTipCap is the tip price of gas
@@ -80,7 +142,72 @@ type FeeLimit struct {
 	GasTipCap *big.Int
 }
 
+// OwnerToken is an item of BalancesOf, the native balance of Owner is read if Token is empty
+type OwnerToken struct {
+	Owner string
+	Token string
+}
+
+// BalanceResult is the balance read for an OwnerToken, Err is set if the read failed
+type BalanceResult struct {
+	OwnerToken
+	Balance *big.Int
+	Err     error
+}
+
+// AllowanceQuery is an item of Allowances, the amount of Token the Spender can transfer from Owner
+type AllowanceQuery struct {
+	Token   string
+	Owner   string
+	Spender string
+}
+
+// AllowanceResult is the allowance read for an AllowanceQuery, Err is set if the read failed
+type AllowanceResult struct {
+	AllowanceQuery
+	Allowance *big.Int
+	Err       error
+}
+
+// TokenMetadata is the metadata of an erc20 or trc20 contract, Err is set if any field failed to read
+type TokenMetadata struct {
+	Contract    string
+	Symbol      string
+	Decimals    uint8
+	TotalSupply *big.Int
+	Err         error
+}
+
+// BlockChainClientCtx defines the methods that talk to the chain with a caller supplied context
+// the deadline and cancellation of ctx are propagated to every RPC or HTTP request
+type BlockChainClientCtx interface {
+	BalanceAtContext(ctx context.Context, address string) (*big.Int, error)
+	BalanceOfContext(ctx context.Context, contract, from string) (*big.Int, error)
+	DecimalsOfContext(ctx context.Context, contract string) (uint8, error)
+	TotalSupplyOfContext(ctx context.Context, contract string) (*big.Int, error)
+	SymbolOfContext(ctx context.Context, contract string) (string, error)
+	GetNonceContext(ctx context.Context, address string) (uint64, error)
+	GetNonceByNumberContext(ctx context.Context, address string, blockNumber *big.Int) (uint64, error)
+	AllowanceContext(ctx context.Context, contract, owner, spender string) (*big.Int, error)
+	BalancesOfContext(ctx context.Context, pairs []OwnerToken) ([]BalanceResult, error)
+	AllowancesContext(ctx context.Context, queries []AllowanceQuery) ([]AllowanceResult, error)
+	TokenMetadataContext(ctx context.Context, contracts []string) ([]TokenMetadata, error)
+	GetSuggestFeeContext(ctx context.Context, td *Transaction) (*FeeLimit, error)
+	EstimateGasContext(ctx context.Context, td *Transaction) (uint64, error)
+	GetGasPriceContext(ctx context.Context) (*big.Int, *big.Int, error)
+	GetSuggestGasPriceContext(ctx context.Context) (*big.Int, *big.Int, *big.Int, error)
+	DeployContractContext(ctx context.Context, contractAbi, contractBin string, td *Transaction) (transaction []byte, hash []byte, contractAddress string, err error)
+	GetTransactionContext(ctx context.Context, td *Transaction) (transaction []byte, transHash []byte, err error)
+	BroadcastTransactionContext(ctx context.Context, trans []byte, signature []byte) ([]byte, error)
+	CallContractContext(ctx context.Context, td *Transaction) ([]byte, error)
+	GetTransactionByHashContext(ctx context.Context, transactionHash string) (*TransactionInfo, error)
+	GetLatestBlockNumberContext(ctx context.Context) (*big.Int, error)
+	ContractAddressContext(ctx context.Context, addr common.Address) (bool, error)
+}
+
 // BlockChainClient defines the methods for working with different block chains
+// EthClient and TronClient implement both BlockChainClient and BlockChainClientCtx, their methods without
+// context are thin wrappers of the ...Context ones using context.Background()
 type BlockChainClient interface {
 	// BalanceAt Account related
 	BalanceAt(address string) (*big.Int, error)
@@ -93,6 +220,13 @@ type BlockChainClient interface {
 	GetNonce(address string) (uint64, error)
 	GetNonceByNumber(address string, blockNumber *big.Int) (uint64, error)
 	Allowance(contract, owner, spender string) (*big.Int, error)
+
+	// BalancesOf, Allowances and TokenMetadata read many items in as few round-trips as the chain allows
+	// the results are in the order of the input, each with its own error. The error returned is
+	// set only when the whole batch failed
+	BalancesOf(pairs []OwnerToken) ([]BalanceResult, error)
+	Allowances(queries []AllowanceQuery) ([]AllowanceResult, error)
+	TokenMetadata(contracts []string) ([]TokenMetadata, error)
 	TransferData(to string, amount *big.Int) ([]byte, error)
 	ApproveData(contract, owner, spender string, amount *big.Int) ([]byte, error)
 
@@ -142,6 +276,7 @@ type BlockChainClient interface {
 	GetTransaction(td *Transaction) (transaction []byte, transHash []byte, err error)
 
 	// BroadcastTransaction will broadcast the transaction to blockchain
+	// ErrTransport means the transaction may be sent or not, check it by hash before sending it again
 	BroadcastTransaction(trans []byte, signature []byte) ([]byte, error)
 
 	// CallContract will execute the call in VM but not generate transaction
@@ -176,3 +311,10 @@ type BlockChainClient interface {
 	NormalizeAddress(address string) string
 	NativeAssetDecimals() uint8
 }
+
+// MessageVerifier verifies the messages signed by the wallets, it's implemented by EthClient and TronClient
+type MessageVerifier interface {
+	// VerifyMessage checks the message is signed by address with the message signing of the wallets of the chain,
+	// the signature is 65 bytes [R || S || V] with V of 0, 1, 27 or 28
+	VerifyMessage(address string, message, signature []byte) (bool, error)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the chain clients can be checked with errors.Is, such as
+// errors.Is(err, client.ErrNonceTooLow), the original message is kept in the error
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrReverted          = errors.New("execution reverted")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrNonceTooLow       = errors.New("nonce too low")
+	ErrNonceTooHigh      = errors.New("nonce too high")
+	ErrUnderpriced       = errors.New("transaction underpriced")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrTransport         = errors.New("transport error")
+)
+
+// Error binds an error returned by node to one of the error kinds above
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// NewError returns an error which matches kind by errors.Is and keeps the message of err
+func NewError(kind error, err error) error {
+	return &Error{Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// RevertError is returned when a call or transaction is reverted by the contract
+// Reason is decoded from the revert data: the message of Error(string), the description of a Panic(uint256) code,
+// or a custom error like "InsufficientBalance(100, 200)" found in the registered abis
+// ErrorName and Args are the decoded error, such as "Error", "Panic" or the name of the custom error
+// errors.Is(err, ErrReverted) is true for RevertError
+type RevertError struct {
+	Reason    string
+	Data      []byte
+	ErrorName string
+	Args      []interface{}
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return ErrReverted.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrReverted, e.Reason)
+}
+
+func (e *RevertError) Is(target error) bool {
+	return target == ErrReverted
+}
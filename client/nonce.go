@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NonceManager hands out the nonces of the sending addresses locally, so the transactions built
+// concurrently from the same address don't collide on the pending nonce of the node
+// the node is queried only for the first nonce of an address and when the nonces go out of sync
+type NonceManager struct {
+	client BlockChainClientCtx
+
+	mu       sync.Mutex
+	accounts map[string]*nonceAccount
+}
+
+// nonceAccount is the nonce state of an address
+// next is the next new nonce, released are the nonces given back to be reused first,
+// reserved are the nonces handed out whose transactions are not reported yet
+type nonceAccount struct {
+	mu       sync.Mutex
+	synced   bool
+	next     uint64
+	released []uint64
+	reserved map[uint64]bool
+}
+
+// NewNonceManager creates the nonce manager, the pending nonces are queried by cli.GetNonceContext
+func NewNonceManager(cli BlockChainClientCtx) *NonceManager {
+	return &NonceManager{client: cli, accounts: make(map[string]*nonceAccount)}
+}
+
+func (m *NonceManager) account(address string) *nonceAccount {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(address)
+	a, ok := m.accounts[key]
+	if !ok {
+		a = &nonceAccount{reserved: make(map[uint64]bool)}
+		m.accounts[key] = a
+	}
+	return a
+}
+
+// sync reloads the pending nonce of the node, a.mu must be held
+// it's retried by the next Reserve if it fails
+func (m *NonceManager) sync(ctx context.Context, address string, a *nonceAccount) error {
+	nonce, err := m.client.GetNonceContext(ctx, address)
+	if err != nil {
+		a.synced = false
+		return fmt.Errorf("get nonce of %s failed, err=%w", address, err)
+	}
+	a.next, a.released, a.synced = nonce, nil, true
+	return nil
+}
+
+func (a *nonceAccount) release(nonce uint64) {
+	i := sort.Search(len(a.released), func(i int) bool { return a.released[i] >= nonce })
+	if i < len(a.released) && a.released[i] == nonce {
+		return
+	}
+	a.released = append(a.released, 0)
+	copy(a.released[i+1:], a.released[i:])
+	a.released[i] = nonce
+}
+
+// Reserve returns the next nonce of address
+func (m *NonceManager) Reserve(address string) (uint64, error) {
+	return m.ReserveContext(context.Background(), address)
+}
+
+// ReserveContext returns the next nonce of address, the released nonces are reused first
+// the nonce must be given back by Release if the transaction is not broadcasted, or by Report after broadcasting
+func (m *NonceManager) ReserveContext(ctx context.Context, address string) (uint64, error) {
+	a := m.account(address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.synced {
+		if err := m.sync(ctx, address, a); err != nil {
+			return 0, err
+		}
+	}
+	var nonce uint64
+	if len(a.released) > 0 {
+		nonce, a.released = a.released[0], a.released[1:]
+	} else {
+		// the nonces still reserved are skipped when the nonce is moved back by a resync
+		for a.reserved[a.next] {
+			a.next++
+		}
+		nonce = a.next
+		a.next++
+	}
+	a.reserved[nonce] = true
+	return nonce, nil
+}
+
+// Release gives back a nonce whose transaction is not broadcasted, such as when building or signing it failed
+func (m *NonceManager) Release(address string, nonce uint64) {
+	a := m.account(address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.reserved[nonce] {
+		return
+	}
+	delete(a.reserved, nonce)
+	a.release(nonce)
+}
+
+// Report records the result of broadcasting the transaction using nonce
+func (m *NonceManager) Report(address string, nonce uint64, err error) error {
+	return m.ReportContext(context.Background(), address, nonce, err)
+}
+
+// ReportContext records the result of broadcasting the transaction using nonce, err is the error of the broadcast
+// the nonce is used if err is nil, the nonces are resynced with the node on ErrNonceTooLow and ErrNonceTooHigh,
+// and the nonce is released to be reused when the transaction is rejected by ErrInsufficientFunds, ErrUnderpriced,
+// ErrReverted or ErrRateLimited. On the other errors, such as ErrTransport or a timeout, the transaction may be sent,
+// so the nonce is kept reserved and the nonces are resynced, the caller looks up the transaction by hash later and
+// calls Release if it's not sent, or Report with nil if it is
+// the error of the resync is returned
+func (m *NonceManager) ReportContext(ctx context.Context, address string, nonce uint64, err error) error {
+	a := m.account(address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case err == nil:
+		delete(a.reserved, nonce)
+		return nil
+	case errors.Is(err, ErrNonceTooLow), errors.Is(err, ErrNonceTooHigh):
+		delete(a.reserved, nonce)
+		return m.sync(ctx, address, a)
+	case errors.Is(err, ErrInsufficientFunds), errors.Is(err, ErrUnderpriced), errors.Is(err, ErrReverted),
+		errors.Is(err, ErrRateLimited):
+		delete(a.reserved, nonce)
+		a.release(nonce)
+		return nil
+	}
+	return m.sync(ctx, address, a)
+}
+
+// Resync discards the local nonces of address and reloads the pending nonce from the node
+func (m *NonceManager) Resync(address string) error {
+	return m.ResyncContext(context.Background(), address)
+}
+
+// ResyncContext discards the local nonces of address and reloads the pending nonce from the node
+func (m *NonceManager) ResyncContext(ctx context.Context, address string) error {
+	a := m.account(address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return m.sync(ctx, address, a)
+}
+
+// Gap returns the lowest nonce of address missing on the node
+func (m *NonceManager) Gap(address string) (uint64, bool, error) {
+	return m.GapContext(context.Background(), address)
+}
+
+// GapContext returns the lowest nonce of address missing on the node, the transactions with higher nonces are stuck until it's filled
+// a nonce is missing when it's below the local nonces and the pending nonce of the node points to it, but no transaction
+// is being built with it, which happens when a transaction is dropped from the mempool or a released nonce is not reused
+func (m *NonceManager) GapContext(ctx context.Context, address string) (uint64, bool, error) {
+	a := m.account(address)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.synced {
+		return 0, false, nil
+	}
+	pending, err := m.client.GetNonceContext(ctx, address)
+	if err != nil {
+		return 0, false, fmt.Errorf("get nonce of %s failed, err=%w", address, err)
+	}
+	if pending >= a.next || a.reserved[pending] {
+		return 0, false, nil
+	}
+	return pending, true, nil
+}
+
+// FillGap sends a zero value transfer from address to itself with nonce
+func (m *NonceManager) FillGap(address string, nonce uint64, sign func(hash []byte) ([]byte, error)) ([]byte, error) {
+	return m.FillGapContext(context.Background(), address, nonce, sign)
+}
+
+// FillGapContext sends a zero value transfer from address to itself with nonce, the gap is found by GapContext
+// sign returns the signature of the transaction hash, the hash of the transaction is returned
+func (m *NonceManager) FillGapContext(ctx context.Context, address string, nonce uint64,
+	sign func(hash []byte) ([]byte, error)) ([]byte, error) {
+	a := m.account(address)
+	a.mu.Lock()
+	if nonce >= a.next || a.reserved[nonce] {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("nonce=%d of %s is not a gap", nonce, address)
+	}
+	for i := range a.released {
+		if a.released[i] == nonce {
+			a.released = append(a.released[:i], a.released[i+1:]...)
+			break
+		}
+	}
+	a.reserved[nonce] = true
+	a.mu.Unlock()
+
+	td := &Transaction{From: address, To: address, Amount: big.NewInt(0), Nonce: nonce}
+	fee, err := m.client.GetSuggestFeeContext(ctx, td)
+	if err != nil {
+		m.Release(address, nonce)
+		return nil, fmt.Errorf("get suggest fee failed, err=%w", err)
+	}
+	td.Fee = fee
+	tx, hash, err := m.client.GetTransactionContext(ctx, td)
+	if err != nil {
+		m.Release(address, nonce)
+		return nil, fmt.Errorf("build transaction failed, err=%w", err)
+	}
+	signature, err := sign(hash)
+	if err != nil {
+		m.Release(address, nonce)
+		return nil, fmt.Errorf("sign transaction failed, err=%w", err)
+	}
+	result, err := m.client.BroadcastTransactionContext(ctx, tx, signature)
+	// a failed resync is retried by the next Reserve
+	_ = m.ReportContext(ctx, address, nonce, err)
+	if err != nil {
+		return nil, fmt.Errorf("broadcast transaction failed, err=%w", err)
+	}
+	return result, nil
+}
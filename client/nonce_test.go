@@ -0,0 +1,128 @@
+package client_test
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+func sign(hash []byte) ([]byte, error) {
+	return make([]byte, 65), nil
+}
+
+// sendWithNonce broadcasts a transfer from alice with nonce and reports the result to m
+func sendWithNonce(t *testing.T, c client.BlockChainClient, m *client.NonceManager, nonce uint64, amount int64) (string, error) {
+	tx, _, err := c.GetTransaction(&client.Transaction{From: alice, To: bob, Amount: big.NewInt(amount), Nonce: nonce})
+	assert.Nil(t, err, "get transaction failed")
+	hash, err := c.BroadcastTransaction(tx, make([]byte, 65))
+	assert.Nil(t, m.Report(alice, nonce, err), "report failed")
+	return string(hash), err
+}
+
+func TestNonceManager(t *testing.T) {
+	f := newFake()
+	m := client.NewNonceManager(f)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	nonces := make([]uint64, 0)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.Reserve(alice)
+			assert.Nil(t, err, "reserve failed")
+			mu.Lock()
+			nonces = append(nonces, nonce)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4}, nonces)
+
+	// the released nonces are reused first
+	m.Release(alice, 3)
+	m.Release(alice, 1)
+	nonce, _ := m.Reserve(alice)
+	assert.Equal(t, uint64(1), nonce)
+	m.Release(alice, 1)
+	for i := uint64(0); i < 5; i++ {
+		m.Release(alice, i)
+	}
+	for i := uint64(0); i < 3; i++ {
+		nonce, _ = m.Reserve(alice)
+		assert.Equal(t, i, nonce)
+		_, err := sendWithNonce(t, f, m, nonce, 1)
+		assert.Nil(t, err, "send failed")
+	}
+	// the released nonces 3 and 4 are not used yet
+	gap, found, err := m.Gap(alice)
+	assert.Nil(t, err, "gap failed")
+	assert.True(t, found, "gap expected")
+	assert.Equal(t, uint64(3), gap)
+
+	// another sender used nonce 3, the nonce is resynced after it's rejected
+	_, err = sendWithNonce(t, f, client.NewNonceManager(f), 3, 2)
+	assert.Nil(t, err, "send failed")
+	nonce, _ = m.Reserve(alice)
+	assert.Equal(t, uint64(3), nonce)
+	_, err = sendWithNonce(t, f, m, nonce, 1)
+	assert.ErrorIs(t, err, client.ErrNonceTooLow)
+	nonce, _ = m.Reserve(alice)
+	assert.Equal(t, uint64(4), nonce)
+	_, err = sendWithNonce(t, f, m, nonce, 1)
+	assert.Nil(t, err, "send failed")
+	_, found, _ = m.Gap(alice)
+	assert.False(t, found, "no gap expected")
+
+	// the transaction with nonce 4 is dropped
+	f.Drop(f.Sent()[len(f.Sent())-1])
+	gap, found, err = m.Gap(alice)
+	assert.Nil(t, err, "gap failed")
+	assert.True(t, found, "gap expected")
+	assert.Equal(t, uint64(4), gap)
+	_, err = m.FillGap(alice, gap, sign)
+	assert.Nil(t, err, "fill gap failed")
+	_, found, _ = m.Gap(alice)
+	assert.False(t, found, "gap should be filled")
+	pending, _ := f.GetNonce(alice)
+	assert.Equal(t, uint64(5), pending)
+	_, err = m.FillGap(alice, 5, sign)
+	assert.NotNil(t, err, "nonce 5 is not a gap")
+}
+
+func TestNonceManagerReport(t *testing.T) {
+	f := newFake()
+	m := client.NewNonceManager(f)
+	timeout := client.NewError(client.ErrTransport, errors.New("request timeout"))
+
+	// the transaction is sent but the response is lost
+	nonce, _ := m.Reserve(alice)
+	tx, _, err := f.GetTransaction(&client.Transaction{From: alice, To: bob, Amount: big.NewInt(1), Nonce: nonce})
+	assert.Nil(t, err, "get transaction failed")
+	_, err = f.BroadcastTransaction(tx, make([]byte, 65))
+	assert.Nil(t, err, "broadcast failed")
+	assert.Nil(t, m.Report(alice, nonce, timeout), "report failed")
+	nonce, _ = m.Reserve(alice)
+	assert.Equal(t, uint64(1), nonce, "nonce of the sent transaction should not be reused")
+
+	// the rejected nonce is reused
+	assert.Nil(t, m.Report(alice, nonce, client.NewError(client.ErrUnderpriced, errors.New("transaction underpriced"))))
+	nonce, _ = m.Reserve(alice)
+	assert.Equal(t, uint64(1), nonce, "rejected nonce should be reused")
+
+	// the transaction is not sent, the nonce is kept until the caller releases it
+	assert.Nil(t, m.Report(alice, nonce, timeout), "report failed")
+	next, _ := m.Reserve(alice)
+	assert.Equal(t, uint64(2), next, "nonce of the unknown transaction should not be reused")
+	m.Release(alice, nonce)
+	next, _ = m.Reserve(alice)
+	assert.Equal(t, uint64(1), next, "released nonce should be reused")
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs the transaction hashes returned by GetTransaction, the signature is passed to BroadcastTransaction
+// Address is the address of the signing key, it's formatted for a chain by AddressToString
+// SignHash returns the 65 bytes [R || S || V] signature with V of 0 or 1, which is used by both EVM chains and TRON
+type Signer interface {
+	Address() common.Address
+	SignHash(ctx context.Context, hash []byte) ([]byte, error)
+}
+
+// prefixes of the signed messages, the length of the message in decimal and the message follow the prefix
+const (
+	// EthereumMessagePrefix is the EIP-191 prefix of personal_sign, used by MetaMask and the other EVM wallets
+	EthereumMessagePrefix = "\x19Ethereum Signed Message:\n"
+	// TronMessagePrefix is the prefix of signMessageV2 of TronWeb, used by TronLink
+	TronMessagePrefix = "\x19TRON Signed Message:\n"
+)
+
+// HashMessage returns the keccak256 hash of the message signed by the wallets, prefix || len(message) || message
+func HashMessage(prefix string, message []byte) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s%d", prefix, len(message))), message)
+}
+
+// RecoverHash returns the address signing hash, V of signature is 0, 1, 27 or 28
+func RecoverHash(hash, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length, expect=%d, got=%d", crypto.SignatureLength, len(signature))
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("recover signer failed, err=%w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// KeySigner signs with an ecdsa key in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates the signer of key
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// NewKeySignerFromHex creates the signer of the hexed private key, with or without heading 0x
+func NewKeySignerFromHex(privateKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("parse private key failed, err=%w", err)
+	}
+	return NewKeySigner(key), nil
+}
+
+// NewKeystoreSigner creates the signer of the go-ethereum keystore json file at path encrypted by passphrase
+func NewKeystoreSigner(path, passphrase string) (*KeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore failed, err=%w", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore failed, err=%w", err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignHash(_ context.Context, hash []byte) ([]byte, error) {
+	signature, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("sign hash failed, err=%w", err)
+	}
+	return signature, nil
+}
+
+// RemoteSigner signs by a signing service holding the key, such as an HSM or KMS gateway
+// the hash is posted to the endpoint as {"address": "0x...", "hash": "0x..."}, and the service replies
+// {"signature": "0x..."}, the signature is checked against the address before it's returned
+type RemoteSigner struct {
+	endpoint   string
+	address    common.Address
+	httpClient *http.Client
+}
+
+// NewRemoteSigner creates the signer of address by the service at endpoint, http.DefaultClient is used if httpClient is nil
+func NewRemoteSigner(endpoint string, address common.Address, httpClient *http.Client) *RemoteSigner {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &RemoteSigner{endpoint: endpoint, address: address, httpClient: httpClient}
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{"address": s.address, "hash": hexutil.Bytes(hash)})
+	if err != nil {
+		return nil, fmt.Errorf("encode request failed, err=%w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request failed, err=%w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, NewError(ErrTransport, fmt.Errorf("request signer failed, err=%w", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("signer rejected, status=%d, body=%s", resp.StatusCode, message)
+	}
+	var result struct {
+		Signature hexutil.Bytes `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode signature failed, err=%w", err)
+	}
+	signature := []byte(result.Signature)
+	signer, err := RecoverHash(hash, signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != s.address {
+		return nil, fmt.Errorf("signature of %s not match address=%s", signer.Hex(), s.address.Hex())
+	}
+	// some services return V of 27 or 28
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+	return signature, nil
+}
+
+// SendOption changes how SignAndSendContext picks the nonce of the transaction
+type SendOption func(*sendOptions)
+
+type sendOptions struct {
+	keepNonce bool
+	nonces    *NonceManager
+}
+
+// WithNonce keeps td.Nonce set by the caller
+func WithNonce() SendOption {
+	return func(o *sendOptions) { o.keepNonce = true }
+}
+
+// WithNonceManager reserves the nonce from m and reports the result of the broadcast to it,
+// so the transactions sent concurrently from one address don't collide
+func WithNonceManager(m *NonceManager) SendOption {
+	return func(o *sendOptions) { o.nonces = m }
+}
+
+// SignAndSendContext generates the transaction of td by cli, signs its hash by signer and broadcasts it,
+// the fee is suggested by cli if td.Fee is nil, the hash of the transaction is returned
+// td.Nonce is set to the pending nonce of td.From unless WithNonce or WithNonceManager is given
+// td.From must be the address of signer, which is checked by the chain clients calling it
+func SignAndSendContext(ctx context.Context, cli BlockChainClientCtx, td *Transaction, signer Signer, opts ...SendOption) ([]byte, error) {
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	switch {
+	case options.nonces != nil:
+		nonce, err := options.nonces.ReserveContext(ctx, td.From)
+		if err != nil {
+			return nil, fmt.Errorf("reserve nonce failed, err=%w", err)
+		}
+		td.Nonce = nonce
+	case !options.keepNonce:
+		nonce, err := cli.GetNonceContext(ctx, td.From)
+		if err != nil {
+			return nil, fmt.Errorf("get nonce failed, err=%w", err)
+		}
+		td.Nonce = nonce
+	}
+	tx, signature, err := signTransaction(ctx, cli, td, signer)
+	if err != nil {
+		if options.nonces != nil {
+			options.nonces.Release(td.From, td.Nonce)
+		}
+		return nil, err
+	}
+	result, err := cli.BroadcastTransactionContext(ctx, tx, signature)
+	if options.nonces != nil {
+		// a failed resync is retried by the next Reserve
+		_ = options.nonces.ReportContext(ctx, td.From, td.Nonce, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("broadcast transaction failed, err=%w", err)
+	}
+	return result, nil
+}
+
+// signTransaction suggests the fee if it's not set, then generates the transaction of td and signs it
+func signTransaction(ctx context.Context, cli BlockChainClientCtx, td *Transaction, signer Signer) ([]byte, []byte, error) {
+	if td.Fee == nil {
+		fee, err := cli.GetSuggestFeeContext(ctx, td)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get suggest fee failed, err=%w", err)
+		}
+		td.Fee = fee
+	}
+	tx, hash, err := cli.GetTransactionContext(ctx, td)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build transaction failed, err=%w", err)
+	}
+	signature, err := signer.SignHash(ctx, hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sign transaction failed, err=%w", err)
+	}
+	return tx, signature, nil
+}
@@ -0,0 +1,166 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// signerServer signs the posted hashes by key and returns V of 27 or 28 like some signing services
+func signerServer(t *testing.T, key *keystore.Key) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Address common.Address `json:"address"`
+			Hash    hexutil.Bytes  `json:"hash"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req), "decode request failed")
+		if req.Address != key.Address {
+			http.Error(w, "unknown address", http.StatusNotFound)
+			return
+		}
+		signature, err := crypto.Sign(req.Hash, key.PrivateKey)
+		assert.Nil(t, err, "sign failed")
+		signature[crypto.RecoveryIDOffset] += 27
+		json.NewEncoder(w).Encode(map[string]interface{}{"signature": hexutil.Bytes(signature)})
+	}))
+}
+
+func TestKeySigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer, err := client.NewKeySignerFromHex(hexutil.Encode(crypto.FromECDSA(key)))
+	assert.Nil(t, err, "create signer failed")
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	hash := crypto.Keccak256([]byte("hello"))
+	signature, err := signer.SignHash(context.Background(), hash)
+	assert.Nil(t, err, "sign failed")
+	pubKey, err := crypto.SigToPub(hash, signature)
+	assert.Nil(t, err, "recover failed")
+	assert.Equal(t, signer.Address(), crypto.PubkeyToAddress(*pubKey))
+
+	_, err = client.NewKeySignerFromHex("0x1234")
+	assert.NotNil(t, err, "invalid key should fail")
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key},
+		"secret", keystore.LightScryptN, keystore.LightScryptP)
+	assert.Nil(t, err, "encrypt key failed")
+	path := filepath.Join(t.TempDir(), "key.json")
+	assert.Nil(t, os.WriteFile(path, keyJSON, 0600), "write keystore failed")
+
+	signer, err := client.NewKeystoreSigner(path, "secret")
+	assert.Nil(t, err, "create signer failed")
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	_, err = client.NewKeystoreSigner(path, "wrong")
+	assert.NotNil(t, err, "wrong passphrase should fail")
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := signerServer(t, &keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key})
+	defer server.Close()
+
+	signer := client.NewRemoteSigner(server.URL, crypto.PubkeyToAddress(key.PublicKey), nil)
+	hash := crypto.Keccak256([]byte("hello"))
+	signature, err := signer.SignHash(context.Background(), hash)
+	assert.Nil(t, err, "sign failed")
+	expected, _ := crypto.Sign(hash, key)
+	assert.Equal(t, expected, signature, "V should be normalized")
+
+	_, err = client.NewRemoteSigner(server.URL, common.HexToAddress(alice), nil).SignHash(context.Background(), hash)
+	assert.Contains(t, err.Error(), "status=404")
+}
+
+func TestSignAndSend(t *testing.T) {
+	f := newFake()
+	key, _ := crypto.GenerateKey()
+	signer := client.NewKeySigner(key)
+	f.SetBalance(signer.Address().Hex(), big.NewInt(1e18))
+
+	td := &client.Transaction{From: signer.Address().Hex(), To: bob, Amount: big.NewInt(100)}
+	hash, err := client.SignAndSendContext(context.Background(), f, td, signer)
+	assert.Nil(t, err, "sign and send failed")
+	assert.NotNil(t, td.Fee, "fee should be suggested")
+	f.Mine()
+	info, err := f.GetTransactionByHash(hexutil.Encode(hash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, client.TransactionStatusSuccess, info.Status)
+
+	// the second transaction from the signer uses the next nonce
+	td = &client.Transaction{From: signer.Address().Hex(), To: bob, Amount: big.NewInt(100)}
+	hash, err = client.SignAndSendContext(context.Background(), f, td, signer)
+	assert.Nil(t, err, "sign and send again failed")
+	assert.Equal(t, uint64(1), td.Nonce, "nonce should be the next one")
+	f.Mine()
+	info, err = f.GetTransactionByHash(hexutil.Encode(hash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, client.TransactionStatusSuccess, info.Status)
+}
+
+func TestSignAndSendNonce(t *testing.T) {
+	f := newFake()
+	key, _ := crypto.GenerateKey()
+	signer := client.NewKeySigner(key)
+	from := signer.Address().Hex()
+	f.SetBalance(from, big.NewInt(1e18))
+	f.SetNonce(from, 5)
+	m := client.NewNonceManager(f)
+	ctx := context.Background()
+
+	// the nonce set by the caller is kept
+	td := &client.Transaction{From: from, To: bob, Amount: big.NewInt(100), Nonce: 7}
+	_, err := client.SignAndSendContext(ctx, f, td, signer, client.WithNonce())
+	assert.ErrorIs(t, err, client.ErrNonceTooHigh)
+	assert.Equal(t, uint64(7), td.Nonce, "nonce of the caller should be kept")
+
+	// the nonce reserved by another sender is skipped
+	reserved, err := m.Reserve(from)
+	assert.Nil(t, err, "reserve failed")
+	assert.Equal(t, uint64(5), reserved)
+	td = &client.Transaction{From: from, To: bob, Amount: big.NewInt(100), Nonce: reserved}
+	_, err = client.SignAndSendContext(ctx, f, td, signer, client.WithNonce())
+	assert.Nil(t, err, "send with reserved nonce failed")
+	assert.Nil(t, m.Report(from, reserved, err), "report failed")
+
+	td = &client.Transaction{From: from, To: bob, Amount: big.NewInt(100)}
+	_, err = client.SignAndSendContext(ctx, f, td, signer, client.WithNonceManager(m))
+	assert.Nil(t, err, "send with nonce manager failed")
+	assert.Equal(t, uint64(6), td.Nonce, "nonce should be reserved from the manager")
+	next, _ := m.Reserve(from)
+	assert.Equal(t, uint64(7), next, "nonce of the sent transaction should be used")
+}
+
+func TestHashMessage(t *testing.T) {
+	hash := client.HashMessage(client.EthereumMessagePrefix, []byte("hello world"))
+	assert.Equal(t, "0xd9eba16ed0ecae432b71fe008c98cc872bb4cc214d3220a36f365326cf807d68", hexutil.Encode(hash))
+	hash = client.HashMessage(client.TronMessagePrefix, []byte("hello world"))
+	assert.Equal(t, crypto.Keccak256([]byte("\x19TRON Signed Message:\n11hello world")), hash)
+
+	key, _ := crypto.GenerateKey()
+	signature, _ := crypto.Sign(hash, key)
+	signer, err := client.RecoverHash(hash, signature)
+	assert.Nil(t, err, "recover failed")
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer)
+	signature[crypto.RecoveryIDOffset] += 27
+	signer, err = client.RecoverHash(hash, signature)
+	assert.Nil(t, err, "recover V of 27 or 28 failed")
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer)
+	_, err = client.RecoverHash(hash, signature[:64])
+	assert.NotNil(t, err, "short signature should fail")
+}
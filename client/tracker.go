@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// TxState is the state of a broadcasted transaction
+type TxState int
+
+const (
+	// TxSubmitted means the transaction is broadcasted but not known by the node yet
+	TxSubmitted TxState = iota
+	// TxPending means the transaction is in the mempool
+	TxPending
+	// TxIncluded means the transaction is executed successfully in a block which is not final yet
+	TxIncluded
+	// TxConfirmed means the block including the transaction is final
+	TxConfirmed
+	// TxFailed means the transaction is included in a block but the execution failed
+	TxFailed
+	// TxDropped means the transaction is evicted or replaced by another one with the same nonce
+	TxDropped
+	// TxTimedOut means the transaction is not final before the timeout of the tracker
+	TxTimedOut
+)
+
+var txStateNames = []string{"submitted", "pending", "included", "confirmed", "failed", "dropped", "timed out"}
+
+func (s TxState) String() string {
+	if s < 0 || int(s) >= len(txStateNames) {
+		return fmt.Sprintf("TxState(%d)", int(s))
+	}
+	return txStateNames[s]
+}
+
+// Final returns whether the transaction stays in the state, the tracker stops after a final state
+func (s TxState) Final() bool {
+	return s >= TxConfirmed
+}
+
+// TxEvent is emitted by the tracker when the state of the transaction changes
+// Info is nil until the node knows the transaction, Confirmations is the number of blocks
+// on top of and including the block of the transaction
+type TxEvent struct {
+	Hash          string
+	State         TxState
+	Info          *TransactionInfo
+	Confirmations uint64
+	Err           error
+}
+
+// Finality is the finality rule of a chain
+// Confirmations is how many blocks, including the block of the transaction, make it final
+// DropAfter is how long a transaction can stay unknown by the node before it's dropped, zero means never
+type Finality struct {
+	Confirmations uint64
+	DropAfter     time.Duration
+}
+
+// FinalityProvider is implemented by the clients knowing the finality rule of their chain
+type FinalityProvider interface {
+	Finality() Finality
+}
+
+// DefaultFinality is used for the clients not implementing FinalityProvider
+var DefaultFinality = Finality{Confirmations: 12}
+
+// TrackerOptions configures TxTracker, the zero values use the defaults
+// Confirmations and DropAfter override the finality rule of the chain
+// PollInterval is how often the node is queried, 3s by default
+// Timeout is how long a transaction is tracked before TxTimedOut, zero means until ctx is done
+type TrackerOptions struct {
+	Confirmations uint64
+	DropAfter     time.Duration
+	PollInterval  time.Duration
+	Timeout       time.Duration
+}
+
+const defaultPollInterval = 3 * time.Second
+
+// TxTracker follows broadcasted transactions until they are final
+// it works with any BlockChainClientCtx by polling GetTransactionByHashContext and GetLatestBlockNumberContext
+type TxTracker struct {
+	client  BlockChainClientCtx
+	options TrackerOptions
+}
+
+// NewTxTracker creates the tracker, the finality rule comes from the client if it implements FinalityProvider
+func NewTxTracker(cli BlockChainClientCtx, options TrackerOptions) *TxTracker {
+	finality := DefaultFinality
+	if p, ok := cli.(FinalityProvider); ok {
+		finality = p.Finality()
+	}
+	if options.Confirmations == 0 {
+		options.Confirmations = finality.Confirmations
+	}
+	if options.Confirmations == 0 {
+		options.Confirmations = 1
+	}
+	if options.DropAfter == 0 {
+		options.DropAfter = finality.DropAfter
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultPollInterval
+	}
+	return &TxTracker{client: cli, options: options}
+}
+
+// Track follows the transaction in a goroutine, the events are sent to the returned channel
+// the channel is closed after a final state or when ctx is done
+func (t *TxTracker) Track(ctx context.Context, hash string) <-chan TxEvent {
+	events := make(chan TxEvent, 8)
+	go func() {
+		defer close(events)
+		t.Watch(ctx, hash, func(event TxEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return events
+}
+
+// Watch follows the transaction until it's in a final state and returns the final event
+// onEvent is called for every state transition, including the final one. The error is
+// ctx.Err() if ctx is done before the transaction is final
+func (t *TxTracker) Watch(ctx context.Context, hash string, onEvent func(TxEvent)) (TxEvent, error) {
+	if t.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.options.Timeout)
+		defer cancel()
+	}
+	w := txWatch{TxTracker: t, hash: hash, onEvent: onEvent, missingSince: time.Now()}
+	w.emit(TxEvent{Hash: hash, State: TxSubmitted})
+
+	ticker := time.NewTicker(t.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		if event, final := w.poll(ctx); final {
+			w.emit(event)
+			return event, nil
+		}
+		select {
+		case <-ctx.Done():
+			if t.options.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				event := w.last
+				event.State, event.Err = TxTimedOut, ctx.Err()
+				w.emit(event)
+				return event, nil
+			}
+			return w.last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// txWatch keeps the state of one transaction followed by Watch
+type txWatch struct {
+	*TxTracker
+	hash    string
+	onEvent func(TxEvent)
+	last    TxEvent
+	emitted bool
+	// seen is the last known transaction, it's used to detect replacements
+	seen         *Transaction
+	missingSince time.Time
+}
+
+func (w *txWatch) emit(event TxEvent) {
+	// the block changes when the transaction is moved by a reorganization
+	if w.emitted && event.State == w.last.State && sameBlock(event.Info, w.last.Info) {
+		return
+	}
+	w.last, w.emitted = event, true
+	if w.onEvent != nil {
+		w.onEvent(event)
+	}
+}
+
+// poll queries the node once, the event is emitted unless it's final, which is returned to Watch
+func (w *txWatch) poll(ctx context.Context) (TxEvent, bool) {
+	info, err := w.client.GetTransactionByHashContext(ctx, w.hash)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			// try again in the next round
+			return TxEvent{}, false
+		}
+		return w.missing(ctx)
+	}
+	w.missingSince = time.Now()
+	if info.Tx != nil && info.Tx.From != "" {
+		w.seen = info.Tx
+	}
+	if info.IsPending || info.BlockNumber == nil {
+		w.emit(TxEvent{Hash: w.hash, State: TxPending, Info: info})
+		return TxEvent{}, false
+	}
+	if info.Status != TransactionStatusSuccess {
+		return TxEvent{Hash: w.hash, State: TxFailed, Info: info, Err: txError(info)}, true
+	}
+	latest, err := w.client.GetLatestBlockNumberContext(ctx)
+	if err != nil {
+		return TxEvent{}, false
+	}
+	confirmations := uint64(0)
+	if latest.Cmp(info.BlockNumber) >= 0 {
+		confirmations = new(big.Int).Sub(latest, info.BlockNumber).Uint64() + 1
+	}
+	event := TxEvent{Hash: w.hash, State: TxIncluded, Info: info, Confirmations: confirmations}
+	if confirmations >= w.options.Confirmations {
+		event.State = TxConfirmed
+		return event, true
+	}
+	w.emit(event)
+	return TxEvent{}, false
+}
+
+// missing handles the transaction unknown by the node, it's either not propagated yet,
+// removed by a reorganization, replaced by another transaction with the same nonce or dropped
+func (w *txWatch) missing(ctx context.Context) (TxEvent, bool) {
+	if w.seen != nil && w.seen.From != "" {
+		nonce, err := w.client.GetNonceByNumberContext(ctx, w.seen.From, nil)
+		if err == nil && nonce > w.seen.Nonce {
+			return TxEvent{Hash: w.hash, State: TxDropped, Info: w.last.Info,
+				Err: fmt.Errorf("nonce %d of %s is used by another transaction", w.seen.Nonce, w.seen.From)}, true
+		}
+	}
+	if w.options.DropAfter > 0 && time.Since(w.missingSince) >= w.options.DropAfter {
+		return TxEvent{Hash: w.hash, State: TxDropped, Info: w.last.Info,
+			Err: fmt.Errorf("transaction not found for %s", w.options.DropAfter)}, true
+	}
+	if w.last.State != TxSubmitted {
+		// the transaction was known, it's removed by a reorganization and may be mined again
+		w.emit(TxEvent{Hash: w.hash, State: TxPending})
+	}
+	return TxEvent{}, false
+}
+
+func txError(info *TransactionInfo) error {
+	if info.Error != "" {
+		return errors.New(info.Error)
+	}
+	return fmt.Errorf("transaction failed")
+}
+
+func sameBlock(a, b *TransactionInfo) bool {
+	var x, y *big.Int
+	if a != nil {
+		x = a.BlockNumber
+	}
+	if b != nil {
+		y = b.BlockNumber
+	}
+	if x == nil || y == nil {
+		return x == nil && y == nil
+	}
+	return x.Cmp(y) == 0
+}
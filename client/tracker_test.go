@@ -0,0 +1,115 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/clienttest"
+)
+
+const (
+	alice = "0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8"
+	bob   = "0x2A6c6b4a1F3e33b1b2E4B0e4c5e4d2F8a1D2E3f4"
+)
+
+func newFake() *clienttest.Fake {
+	f := clienttest.NewFake(clienttest.FormatEVM)
+	f.SetBalance(alice, big.NewInt(1e18))
+	f.SetAutoMine(false)
+	return f
+}
+
+func send(t *testing.T, c client.BlockChainClient, td *client.Transaction) string {
+	tx, _, err := c.GetTransaction(td)
+	assert.Nil(t, err, "get transaction failed")
+	hash, err := c.BroadcastTransaction(tx, make([]byte, 65))
+	assert.Nil(t, err, "broadcast transaction failed")
+	return hexutil.Encode(hash)
+}
+
+// next waits for the next event of the tracker
+func next(t *testing.T, events <-chan client.TxEvent) client.TxEvent {
+	select {
+	case event, ok := <-events:
+		assert.True(t, ok, "events closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return client.TxEvent{}
+}
+
+func TestTxTrackerConfirmed(t *testing.T) {
+	f := newFake()
+	hash := send(t, f, &client.Transaction{From: alice, To: bob, Amount: big.NewInt(1)})
+	tracker := client.NewTxTracker(f, client.TrackerOptions{Confirmations: 3, PollInterval: 5 * time.Millisecond})
+	events := tracker.Track(context.Background(), hash)
+
+	assert.Equal(t, client.TxSubmitted, next(t, events).State)
+	assert.Equal(t, client.TxPending, next(t, events).State)
+	f.Mine()
+	event := next(t, events)
+	assert.Equal(t, client.TxIncluded, event.State)
+	assert.Equal(t, uint64(1), event.Confirmations)
+	f.Mine()
+	f.Mine()
+	event = next(t, events)
+	assert.Equal(t, client.TxConfirmed, event.State)
+	assert.Equal(t, uint64(3), event.Confirmations)
+	assert.True(t, event.State.Final())
+	_, ok := <-events
+	assert.False(t, ok, "events should be closed after the final state")
+}
+
+func TestTxTrackerFailed(t *testing.T) {
+	f := newFake()
+	f.SetAutoMine(true)
+	f.SetContract(bob, func(*client.Transaction) ([]byte, []*client.EventLog, error) {
+		return nil, nil, errors.New("always revert")
+	})
+	hash := send(t, f, &client.Transaction{From: alice, To: bob, Data: []byte{1, 2, 3, 4}})
+	tracker := client.NewTxTracker(f, client.TrackerOptions{PollInterval: 5 * time.Millisecond})
+	var states []client.TxState
+	event, err := tracker.Watch(context.Background(), hash, func(e client.TxEvent) { states = append(states, e.State) })
+	assert.Nil(t, err, "watch failed")
+	assert.Equal(t, client.TxFailed, event.State)
+	assert.Contains(t, event.Err.Error(), "always revert")
+	assert.Equal(t, []client.TxState{client.TxSubmitted, client.TxFailed}, states)
+}
+
+func TestTxTrackerDropped(t *testing.T) {
+	f := newFake()
+	hash := send(t, f, &client.Transaction{From: alice, To: bob, Amount: big.NewInt(1)})
+	tracker := client.NewTxTracker(f, client.TrackerOptions{PollInterval: 5 * time.Millisecond, DropAfter: 30 * time.Millisecond})
+	events := tracker.Track(context.Background(), hash)
+	assert.Equal(t, client.TxSubmitted, next(t, events).State)
+	assert.Equal(t, client.TxPending, next(t, events).State)
+
+	// replaced by another transaction with the same nonce
+	f.Drop(hash)
+	send(t, f, &client.Transaction{From: alice, To: bob, Amount: big.NewInt(2)})
+	f.Mine()
+	event := next(t, events)
+	assert.Equal(t, client.TxDropped, event.State)
+	assert.NotNil(t, event.Err)
+}
+
+func TestTxTrackerTimeout(t *testing.T) {
+	f := newFake()
+	tracker := client.NewTxTracker(f, client.TrackerOptions{PollInterval: 5 * time.Millisecond, Timeout: 30 * time.Millisecond})
+	event, err := tracker.Watch(context.Background(), "0x01", nil)
+	assert.Nil(t, err, "watch failed")
+	assert.Equal(t, client.TxTimedOut, event.State)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.NewTxTracker(f, client.TrackerOptions{}).Watch(ctx, "0x01", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -2,11 +2,15 @@ package clients
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
 	"sync"
+	"time"
 
 	"git.bipal.space/shared-lib/blockchain/client"
-	"git.bipal.space/shared-lib/blockchain/eth"
-	"git.bipal.space/shared-lib/blockchain/tron"
 )
 
 var (
@@ -29,41 +33,296 @@ func GetClientByConfig(config *client.ChainConfiguration) (client.BlockChainClie
 	return cli, err
 }
 
-func NewClient(config *client.ChainConfiguration) (client.BlockChainClient, error) {
-	if config.ChainName == "Tron" {
-		return tron.NewTronClient(config)
-	} else {
-		cli, err := eth.NewEthClient(config)
-		if err != nil {
-			return nil, err
+// CloseClients closes and drops the clients cached by GetClientByConfig
+func CloseClients() error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	var firstErr error
+	for key, cli := range clientMap {
+		if err := closeClient(cli); err != nil && firstErr == nil {
+			firstErr = err
 		}
-		cli.SupportEIP1559 = config.SupportEIP1559
-		return cli, nil
+		delete(clientMap, key)
 	}
-	return nil, fmt.Errorf("chain=%s is not supported", config.ChainName)
+	return firstErr
+}
+
+// closeClient closes the client if it holds any connection
+func closeClient(cli client.BlockChainClient) error {
+	if closer, ok := cli.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// defaultRetireGrace is how long a client returned by GetClient stays open after it's replaced or removed
+const defaultRetireGrace = time.Minute
+
+// managedClient is a client owned by ClientManager, once it's replaced or removed
+// it's closed after all the callers acquired it have released it
+type managedClient struct {
+	config  client.ChainConfiguration
+	client  client.BlockChainClient
+	refs    int
+	shared  bool // returned by GetClient, the callers can't release it
+	retired bool
 }
 
+// ClientManager keeps a client for every chain, the chains can be added, replaced and
+// removed at runtime, either by calling Set/Remove/Reload or by watching a config file
 type ClientManager struct {
-	chainClients map[uint64]client.BlockChainClient
+	mu     sync.Mutex
+	chains map[uint64]*managedClient
+	// retiring are the retired clients returned by GetClient, each is closed when its timer fires
+	retiring map[*managedClient]*time.Timer
+	grace    time.Duration
+	closed   bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
 }
 
 func NewClientManager(configs map[uint64]*client.ChainConfiguration) (*ClientManager, error) {
 	cm := &ClientManager{
-		chainClients: make(map[uint64]client.BlockChainClient),
+		chains:   make(map[uint64]*managedClient),
+		retiring: make(map[*managedClient]*time.Timer),
+		grace:    defaultRetireGrace,
+		stop:     make(chan struct{}),
 	}
 	for cid, config := range configs {
-		cli, err := NewClient(config)
-		if err != nil {
+		if err := cm.Set(cid, config); err != nil {
+			cm.Close()
 			return nil, err
 		}
-		cm.chainClients[cid] = cli
 	}
 	return cm, nil
 }
 
+// GetClient returns the current client of the chain
+// Once the chain is replaced or removed, the client keeps open for a grace period of a minute
+// so the calls in flight can finish, use Acquire if the client is kept longer
 func (cm *ClientManager) GetClient(chainID uint64) (client.BlockChainClient, error) {
-	if cli, ok := cm.chainClients[chainID]; ok {
-		return cli, nil
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if mc, ok := cm.chains[chainID]; ok {
+		mc.shared = true
+		return mc.client, nil
 	}
 	return nil, fmt.Errorf("chain=%d not found", chainID)
 }
+
+// Acquire returns the current client of the chain and keeps it open until release is called,
+// even if the chain is replaced or removed in the meantime
+func (cm *ClientManager) Acquire(chainID uint64) (cli client.BlockChainClient, release func(), err error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	mc, ok := cm.chains[chainID]
+	if !ok {
+		return nil, nil, fmt.Errorf("chain=%d not found", chainID)
+	}
+	mc.refs++
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			cm.mu.Lock()
+			defer cm.mu.Unlock()
+			mc.refs--
+			cm.closeRetired(mc)
+		})
+	}
+	return mc.client, release, nil
+}
+
+// Chains returns the ids of the chains managed, in ascending order
+func (cm *ClientManager) Chains() []uint64 {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	ids := make([]uint64, 0, len(cm.chains))
+	for cid := range cm.chains {
+		ids = append(ids, cid)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Set adds the chain or replaces its client if the config changed
+// The old client keeps serving if the new one can't be created
+func (cm *ClientManager) Set(chainID uint64, config *client.ChainConfiguration) error {
+	cm.mu.Lock()
+	if mc, ok := cm.chains[chainID]; ok && reflect.DeepEqual(mc.config, *config) {
+		cm.mu.Unlock()
+		return nil
+	}
+	cm.mu.Unlock()
+
+	cli, err := NewClient(config)
+	if err != nil {
+		return fmt.Errorf("create client of chain=%d failed, err=%w", chainID, err)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.closed {
+		closeClient(cli)
+		return fmt.Errorf("client manager is closed")
+	}
+	if old, ok := cm.chains[chainID]; ok {
+		cm.retire(old)
+	}
+	cm.chains[chainID] = &managedClient{config: *config, client: cli}
+	return nil
+}
+
+// Remove removes the chain and closes its client once it's released
+func (cm *ClientManager) Remove(chainID uint64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if mc, ok := cm.chains[chainID]; ok {
+		delete(cm.chains, chainID)
+		cm.retire(mc)
+	}
+}
+
+// Reload makes the chains managed match configs, the unchanged chains keep their clients
+// The chains failed to be created keep the old clients, the first error is returned
+func (cm *ClientManager) Reload(configs map[uint64]*client.ChainConfiguration) error {
+	var firstErr error
+	for cid, config := range configs {
+		if err := cm.Set(cid, config); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, cid := range cm.Chains() {
+		if _, ok := configs[cid]; !ok {
+			cm.Remove(cid)
+		}
+	}
+	return firstErr
+}
+
+// WatchFile loads the chain configs from a json or yaml file, then checks the file every interval
+// and reloads the chains when it's modified, the watching stops when the manager is closed
+func (cm *ClientManager) WatchFile(path string, interval time.Duration) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file failed, err=%w", err)
+	}
+	configs, err := LoadChainConfigs(path)
+	if err != nil {
+		return err
+	}
+	if err := cm.Reload(configs); err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.closed {
+		return fmt.Errorf("client manager is closed")
+	}
+	cm.wg.Add(1)
+	go cm.watch(path, interval, info)
+	return nil
+}
+
+func (cm *ClientManager) watch(path string, interval time.Duration, last os.FileInfo) {
+	defer cm.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cm.stop:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("stat chain config file=%s failed, err=%s\n", path, err)
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		configs, err := LoadChainConfigs(path)
+		if err != nil {
+			log.Printf("load chain config file=%s failed, err=%s\n", path, err)
+			continue
+		}
+		if err := cm.Reload(configs); err != nil {
+			log.Printf("reload chain config file=%s failed, err=%s\n", path, err)
+		}
+	}
+}
+
+// Close stops watching the config files and closes all the clients without waiting for the
+// grace period of GetClient, the clients still acquired are closed when they are released
+func (cm *ClientManager) Close() error {
+	cm.mu.Lock()
+	if cm.closed {
+		cm.mu.Unlock()
+		return nil
+	}
+	cm.closed = true
+	close(cm.stop)
+	var firstErr error
+	for cid, mc := range cm.chains {
+		delete(cm.chains, cid)
+		mc.shared = false
+		if err := cm.retire(mc); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for mc, timer := range cm.retiring {
+		timer.Stop()
+		if err := cm.endGrace(mc); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	cm.mu.Unlock()
+
+	cm.wg.Wait()
+	return firstErr
+}
+
+// retire marks the client as replaced, must be called with mu held
+// A client returned by GetClient is held for the grace period before it can be closed
+func (cm *ClientManager) retire(mc *managedClient) error {
+	mc.retired = true
+	if mc.shared {
+		mc.refs++
+		cm.retiring[mc] = time.AfterFunc(cm.grace, func() {
+			cm.mu.Lock()
+			defer cm.mu.Unlock()
+			cm.endGrace(mc)
+		})
+		return nil
+	}
+	return cm.closeRetired(mc)
+}
+
+// endGrace drops the hold of the grace period and closes the client if it's not used, must be called with mu held
+func (cm *ClientManager) endGrace(mc *managedClient) error {
+	if _, ok := cm.retiring[mc]; !ok {
+		return nil
+	}
+	delete(cm.retiring, mc)
+	mc.refs--
+	return cm.closeRetired(mc)
+}
+
+// closeRetired closes the client if it's retired and not used, must be called with mu held
+func (cm *ClientManager) closeRetired(mc *managedClient) error {
+	if !mc.retired || mc.refs > 0 {
+		return nil
+	}
+	if err := closeClient(mc.client); err != nil {
+		log.Printf("close client of chain=%s failed, err=%s\n", mc.config.ChainName, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package clients
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/clienttest"
+)
+
+func tronConfig(endpoint string) *client.ChainConfiguration {
+	return &client.ChainConfiguration{
+		ChainID:   big.NewInt(728126428),
+		ChainName: "Tron",
+		Endpoints: []string{endpoint + "/jsonrpc", endpoint, endpoint},
+	}
+}
+
+func TestClientManagerReplace(t *testing.T) {
+	cm, err := NewClientManager(map[uint64]*client.ChainConfiguration{
+		728126428: tronConfig("http://127.0.0.1:1"),
+	})
+	assert.Nil(t, err, "create manager failed")
+	defer cm.Close()
+
+	old, release, err := cm.Acquire(728126428)
+	assert.Nil(t, err, "acquire client failed")
+	defer release()
+
+	assert.Nil(t, cm.Set(728126428, tronConfig("http://127.0.0.1:1")), "set same config failed")
+	cli, err := cm.GetClient(728126428)
+	assert.Nil(t, err, "get client failed")
+	assert.Same(t, old, cli, "unchanged config should keep the client")
+
+	assert.Nil(t, cm.Set(728126428, tronConfig("http://127.0.0.1:2")), "replace config failed")
+	cli, err = cm.GetClient(728126428)
+	assert.Nil(t, err, "get client failed")
+	assert.NotSame(t, old, cli, "changed config should replace the client")
+
+	cm.Remove(728126428)
+	_, err = cm.GetClient(728126428)
+	assert.NotNil(t, err, "removed chain should not be found")
+}
+
+// closingClient is a fake client recording whether it's closed
+type closingClient struct {
+	*clienttest.Fake
+	closed int32
+}
+
+func (c *closingClient) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func (c *closingClient) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+func TestClientManagerRetire(t *testing.T) {
+	RegisterDriver("closing", func(config *client.ChainConfiguration) (client.BlockChainClient, error) {
+		return &closingClient{Fake: clienttest.NewFake(clienttest.FormatEVM)}, nil
+	})
+	config := func(name string) *client.ChainConfiguration {
+		return &client.ChainConfiguration{ChainID: big.NewInt(1), ChainName: name, ChainType: "closing"}
+	}
+	cm, err := NewClientManager(map[uint64]*client.ChainConfiguration{1: config("v1")})
+	assert.Nil(t, err, "create manager failed")
+	defer cm.Close()
+	cm.grace = 50 * time.Millisecond
+
+	// the client returned by GetClient keeps open for the grace period
+	cli, err := cm.GetClient(1)
+	assert.Nil(t, err, "get client failed")
+	old := cli.(*closingClient)
+	assert.Nil(t, cm.Set(1, config("v2")), "replace config failed")
+	_, err = old.BalanceAt("0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8")
+	assert.Nil(t, err, "old client should be usable")
+	assert.False(t, old.isClosed(), "old client should not be closed in the grace period")
+	assert.Eventually(t, old.isClosed, time.Second, 10*time.Millisecond, "old client should be closed after the grace period")
+
+	// the client acquired keeps open after the grace period until it's released
+	cli, release, err := cm.Acquire(1)
+	assert.Nil(t, err, "acquire client failed")
+	acquired := cli.(*closingClient)
+	cm.GetClient(1)
+	cm.Remove(1)
+	time.Sleep(3 * cm.grace)
+	_, err = acquired.BalanceAt("0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8")
+	assert.Nil(t, err, "acquired client should be usable")
+	assert.False(t, acquired.isClosed(), "acquired client should not be closed")
+	release()
+	assert.True(t, acquired.isClosed(), "released client should be closed")
+
+	// the manager closes the retired clients without waiting for the grace period
+	assert.Nil(t, cm.Set(1, config("v3")), "set config failed")
+	cli, _ = cm.GetClient(1)
+	assert.Nil(t, cm.Set(1, config("v4")), "replace config failed")
+	cm.Close()
+	assert.True(t, cli.(*closingClient).isClosed(), "retired client should be closed with the manager")
+}
+
+func TestClientManagerWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chains.yaml")
+	write := func(content string) {
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644), "write file failed")
+	}
+	write(`
+- chainId: 1
+  chainName: Ethereum
+  endpoints: ["http://127.0.0.1:1"]
+  timeout: 10s
+- chainId: 728126428
+  chainName: Tron
+  endpoints: ["http://127.0.0.1:1/jsonrpc", "http://127.0.0.1:1", "http://127.0.0.1:1"]
+`)
+	cm, err := NewClientManager(nil)
+	assert.Nil(t, err, "create manager failed")
+	defer cm.Close()
+	assert.Nil(t, cm.WatchFile(path, 10*time.Millisecond), "watch file failed")
+	assert.Equal(t, []uint64{1, 728126428}, cm.Chains(), "chains not match")
+
+	write(`
+- chainId: 56
+  chainName: BSC
+  endpoints: ["http://127.0.0.1:1"]
+`)
+	// make sure the modification is noticed on file systems with coarse mtime
+	os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	assert.Eventually(t, func() bool {
+		chains := cm.Chains()
+		return len(chains) == 1 && chains[0] == 56
+	}, time.Second, 10*time.Millisecond, "chains should be reloaded")
+
+	// a bad edit is rejected and the chains are kept
+	write(`
+- chainId: 728126428
+  chainName: Tron
+  endpoints: ["http://127.0.0.1:1/jsonrpc"]
+`)
+	os.Chtimes(path, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []uint64{56}, cm.Chains(), "chains should be kept")
+}
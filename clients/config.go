@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/tron"
+)
+
+// chainConfigFile is the format of a chain in the config file, the durations are
+// written like "10s" or "1m", and the fees are decimal strings in wei
+type chainConfigFile struct {
+	ChainID             uint64   `json:"chainId" yaml:"chainId"`
+	ChainName           string   `json:"chainName" yaml:"chainName"`
+	ChainType           string   `json:"chainType" yaml:"chainType"`
+	ChainLogo           string   `json:"chainLogo" yaml:"chainLogo"`
+	Currency            string   `json:"currency" yaml:"currency"`
+	Endpoints           []string `json:"endpoints" yaml:"endpoints"`
+	SupportEIP1559      bool     `json:"supportEIP1559" yaml:"supportEIP1559"`
+	APIKey              string   `json:"apiKey" yaml:"apiKey"`
+	Timeout             string   `json:"timeout" yaml:"timeout"`
+	HealthCheckInterval string   `json:"healthCheckInterval" yaml:"healthCheckInterval"`
+	MaxBlockLag         uint64   `json:"maxBlockLag" yaml:"maxBlockLag"`
+	Multicall3Address   string   `json:"multicall3Address" yaml:"multicall3Address"`
+	MinGasTipCap        string   `json:"minGasTipCap" yaml:"minGasTipCap"`
+	MaxGasFeeCap        string   `json:"maxGasFeeCap" yaml:"maxGasFeeCap"`
+}
+
+// LoadChainConfigs reads a list of chains from a .json, .yaml or .yml file, keyed by chain id
+func LoadChainConfigs(path string) (map[uint64]*client.ChainConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file failed, err=%w", err)
+	}
+	var chains []chainConfigFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &chains)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &chains)
+	default:
+		return nil, fmt.Errorf("config file type=%s is not supported", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse file=%s failed, err=%w", path, err)
+	}
+
+	configs := make(map[uint64]*client.ChainConfiguration, len(chains))
+	for _, c := range chains {
+		if _, ok := configs[c.ChainID]; ok {
+			return nil, fmt.Errorf("chain=%d is duplicated", c.ChainID)
+		}
+		config := &client.ChainConfiguration{
+			ChainID:           new(big.Int).SetUint64(c.ChainID),
+			ChainName:         c.ChainName,
+			ChainType:         c.ChainType,
+			ChainLogo:         c.ChainLogo,
+			Currency:          c.Currency,
+			Endpoints:         c.Endpoints,
+			SupportEIP1559:    c.SupportEIP1559,
+			APIKey:            c.APIKey,
+			MaxBlockLag:       c.MaxBlockLag,
+			Multicall3Address: c.Multicall3Address,
+		}
+		if err := checkEndpoints(config); err != nil {
+			return nil, fmt.Errorf("chain=%d %w", c.ChainID, err)
+		}
+		if config.Timeout, err = parseDuration(c.Timeout); err != nil {
+			return nil, fmt.Errorf("parse timeout of chain=%d failed, err=%w", c.ChainID, err)
+		}
+		if config.HealthCheckInterval, err = parseDuration(c.HealthCheckInterval); err != nil {
+			return nil, fmt.Errorf("parse healthCheckInterval of chain=%d failed, err=%w", c.ChainID, err)
+		}
+		if config.MinGasTipCap, err = parseWei(c.MinGasTipCap); err != nil {
+			return nil, fmt.Errorf("parse minGasTipCap of chain=%d failed, err=%w", c.ChainID, err)
+		}
+		if config.MaxGasFeeCap, err = parseWei(c.MaxGasFeeCap); err != nil {
+			return nil, fmt.Errorf("parse maxGasFeeCap of chain=%d failed, err=%w", c.ChainID, err)
+		}
+		configs[c.ChainID] = config
+	}
+	return configs, nil
+}
+
+// checkEndpoints checks the number of endpoints needed by the chain type of config,
+// so a bad config is rejected when it's loaded instead of when the client is created
+func checkEndpoints(config *client.ChainConfiguration) error {
+	switch {
+	case len(config.Endpoints) == 0:
+		return fmt.Errorf("has no endpoint")
+	case chainType(config) == client.ChainTypeTron && len(config.Endpoints) < tron.EndpointCount:
+		return fmt.Errorf("needs %d tron endpoints, got=%d", tron.EndpointCount, len(config.Endpoints))
+	}
+	return nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func parseWei(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount=%s", s)
+	}
+	return value, nil
+}
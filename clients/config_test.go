@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadChainConfigs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chains.yaml")
+	write := func(content string) {
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644), "write file failed")
+	}
+	write(`
+- chainId: 1
+  chainName: Ethereum
+  endpoints: ["http://127.0.0.1:1"]
+  timeout: 10s
+- chainId: 728126428
+  chainName: Tron
+  endpoints: ["http://127.0.0.1:1/jsonrpc", "http://127.0.0.1:1", "http://127.0.0.1:1"]
+`)
+	configs, err := LoadChainConfigs(path)
+	assert.Nil(t, err, "load configs failed")
+	assert.Len(t, configs, 2)
+	assert.Equal(t, "Tron", configs[728126428].ChainName)
+
+	write(`
+- chainId: 1
+  chainName: Ethereum
+`)
+	_, err = LoadChainConfigs(path)
+	assert.NotNil(t, err, "chain without endpoint should fail")
+
+	// tron needs the json-rpc, full node and trongrid endpoints
+	write(`
+- chainId: 728126428
+  chainName: Tron
+  endpoints: ["http://127.0.0.1:1/jsonrpc"]
+`)
+	_, err = LoadChainConfigs(path)
+	assert.NotNil(t, err, "tron with one endpoint should fail")
+	write(`
+- chainId: 2494104990
+  chainName: Shasta
+  chainType: tron
+  endpoints: ["http://127.0.0.1:1/jsonrpc", "http://127.0.0.1:1"]
+`)
+	_, err = LoadChainConfigs(path)
+	assert.NotNil(t, err, "tron type with two endpoints should fail")
+}
@@ -0,0 +1,81 @@
+package clients
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/eth"
+	"git.bipal.space/shared-lib/blockchain/tron"
+)
+
+// Driver creates the client of a chain type
+type Driver func(config *client.ChainConfiguration) (client.BlockChainClient, error)
+
+var (
+	drivers     = make(map[string]Driver)
+	driversLock sync.RWMutex
+)
+
+func init() {
+	RegisterDriver(client.ChainTypeEVM, func(config *client.ChainConfiguration) (client.BlockChainClient, error) {
+		cli, err := eth.NewEthClient(config)
+		if err != nil {
+			return nil, err
+		}
+		cli.SupportEIP1559 = config.SupportEIP1559
+		return cli, nil
+	})
+	RegisterDriver(client.ChainTypeTron, func(config *client.ChainConfiguration) (client.BlockChainClient, error) {
+		return tron.NewTronClient(config)
+	})
+}
+
+// RegisterDriver makes the driver available for the configs with ChainType set to name
+// Registering an existing name replaces the driver, including the built-in ones
+func RegisterDriver(name string, factory Driver) {
+	if factory == nil {
+		panic("clients: RegisterDriver driver is nil")
+	}
+	driversLock.Lock()
+	defer driversLock.Unlock()
+	drivers[name] = factory
+}
+
+// Drivers returns the names of the registered drivers in ascending order
+func Drivers() []string {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// chainType returns the chain type of config, the configs without ChainType are
+// treated as before: "Tron" chain is tron and the others are evm
+func chainType(config *client.ChainConfiguration) string {
+	if config.ChainType != "" {
+		return config.ChainType
+	}
+	if config.ChainName == "Tron" {
+		return client.ChainTypeTron
+	}
+	return client.ChainTypeEVM
+}
+
+// NewClient creates the client by the driver registered for the chain type of config
+func NewClient(config *client.ChainConfiguration) (client.BlockChainClient, error) {
+	name := chainType(config)
+	driversLock.RLock()
+	factory, ok := drivers[name]
+	driversLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("chain type=%s of chain=%s is not supported", name, config.ChainName)
+	}
+	return factory(config)
+}
@@ -0,0 +1,36 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/eth"
+	"git.bipal.space/shared-lib/blockchain/tron"
+)
+
+func TestRegisterDriver(t *testing.T) {
+	var created *client.ChainConfiguration
+	RegisterDriver("mock", func(config *client.ChainConfiguration) (client.BlockChainClient, error) {
+		created = config
+		return nil, nil
+	})
+	config := &client.ChainConfiguration{ChainName: "Mock", ChainType: "mock"}
+	_, err := NewClient(config)
+	assert.Nil(t, err, "create client failed")
+	assert.Same(t, config, created, "registered driver should be used")
+	assert.Contains(t, Drivers(), "mock", "driver should be listed")
+
+	_, err = NewClient(&client.ChainConfiguration{ChainName: "Unknown", ChainType: "unknown"})
+	assert.NotNil(t, err, "unknown chain type should fail")
+
+	cli, err := NewClient(tronConfig("http://127.0.0.1:1"))
+	assert.Nil(t, err, "create tron client failed")
+	assert.IsType(t, &tron.TronClient{}, cli, "Tron chain should use tron driver")
+
+	cli, err = NewClient(&client.ChainConfiguration{ChainName: "Ethereum", Endpoints: []string{"http://127.0.0.1:1"}})
+	assert.Nil(t, err, "create eth client failed")
+	assert.IsType(t, &eth.EthClient{}, cli, "other chains should use evm driver")
+	closeClient(cli)
+}
@@ -0,0 +1,257 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// fakeTx is the transaction returned by GetTransaction, the hash to sign is the keccak256 of its json
+type fakeTx struct {
+	From    common.Address
+	To      *common.Address
+	Amount  *big.Int
+	Data    []byte
+	Nonce   uint64
+	ChainID *big.Int
+	Fee     *client.FeeLimit
+}
+
+func (f *Fake) newTx(td *client.Transaction, to *common.Address) ([]byte, []byte, error) {
+	from, err := f.parse(td.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrong from address, err=%w", err)
+	}
+	tx := fakeTx{From: from, To: to, Amount: td.Amount, Data: td.Data, Nonce: td.Nonce, ChainID: f.chainID, Fee: td.Fee}
+	if tx.Amount == nil {
+		tx.Amount = new(big.Int)
+	}
+	message, err := json.Marshal(&tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode message failed, err=%w", err)
+	}
+	return message, crypto.Keccak256(message), nil
+}
+
+// send validates the transaction like the mempool of a node, and mines it if auto mining is on
+func (f *Fake) send(trans []byte) (common.Hash, error) {
+	tx := fakeTx{}
+	if err := json.Unmarshal(trans, &tx); err != nil {
+		return common.Hash{}, fmt.Errorf("parse transaction failed, err=%w", err)
+	}
+	hash := common.BytesToHash(crypto.Keccak256(trans))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.txs[hash]; ok {
+		return common.Hash{}, fmt.Errorf("already known")
+	}
+	nonce := f.nonces[tx.From]
+	if tx.Nonce < nonce {
+		return common.Hash{}, client.NewError(client.ErrNonceTooLow,
+			fmt.Errorf("nonce too low: address %s, tx: %d state: %d", tx.From.Hex(), tx.Nonce, nonce))
+	}
+	if tx.Nonce > nonce {
+		return common.Hash{}, client.NewError(client.ErrNonceTooHigh,
+			fmt.Errorf("nonce too high: address %s, tx: %d state: %d", tx.From.Hex(), tx.Nonce, nonce))
+	}
+	if f.balanceOf(tx.From).Cmp(tx.Amount) < 0 {
+		return common.Hash{}, client.NewError(client.ErrInsufficientFunds,
+			fmt.Errorf("insufficient funds for transfer: address %s", tx.From.Hex()))
+	}
+	f.nonces[tx.From] = nonce + 1
+
+	td := f.toTransaction(&tx)
+	f.txs[hash] = &client.TransactionInfo{Tx: td, IsPending: true, Logs: []*client.EventLog{}}
+	f.sent = append(f.sent, hash)
+	f.pending = append(f.pending, hash)
+	if f.autoMine {
+		f.mine()
+	}
+	return hash, nil
+}
+
+func (f *Fake) toTransaction(tx *fakeTx) *client.Transaction {
+	td := &client.Transaction{
+		From:    f.addressString(tx.From),
+		Amount:  tx.Amount,
+		Data:    tx.Data,
+		Nonce:   tx.Nonce,
+		ChainID: tx.ChainID,
+		Fee:     tx.Fee,
+	}
+	if tx.To != nil {
+		td.To = f.addressString(*tx.To)
+	}
+	return td
+}
+
+// mine executes the pending transactions in a new block, must be called with mu held
+func (f *Fake) mine() {
+	f.blockNumber++
+	// the index of a log is counted in the block
+	var logIndex uint
+	for _, hash := range f.pending {
+		info := f.txs[hash]
+		info.IsPending = false
+		info.BlockNumber = new(big.Int).SetUint64(f.blockNumber)
+		logs, err := f.execute(info.Tx)
+		if err != nil {
+			info.Status, info.Error = client.TransactionStatusFailed, err.Error()
+		} else {
+			info.Status, info.Logs = client.TransactionStatusSuccess, logs
+			for _, log := range logs {
+				log.BlockNumber, log.TxHash, log.LogIndex = f.blockNumber, hash.Hex(), logIndex
+				logIndex++
+			}
+		}
+		info.Gas = f.gasInfo(info.Tx)
+	}
+	f.pending = nil
+}
+
+// execute applies the transaction to the state, the state is kept if it fails
+func (f *Fake) execute(td *client.Transaction) ([]*client.EventLog, error) {
+	from := f.mustParse(td.From)
+	if f.balanceOf(from).Cmp(td.Amount) < 0 {
+		return nil, fmt.Errorf("insufficient funds for transfer")
+	}
+	var logs []*client.EventLog
+	if td.To == "" {
+		// contract creation, the contract does nothing unless it's simulated by SetContract
+		contract := crypto.CreateAddress(from, td.Nonce)
+		if _, ok := f.contracts[contract]; !ok {
+			f.contracts[contract] = func(*client.Transaction) ([]byte, []*client.EventLog, error) { return nil, nil, nil }
+		}
+		td.To = f.addressString(contract)
+	} else if len(td.Data) > 0 {
+		var err error
+		if logs, _, err = f.call(td, true); err != nil {
+			return nil, fmt.Errorf("execution reverted: %w", err)
+		}
+	}
+	to := f.mustParse(td.To)
+	f.balances[from] = new(big.Int).Sub(f.balanceOf(from), td.Amount)
+	f.balances[to] = new(big.Int).Add(f.balanceOf(to), td.Amount)
+	return logs, nil
+}
+
+// call runs the contract, the state of tokens is changed only if write is set, must be called with mu held
+func (f *Fake) call(td *client.Transaction, write bool) ([]*client.EventLog, []byte, error) {
+	contract, err := f.parse(td.To)
+	if err != nil {
+		return nil, nil, err
+	}
+	if handler, ok := f.contracts[contract]; ok {
+		output, logs, err := handler(td)
+		return logs, output, err
+	}
+	t, ok := f.tokens[contract]
+	if !ok || len(td.Data) < 4 {
+		// calling an account without code succeeds with nothing returned
+		return nil, nil, nil
+	}
+	method, err := erc20.MethodById(td.Data[:4])
+	if err != nil {
+		return nil, nil, fmt.Errorf("method not found")
+	}
+	args, err := method.Inputs.Unpack(td.Data[4:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unpack arguments failed, err=%w", err)
+	}
+	sender := f.mustParse(td.From)
+	var logs []*client.EventLog
+	var result []interface{}
+	switch method.Name {
+	case "balanceOf":
+		result = []interface{}{t.balanceOf(args[0].(common.Address))}
+	case "allowance":
+		result = []interface{}{t.allowance(args[0].(common.Address), args[1].(common.Address))}
+	case "decimals":
+		result = []interface{}{t.decimals}
+	case "symbol":
+		result = []interface{}{t.symbol}
+	case "totalSupply":
+		result = []interface{}{t.totalSupply()}
+	case "transfer":
+		to, value := args[0].(common.Address), args[1].(*big.Int)
+		if t.balanceOf(sender).Cmp(value) < 0 {
+			return nil, nil, fmt.Errorf("transfer amount exceeds balance")
+		}
+		if write {
+			t.transfer(sender, to, value)
+			logs = append(logs, f.tokenLog(contract, "Transfer", sender, to, value))
+		}
+		result = []interface{}{true}
+	case "approve":
+		spender, value := args[0].(common.Address), args[1].(*big.Int)
+		if write {
+			t.setAllowance(sender, spender, value)
+			logs = append(logs, f.tokenLog(contract, "Approval", sender, spender, value))
+		}
+		result = []interface{}{true}
+	case "transferFrom":
+		owner, to, value := args[0].(common.Address), args[1].(common.Address), args[2].(*big.Int)
+		allowance := t.allowance(owner, sender)
+		if allowance.Cmp(value) < 0 {
+			return nil, nil, fmt.Errorf("insufficient allowance")
+		}
+		if t.balanceOf(owner).Cmp(value) < 0 {
+			return nil, nil, fmt.Errorf("transfer amount exceeds balance")
+		}
+		if write {
+			t.setAllowance(owner, sender, new(big.Int).Sub(allowance, value))
+			t.transfer(owner, to, value)
+			logs = append(logs, f.tokenLog(contract, "Transfer", owner, to, value))
+		}
+		result = []interface{}{true}
+	}
+	output, err := method.Outputs.Pack(result...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pack result failed, err=%w", err)
+	}
+	return logs, output, nil
+}
+
+// tokenLog builds the Transfer or Approval log encoded as the evm does
+func (f *Fake) tokenLog(contract common.Address, name string, from, to common.Address, value *big.Int) *client.EventLog {
+	event := erc20.Events[name]
+	data, _ := event.Inputs.NonIndexed().Pack(value)
+	return &client.EventLog{
+		Address: f.addressString(contract),
+		Topics:  [][]byte{event.ID.Bytes(), common.LeftPadBytes(from.Bytes(), 32), common.LeftPadBytes(to.Bytes(), 32)},
+		Data:    data,
+	}
+}
+
+// gasPrice returns the effective gas price of the fee, must be called with mu held
+func (f *Fake) gasPrice(fee *client.FeeLimit) *big.Int {
+	price := new(big.Int).Add(f.baseFee, f.tipCap)
+	if fee != nil && fee.GasFeeCap != nil && fee.GasFeeCap.Sign() > 0 && fee.GasFeeCap.Cmp(price) < 0 {
+		price = new(big.Int).Set(fee.GasFeeCap)
+	}
+	return price
+}
+
+func (f *Fake) estimateGas(td *client.Transaction) uint64 {
+	if len(td.Data) == 0 {
+		return defaultGasLimit
+	}
+	return defaultContractGas
+}
+
+// gasInfo returns the gas used by the transaction, must be called with mu held
+func (f *Fake) gasInfo(td *client.Transaction) *client.TxGasInfo {
+	gasUsed := new(big.Int).SetUint64(f.estimateGas(td))
+	gasPrice := f.gasPrice(td.Fee)
+	return &client.TxGasInfo{
+		Fee:      new(big.Int).Mul(gasUsed, gasPrice),
+		GasPrice: gasPrice,
+		GasUsed:  gasUsed,
+	}
+}
@@ -0,0 +1,594 @@
+package clienttest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+var (
+	_ client.BlockChainClient    = (*Fake)(nil)
+	_ client.BlockChainClientCtx = (*Fake)(nil)
+	_ client.MessageVerifier     = (*Fake)(nil)
+)
+
+func (f *Fake) BalanceAt(address string) (*big.Int, error) {
+	return f.BalanceAtContext(context.Background(), address)
+}
+
+func (f *Fake) BalanceAtContext(ctx context.Context, address string) (*big.Int, error) {
+	if err := f.before(ctx, "BalanceAt"); err != nil {
+		return nil, err
+	}
+	addr, err := f.parse(address)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(f.balanceOf(addr)), nil
+}
+
+func (f *Fake) BalanceOf(contract, from string) (*big.Int, error) {
+	return f.BalanceOfContext(context.Background(), contract, from)
+}
+
+func (f *Fake) BalanceOfContext(ctx context.Context, contract, from string) (*big.Int, error) {
+	if err := f.before(ctx, "BalanceOf"); err != nil {
+		return nil, err
+	}
+	t, err := f.token(contract)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := f.parse(from)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(t.balanceOf(addr)), nil
+}
+
+func (f *Fake) DecimalsOf(contract string) (uint8, error) {
+	return f.DecimalsOfContext(context.Background(), contract)
+}
+
+func (f *Fake) DecimalsOfContext(ctx context.Context, contract string) (uint8, error) {
+	if err := f.before(ctx, "DecimalsOf"); err != nil {
+		return 0, err
+	}
+	t, err := f.token(contract)
+	if err != nil {
+		return 0, err
+	}
+	return t.decimals, nil
+}
+
+func (f *Fake) TotalSupplyOf(contract string) (*big.Int, error) {
+	return f.TotalSupplyOfContext(context.Background(), contract)
+}
+
+func (f *Fake) TotalSupplyOfContext(ctx context.Context, contract string) (*big.Int, error) {
+	if err := f.before(ctx, "TotalSupplyOf"); err != nil {
+		return nil, err
+	}
+	t, err := f.token(contract)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return t.totalSupply(), nil
+}
+
+func (f *Fake) SymbolOf(contract string) (string, error) {
+	return f.SymbolOfContext(context.Background(), contract)
+}
+
+func (f *Fake) SymbolOfContext(ctx context.Context, contract string) (string, error) {
+	if err := f.before(ctx, "SymbolOf"); err != nil {
+		return "", err
+	}
+	t, err := f.token(contract)
+	if err != nil {
+		return "", err
+	}
+	return t.symbol, nil
+}
+
+func (f *Fake) GetNonce(address string) (uint64, error) {
+	return f.GetNonceContext(context.Background(), address)
+}
+
+// GetNonceContext returns the next nonce, including the pending transactions
+func (f *Fake) GetNonceContext(ctx context.Context, address string) (uint64, error) {
+	if err := f.before(ctx, "GetNonce"); err != nil {
+		return 0, err
+	}
+	addr, err := f.parse(address)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nonces[addr], nil
+}
+
+func (f *Fake) GetNonceByNumber(address string, blockNumber *big.Int) (uint64, error) {
+	return f.GetNonceByNumberContext(context.Background(), address, blockNumber)
+}
+
+// GetNonceByNumberContext returns the current nonce, the history is not kept
+func (f *Fake) GetNonceByNumberContext(ctx context.Context, address string, blockNumber *big.Int) (uint64, error) {
+	if err := f.before(ctx, "GetNonceByNumber"); err != nil {
+		return 0, err
+	}
+	addr, err := f.parse(address)
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nonces[addr], nil
+}
+
+func (f *Fake) Allowance(contract, owner, spender string) (*big.Int, error) {
+	return f.AllowanceContext(context.Background(), contract, owner, spender)
+}
+
+func (f *Fake) AllowanceContext(ctx context.Context, contract, owner, spender string) (*big.Int, error) {
+	if err := f.before(ctx, "Allowance"); err != nil {
+		return nil, err
+	}
+	t, err := f.token(contract)
+	if err != nil {
+		return nil, err
+	}
+	ownerAddr, err := f.parse(owner)
+	if err != nil {
+		return nil, err
+	}
+	spenderAddr, err := f.parse(spender)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(t.allowance(ownerAddr, spenderAddr)), nil
+}
+
+// BalancesOf reads the items one by one, the failures set by FailNext for "BalanceOf" and
+// "BalanceAt" are returned as the errors of the items
+func (f *Fake) BalancesOf(pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	return f.BalancesOfContext(context.Background(), pairs)
+}
+
+func (f *Fake) BalancesOfContext(ctx context.Context, pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	if err := f.before(ctx, "BalancesOf"); err != nil {
+		return nil, err
+	}
+	results := make([]client.BalanceResult, len(pairs))
+	for i, p := range pairs {
+		results[i].OwnerToken = p
+		if p.Token == "" {
+			results[i].Balance, results[i].Err = f.BalanceAtContext(ctx, p.Owner)
+		} else {
+			results[i].Balance, results[i].Err = f.BalanceOfContext(ctx, p.Token, p.Owner)
+		}
+	}
+	return results, nil
+}
+
+func (f *Fake) Allowances(queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	return f.AllowancesContext(context.Background(), queries)
+}
+
+func (f *Fake) AllowancesContext(ctx context.Context, queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	if err := f.before(ctx, "Allowances"); err != nil {
+		return nil, err
+	}
+	results := make([]client.AllowanceResult, len(queries))
+	for i, q := range queries {
+		results[i].AllowanceQuery = q
+		results[i].Allowance, results[i].Err = f.AllowanceContext(ctx, q.Token, q.Owner, q.Spender)
+	}
+	return results, nil
+}
+
+func (f *Fake) TokenMetadata(contracts []string) ([]client.TokenMetadata, error) {
+	return f.TokenMetadataContext(context.Background(), contracts)
+}
+
+func (f *Fake) TokenMetadataContext(ctx context.Context, contracts []string) ([]client.TokenMetadata, error) {
+	if err := f.before(ctx, "TokenMetadata"); err != nil {
+		return nil, err
+	}
+	results := make([]client.TokenMetadata, len(contracts))
+	for i, contract := range contracts {
+		m := &results[i]
+		m.Contract = contract
+		if m.Symbol, m.Err = f.SymbolOfContext(ctx, contract); m.Err != nil {
+			continue
+		}
+		if m.Decimals, m.Err = f.DecimalsOfContext(ctx, contract); m.Err != nil {
+			continue
+		}
+		m.TotalSupply, m.Err = f.TotalSupplyOfContext(ctx, contract)
+	}
+	return results, nil
+}
+
+func (f *Fake) TransferData(to string, amount *big.Int) ([]byte, error) {
+	toAddr, err := f.parse(to)
+	if err != nil {
+		return nil, err
+	}
+	return erc20.Pack("transfer", toAddr, amount)
+}
+
+func (f *Fake) ApproveData(contract, owner, spender string, amount *big.Int) ([]byte, error) {
+	spenderAddr, err := f.parse(spender)
+	if err != nil {
+		return nil, err
+	}
+	return erc20.Pack("approve", spenderAddr, amount)
+}
+
+func (f *Fake) AbiConvertToInt(v interface{}) *big.Int {
+	return *abi.ConvertType(v, new(*big.Int)).(**big.Int)
+}
+
+func (f *Fake) AbiConvertToString(v interface{}) string {
+	return *abi.ConvertType(v, new(string)).(*string)
+}
+
+func (f *Fake) AbiConvertToBytes(v interface{}) []byte {
+	return *abi.ConvertType(v, new([]byte)).(*[]byte)
+}
+
+func (f *Fake) AbiConvertToAddress(v interface{}) string {
+	return f.addressString(*abi.ConvertType(v, new(common.Address)).(*common.Address))
+}
+
+func (f *Fake) GetTransactionData(method string, abiDesc string, args ...interface{}) ([]byte, error) {
+	if abiDesc == "" {
+		return nil, fmt.Errorf("empty abi")
+	}
+	methodAbi, err := abi.JSON(strings.NewReader(abiDesc))
+	if err != nil {
+		return nil, fmt.Errorf("parse abi failed, err=%w", err)
+	}
+	return methodAbi.Pack(method, args...)
+}
+
+func (f *Fake) RegisterABI(name, abiStr string) error {
+	compiled, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return err
+	}
+	f.abiMap.Store(name, &compiled)
+	return nil
+}
+
+func (f *Fake) GetABIByName(name string) (*abi.ABI, error) {
+	compiled, ok := f.abiMap.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("abi=%s not found", name)
+	}
+	return compiled.(*abi.ABI), nil
+}
+
+func (f *Fake) GetTransactionDataByABI(method, abiName string, args ...interface{}) ([]byte, error) {
+	compiled, err := f.GetABIByName(abiName)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Pack(method, args...)
+}
+
+func (f *Fake) UnpackByABI(method, name string, data []byte) ([]interface{}, error) {
+	compiled, err := f.GetABIByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Unpack(method, data)
+}
+
+func (f *Fake) GetSuggestFee(td *client.Transaction) (*client.FeeLimit, error) {
+	return f.GetSuggestFeeContext(context.Background(), td)
+}
+
+func (f *Fake) GetSuggestFeeContext(ctx context.Context, td *client.Transaction) (*client.FeeLimit, error) {
+	if err := f.before(ctx, "GetSuggestFee"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &client.FeeLimit{
+		Gas:       new(big.Int).SetUint64(f.estimateGas(td)),
+		GasFeeCap: f.gasPrice(nil),
+		GasTipCap: new(big.Int).Set(f.tipCap),
+	}, nil
+}
+
+func (f *Fake) EstimateGas(td *client.Transaction) (uint64, error) {
+	return f.EstimateGasContext(context.Background(), td)
+}
+
+// EstimateGasContext returns 21000 for transfers and 60000 for contract calls,
+// the calls reverted by the contract fail like a node does
+func (f *Fake) EstimateGasContext(ctx context.Context, td *client.Transaction) (uint64, error) {
+	if err := f.before(ctx, "EstimateGas"); err != nil {
+		return 0, err
+	}
+	if len(td.Data) > 0 && td.To != "" {
+		f.mu.Lock()
+		_, _, err := f.call(td, false)
+		f.mu.Unlock()
+		if err != nil {
+			return 0, &client.RevertError{Reason: err.Error()}
+		}
+	}
+	return f.estimateGas(td), nil
+}
+
+func (f *Fake) GetGasPrice() (*big.Int, *big.Int, error) {
+	return f.GetGasPriceContext(context.Background())
+}
+
+func (f *Fake) GetGasPriceContext(ctx context.Context) (*big.Int, *big.Int, error) {
+	if err := f.before(ctx, "GetGasPrice"); err != nil {
+		return nil, nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gasPrice(nil), new(big.Int).Set(f.tipCap), nil
+}
+
+func (f *Fake) GetSuggestGasPrice() (*big.Int, *big.Int, *big.Int, error) {
+	return f.GetSuggestGasPriceContext(context.Background())
+}
+
+// GetSuggestGasPriceContext returns the base fee, tip and gas price
+func (f *Fake) GetSuggestGasPriceContext(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
+	if err := f.before(ctx, "GetSuggestGasPrice"); err != nil {
+		return nil, nil, nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(f.baseFee), new(big.Int).Set(f.tipCap), f.gasPrice(nil), nil
+}
+
+func (f *Fake) DeployContract(contractAbi, contractBin string, td *client.Transaction) ([]byte, []byte, string, error) {
+	return f.DeployContractContext(context.Background(), contractAbi, contractBin, td)
+}
+
+// DeployContractContext generates the transaction creating a contract, the contract does nothing
+// once deployed unless it's simulated by SetContract
+func (f *Fake) DeployContractContext(ctx context.Context, contractAbi, contractBin string, td *client.Transaction) ([]byte, []byte, string, error) {
+	if err := f.before(ctx, "DeployContract"); err != nil {
+		return nil, nil, "", err
+	}
+	deploy := *td
+	deploy.Data = common.FromHex(contractBin)
+	message, hash, err := f.newTx(&deploy, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	from := f.mustParse(td.From)
+	return message, hash, f.addressString(crypto.CreateAddress(from, td.Nonce)), nil
+}
+
+func (f *Fake) GetTransaction(td *client.Transaction) ([]byte, []byte, error) {
+	return f.GetTransactionContext(context.Background(), td)
+}
+
+// GetTransactionContext generates the transaction, the hash is deterministic for the same td
+func (f *Fake) GetTransactionContext(ctx context.Context, td *client.Transaction) ([]byte, []byte, error) {
+	if err := f.before(ctx, "GetTransaction"); err != nil {
+		return nil, nil, err
+	}
+	to, err := f.parse(td.To)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrong to address, err=%w", err)
+	}
+	return f.newTx(td, &to)
+}
+
+func (f *Fake) BroadcastTransaction(trans []byte, signature []byte) ([]byte, error) {
+	return f.BroadcastTransactionContext(context.Background(), trans, signature)
+}
+
+// BroadcastTransactionContext sends the transaction generated by GetTransaction or DeployContract,
+// the signature is not verified
+func (f *Fake) BroadcastTransactionContext(ctx context.Context, trans []byte, signature []byte) ([]byte, error) {
+	if err := f.before(ctx, "BroadcastTransaction"); err != nil {
+		return nil, err
+	}
+	hash, err := f.send(trans)
+	if err != nil {
+		return nil, err
+	}
+	return hash.Bytes(), nil
+}
+
+func (f *Fake) CallContract(td *client.Transaction) ([]byte, error) {
+	return f.CallContractContext(context.Background(), td)
+}
+
+func (f *Fake) CallContractContext(ctx context.Context, td *client.Transaction) ([]byte, error) {
+	if err := f.before(ctx, "CallContract"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, output, err := f.call(td, false)
+	if err != nil {
+		return nil, &client.RevertError{Reason: err.Error()}
+	}
+	return output, nil
+}
+
+func (f *Fake) GetTransactionByHash(transactionHash string) (*client.TransactionInfo, error) {
+	return f.GetTransactionByHashContext(context.Background(), transactionHash)
+}
+
+func (f *Fake) GetTransactionByHashContext(ctx context.Context, transactionHash string) (*client.TransactionInfo, error) {
+	if err := f.before(ctx, "GetTransactionByHash"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, ok := f.txs[common.HexToHash(transactionHash)]
+	if !ok {
+		return nil, client.NewError(client.ErrNotFound, fmt.Errorf("transaction %s not found", transactionHash))
+	}
+	copied := *info
+	tx := *info.Tx
+	copied.Tx = &tx
+	return &copied, nil
+}
+
+func (f *Fake) GetLatestBlockNumber() (*big.Int, error) {
+	return f.GetLatestBlockNumberContext(context.Background())
+}
+
+func (f *Fake) GetLatestBlockNumberContext(ctx context.Context) (*big.Int, error) {
+	if err := f.before(ctx, "GetLatestBlockNumber"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).SetUint64(f.blockNumber), nil
+}
+
+// ParseEventLog unpacks the non-indexed fields of the log, the logs are evm encoded in both address formats
+func (f *Fake) ParseEventLog(abiName string, eventLog *client.EventLog) ([]interface{}, error) {
+	compiled, err := f.GetABIByName(abiName)
+	if err != nil {
+		return nil, err
+	}
+	if len(eventLog.Topics) == 0 {
+		return nil, fmt.Errorf("no topic found")
+	}
+	event, err := compiled.EventByID(common.BytesToHash(eventLog.Topics[0]))
+	if err != nil {
+		return nil, fmt.Errorf("get event from id failed, err=%w", err)
+	}
+	return event.Inputs.NonIndexed().Unpack(eventLog.Data)
+}
+
+func (f *Fake) AddressFromPrivateKey(privateKey string) (string, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("wrong private key, err=%w", err)
+	}
+	return f.AddressFromPublicKey(&key.PublicKey)
+}
+
+func (f *Fake) AddressFromPublicKey(pubKey *ecdsa.PublicKey) (string, error) {
+	return f.addressString(crypto.PubkeyToAddress(*pubKey)), nil
+}
+
+func (f *Fake) AddressFromString(addr string) (common.Address, error) {
+	return f.parse(addr)
+}
+
+func (f *Fake) AddressToString(addr common.Address) string {
+	return f.addressString(addr)
+}
+
+func (f *Fake) ContractAddress(addr common.Address) (bool, error) {
+	return f.ContractAddressContext(context.Background(), addr)
+}
+
+func (f *Fake) ContractAddressContext(ctx context.Context, addr common.Address) (bool, error) {
+	if err := f.before(ctx, "ContractAddress"); err != nil {
+		return false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, isToken := f.tokens[addr]
+	_, isContract := f.contracts[addr]
+	return isToken || isContract, nil
+}
+
+func (f *Fake) IsValidAddress(address string) bool {
+	addr, err := f.parse(address)
+	return err == nil && addr != (common.Address{})
+}
+
+func (f *Fake) IsNativeAsset(address string) bool {
+	addr, err := f.parse(address)
+	return err == nil && addr == (common.Address{})
+}
+
+func (f *Fake) NativeAssetAddress() string {
+	return f.addressString(common.Address{})
+}
+
+func (f *Fake) PublicKeyHexToAddress(publicKey string) (string, error) {
+	buffer, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("decode public key failed, err=%w", err)
+	}
+	pubKey, err := crypto.UnmarshalPubkey(buffer)
+	if err != nil {
+		return "", fmt.Errorf("unmarshal public key failed, err=%w", err)
+	}
+	return f.AddressFromPublicKey(pubKey)
+}
+
+// VerifyMessage verifies the message signed with personal_sign, or signMessageV2 of TronWeb for FormatTron
+func (f *Fake) VerifyMessage(address string, message, signature []byte) (bool, error) {
+	addr, err := f.parse(address)
+	if err != nil {
+		return false, err
+	}
+	prefix := client.EthereumMessagePrefix
+	if f.format == FormatTron {
+		prefix = client.TronMessagePrefix
+	}
+	signer, err := client.RecoverHash(client.HashMessage(prefix, message), signature)
+	if err != nil {
+		return false, err
+	}
+	return signer == addr, nil
+}
+
+func (f *Fake) NormalizeAddress(address string) string {
+	addr, err := f.parse(address)
+	if err != nil {
+		return address
+	}
+	return f.addressString(addr)
+}
+
+func (f *Fake) NativeAssetDecimals() uint8 {
+	return f.decimals
+}
+
+// token returns the token added by AddToken, calling a token not added fails like calling an account without code
+func (f *Fake) token(contract string) (*token, error) {
+	addr, err := f.parse(contract)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tokens[addr]
+	if !ok {
+		return nil, fmt.Errorf("token=%s not found", contract)
+	}
+	return t, nil
+}
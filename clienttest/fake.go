@@ -0,0 +1,313 @@
+// Package clienttest provides an in-memory client.BlockChainClient for unit tests,
+// so services built on the client can be tested without a live node
+package clienttest
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// AddressFormat decides how the fake formats the addresses it returns
+// both formats are accepted as input whichever format is used
+type AddressFormat int
+
+const (
+	// FormatEVM formats addresses as checksummed hex, such as "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	FormatEVM AddressFormat = iota
+	// FormatTron formats addresses as base58, such as "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	FormatTron
+)
+
+const (
+	// erc20Abi is the standard erc20 abi including the events, it's registered as "erc20" and "trc20"
+	erc20Abi     = `[{"inputs":[],"name":"totalSupply","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"account","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"name":"approve","outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transferFrom","outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"},{"indexed":true,"name":"spender","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Approval","type":"event"}]`
+	erc20ABIName = "erc20"
+	trc20ABIName = "trc20"
+
+	defaultGasLimit    = 21000
+	defaultContractGas = 60000
+)
+
+var erc20, _ = abi.JSON(strings.NewReader(erc20Abi))
+
+// ContractHandler simulates a contract, it's called for both CallContract and the transactions sent to the contract
+// The logs are recorded in the receipt of a transaction, an error reverts the transaction
+type ContractHandler func(td *client.Transaction) (output []byte, logs []*client.EventLog, err error)
+
+// token keeps the state of an erc20 or trc20 contract
+type token struct {
+	symbol     string
+	decimals   uint8
+	balances   map[common.Address]*big.Int
+	allowances map[common.Address]map[common.Address]*big.Int
+}
+
+// Fake implements client.BlockChainClient in memory
+// The transactions are not verified against the signature, fees are recorded in the receipt but not charged
+type Fake struct {
+	format   AddressFormat
+	chainID  *big.Int
+	decimals uint8
+	abiMap   sync.Map
+
+	mu          sync.Mutex
+	balances    map[common.Address]*big.Int
+	nonces      map[common.Address]uint64
+	tokens      map[common.Address]*token
+	contracts   map[common.Address]ContractHandler
+	txs         map[common.Hash]*client.TransactionInfo
+	sent        []common.Hash
+	pending     []common.Hash
+	failures    map[string][]error
+	blockNumber uint64
+	baseFee     *big.Int
+	tipCap      *big.Int
+	autoMine    bool
+}
+
+// NewFake creates an empty chain, the chain id and native decimals follow ethereum mainnet
+// for FormatEVM and tron mainnet for FormatTron
+func NewFake(format AddressFormat) *Fake {
+	f := &Fake{
+		format:      format,
+		chainID:     big.NewInt(1),
+		decimals:    18,
+		balances:    make(map[common.Address]*big.Int),
+		nonces:      make(map[common.Address]uint64),
+		tokens:      make(map[common.Address]*token),
+		contracts:   make(map[common.Address]ContractHandler),
+		txs:         make(map[common.Hash]*client.TransactionInfo),
+		failures:    make(map[string][]error),
+		blockNumber: 1,
+		baseFee:     big.NewInt(1000000000),
+		tipCap:      big.NewInt(1000000000),
+		autoMine:    true,
+	}
+	if format == FormatTron {
+		f.chainID = big.NewInt(728126428)
+		f.decimals = 6
+		f.baseFee = big.NewInt(420)
+		f.tipCap = big.NewInt(0)
+	}
+	f.RegisterABI(erc20ABIName, erc20Abi)
+	f.RegisterABI(trc20ABIName, erc20Abi)
+	return f
+}
+
+// SetBalance sets the native balance of an address
+func (f *Fake) SetBalance(addr string, amount *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[f.mustParse(addr)] = new(big.Int).Set(amount)
+}
+
+// SetNonce sets the nonce of an address
+func (f *Fake) SetNonce(addr string, nonce uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nonces[f.mustParse(addr)] = nonce
+}
+
+// AddToken deploys a token contract with the symbol and decimals
+func (f *Fake) AddToken(contract, symbol string, decimals uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens[f.mustParse(contract)] = &token{
+		symbol:     symbol,
+		decimals:   decimals,
+		balances:   make(map[common.Address]*big.Int),
+		allowances: make(map[common.Address]map[common.Address]*big.Int),
+	}
+}
+
+// SetTokenBalance sets the token balance of an address, the token must be added by AddToken
+func (f *Fake) SetTokenBalance(contract, addr string, amount *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mustToken(contract).balances[f.mustParse(addr)] = new(big.Int).Set(amount)
+}
+
+// SetAllowance sets the amount of token the spender can transfer from owner
+func (f *Fake) SetAllowance(contract, owner, spender string, amount *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mustToken(contract).setAllowance(f.mustParse(owner), f.mustParse(spender), amount)
+}
+
+// SetContract simulates a contract by handler
+func (f *Fake) SetContract(contract string, handler ContractHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contracts[f.mustParse(contract)] = handler
+}
+
+// SetGasPrice sets the base fee and tip returned by the gas price methods
+func (f *Fake) SetGasPrice(baseFee, tipCap *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.baseFee, f.tipCap = new(big.Int).Set(baseFee), new(big.Int).Set(tipCap)
+}
+
+// SetAutoMine decides whether the broadcasted transactions are mined at once
+// Without auto mining the transactions stay pending until Mine is called
+func (f *Fake) SetAutoMine(autoMine bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.autoMine = autoMine
+}
+
+// Mine mines the pending transactions in a new block
+func (f *Fake) Mine() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mine()
+}
+
+// Drop removes a pending transaction as if the node evicted it from the mempool
+func (f *Fake) Drop(hash string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := common.HexToHash(hash)
+	for i := range f.pending {
+		if f.pending[i] == h {
+			td := f.txs[h].Tx
+			from := f.mustParse(td.From)
+			if f.nonces[from] == td.Nonce+1 {
+				f.nonces[from] = td.Nonce
+			}
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			delete(f.txs, h)
+			return
+		}
+	}
+}
+
+// FailNext makes the next call of method return err, method is the name without the Context
+// suffix, such as "BroadcastTransaction". Multiple errors are returned in order
+func (f *Fake) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], err)
+}
+
+// Sent returns the hashes of the broadcasted transactions in order
+func (f *Fake) Sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hashes := make([]string, 0, len(f.sent))
+	for _, h := range f.sent {
+		hashes = append(hashes, h.Hex())
+	}
+	return hashes
+}
+
+// before is called at the beginning of every method talking to the chain
+func (f *Fake) before(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if errs := f.failures[method]; len(errs) > 0 {
+		f.failures[method] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+// parse accepts hex, base58 and tron hex ("41...") addresses
+func (f *Fake) parse(addr string) (common.Address, error) {
+	if common.IsHexAddress(addr) {
+		return common.HexToAddress(addr), nil
+	}
+	if len(addr) == 42 && strings.HasPrefix(addr, "41") {
+		if b, err := hex.DecodeString(addr); err == nil {
+			return common.BytesToAddress(b[1:]), nil
+		}
+	}
+	tronAddr, err := address.Base58ToAddress(addr)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid address=%s", addr)
+	}
+	return common.BytesToAddress(tronAddr.Bytes()[1:]), nil
+}
+
+// mustParse is used by the setup methods, an invalid address is a bug of the test
+func (f *Fake) mustParse(addr string) common.Address {
+	a, err := f.parse(addr)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (f *Fake) mustToken(contract string) *token {
+	t, ok := f.tokens[f.mustParse(contract)]
+	if !ok {
+		panic(fmt.Sprintf("token=%s not added", contract))
+	}
+	return t
+}
+
+func (f *Fake) addressString(addr common.Address) string {
+	if f.format == FormatTron {
+		return address.Address(append([]byte{address.TronBytePrefix}, addr.Bytes()...)).String()
+	}
+	return addr.Hex()
+}
+
+func (f *Fake) balanceOf(addr common.Address) *big.Int {
+	if b, ok := f.balances[addr]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+func (t *token) balanceOf(addr common.Address) *big.Int {
+	if b, ok := t.balances[addr]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+func (t *token) allowance(owner, spender common.Address) *big.Int {
+	if b, ok := t.allowances[owner][spender]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+func (t *token) setAllowance(owner, spender common.Address, amount *big.Int) {
+	if t.allowances[owner] == nil {
+		t.allowances[owner] = make(map[common.Address]*big.Int)
+	}
+	t.allowances[owner][spender] = new(big.Int).Set(amount)
+}
+
+func (t *token) totalSupply() *big.Int {
+	total := new(big.Int)
+	for _, b := range t.balances {
+		total.Add(total, b)
+	}
+	return total
+}
+
+func (t *token) transfer(from, to common.Address, amount *big.Int) error {
+	balance := t.balanceOf(from)
+	if balance.Cmp(amount) < 0 {
+		return fmt.Errorf("transfer amount exceeds balance")
+	}
+	t.balances[from] = new(big.Int).Sub(balance, amount)
+	t.balances[to] = new(big.Int).Add(t.balanceOf(to), amount)
+	return nil
+}
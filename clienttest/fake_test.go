@@ -0,0 +1,111 @@
+package clienttest
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	usdt  = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	alice = "0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8"
+	bob   = "0x2A6c6b4a1F3e33b1b2E4B0e4c5e4d2F8a1D2E3f4"
+)
+
+// send generates, signs and broadcasts the transaction like the callers of BlockChainClient do
+func send(t *testing.T, c client.BlockChainClient, td *client.Transaction) string {
+	tx, _, err := c.GetTransaction(td)
+	assert.Nil(t, err, "get transaction failed")
+	hash, err := c.BroadcastTransaction(tx, make([]byte, 65))
+	assert.Nil(t, err, "broadcast transaction failed")
+	return hexutil.Encode(hash)
+}
+
+func TestTokenTransfer(t *testing.T) {
+	for _, format := range []AddressFormat{FormatEVM, FormatTron} {
+		f := NewFake(format)
+		f.AddToken(usdt, "USDT", 6)
+		f.SetTokenBalance(usdt, alice, big.NewInt(100))
+
+		var c client.BlockChainClient = f
+		from, to := c.NormalizeAddress(alice), c.NormalizeAddress(bob)
+		contract := c.NormalizeAddress(usdt)
+		data, err := c.TransferData(to, big.NewInt(40))
+		assert.Nil(t, err, "pack transfer failed")
+		hash := send(t, c, &client.Transaction{From: from, To: contract, Data: data})
+
+		info, err := c.GetTransactionByHash(hash)
+		assert.Nil(t, err, "get transaction failed")
+		assert.Equal(t, client.TransactionStatusSuccess, info.Status, "transfer should succeed")
+		assert.Len(t, info.Logs, 1, "transfer log expected")
+		assert.Equal(t, contract, info.Logs[0].Address, "log address not match")
+		fields, err := c.ParseEventLog("erc20", info.Logs[0])
+		assert.Nil(t, err, "parse log failed")
+		assert.Equal(t, big.NewInt(40), c.AbiConvertToInt(fields[0]), "log value not match")
+
+		balance, err := c.BalanceOf(contract, to)
+		assert.Nil(t, err, "get balance failed")
+		assert.Equal(t, big.NewInt(40), balance, "balance of receiver not match")
+		nonce, err := c.GetNonce(from)
+		assert.Nil(t, err, "get nonce failed")
+		assert.Equal(t, uint64(1), nonce, "nonce should be increased")
+
+		// the second transfer exceeds the balance and is reverted on chain
+		data, _ = c.TransferData(to, big.NewInt(100))
+		hash = send(t, c, &client.Transaction{From: from, To: contract, Data: data, Nonce: 1})
+		info, err = c.GetTransactionByHash(hash)
+		assert.Nil(t, err, "get transaction failed")
+		assert.Equal(t, client.TransactionStatusFailed, info.Status, "transfer should be reverted")
+		balance, _ = c.BalanceOf(contract, from)
+		assert.Equal(t, big.NewInt(60), balance, "balance should be kept")
+	}
+}
+
+func TestFailures(t *testing.T) {
+	f := NewFake(FormatEVM)
+	f.SetBalance(alice, big.NewInt(1000))
+	f.SetNonce(alice, 5)
+
+	tx, _, err := f.GetTransaction(&client.Transaction{From: alice, To: bob, Amount: big.NewInt(1), Nonce: 4})
+	assert.Nil(t, err, "get transaction failed")
+	_, err = f.BroadcastTransaction(tx, nil)
+	assert.ErrorIs(t, err, client.ErrNonceTooLow, "nonce too low expected")
+
+	tx, _, _ = f.GetTransaction(&client.Transaction{From: alice, To: bob, Amount: big.NewInt(2000), Nonce: 5})
+	_, err = f.BroadcastTransaction(tx, nil)
+	assert.ErrorIs(t, err, client.ErrInsufficientFunds, "insufficient funds expected")
+
+	injected := errors.New("connection reset")
+	f.FailNext("BalanceAt", injected)
+	_, err = f.BalanceAt(alice)
+	assert.ErrorIs(t, err, injected, "injected error expected")
+	balance, err := f.BalanceAt(alice)
+	assert.Nil(t, err, "only the next call should fail")
+	assert.Equal(t, big.NewInt(1000), balance, "balance not match")
+
+	_, err = f.GetTransactionByHash("0x1234")
+	assert.ErrorIs(t, err, client.ErrNotFound, "not found expected")
+}
+
+func TestPendingTransaction(t *testing.T) {
+	f := NewFake(FormatTron)
+	f.SetBalance(alice, big.NewInt(1000))
+	f.SetAutoMine(false)
+
+	hash := send(t, f, &client.Transaction{From: alice, To: bob, Amount: big.NewInt(10)})
+	info, err := f.GetTransactionByHash(hash)
+	assert.Nil(t, err, "get transaction failed")
+	assert.True(t, info.IsPending, "transaction should be pending")
+
+	f.Mine()
+	info, _ = f.GetTransactionByHash(hash)
+	assert.False(t, info.IsPending, "transaction should be mined")
+	balance, _ := f.BalanceAt(bob)
+	assert.Equal(t, big.NewInt(10), balance, "balance of receiver not match")
+	assert.Equal(t, []string{hash}, f.Sent(), "sent transactions not match")
+}
@@ -0,0 +1,78 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// ErrAccessListUnsupported is returned by CreateAccessList when the node doesn't support eth_createAccessList
+var ErrAccessListUnsupported = errors.New("access list not supported")
+
+// accessListResult is the result of eth_createAccessList, Error is the error of executing the call
+type accessListResult struct {
+	AccessList types.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	Error      string           `json:"error"`
+}
+
+// CreateAccessList returns the access list of td and the gas used with it
+func (e *EthClient) CreateAccessList(td *client.Transaction) (client.AccessList, uint64, error) {
+	return e.CreateAccessListContext(context.Background(), td)
+}
+
+// CreateAccessListContext returns the addresses and storage slots td accesses on the latest block by eth_createAccessList,
+// and the gas used by td with the access list, set it to td.AccessList to send td with it
+// ErrAccessListUnsupported is returned if the node doesn't support it
+func (e *EthClient) CreateAccessListContext(ctx context.Context, td *client.Transaction) (client.AccessList, uint64, error) {
+	b, ok := e.client.(rawBackend)
+	if !ok {
+		return nil, 0, ErrAccessListUnsupported
+	}
+	var result accessListResult
+	if err := b.CallContext(ctx, &result, "eth_createAccessList", callArg(td), "latest"); err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFound {
+			return nil, 0, ErrAccessListUnsupported
+		}
+		return nil, 0, fmt.Errorf("create access list failed, err=%w", e.decodeRevert(err))
+	}
+	if result.Error != "" {
+		return nil, 0, fmt.Errorf("create access list failed, err=%w", client.NewError(client.ErrReverted, errors.New(result.Error)))
+	}
+	return fromAccessList(result.AccessList), uint64(result.GasUsed), nil
+}
+
+func toAccessList(list client.AccessList) types.AccessList {
+	if len(list) == 0 {
+		return nil
+	}
+	result := make(types.AccessList, 0, len(list))
+	for _, tuple := range list {
+		keys := make([]common.Hash, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			keys = append(keys, common.HexToHash(key))
+		}
+		result = append(result, types.AccessTuple{Address: common.HexToAddress(tuple.Address), StorageKeys: keys})
+	}
+	return result
+}
+
+func fromAccessList(list types.AccessList) client.AccessList {
+	result := make(client.AccessList, 0, len(list))
+	for _, tuple := range list {
+		keys := make([]string, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			keys = append(keys, key.Hex())
+		}
+		result = append(result, client.AccessTuple{Address: tuple.Address.Hex(), StorageKeys: keys})
+	}
+	return result
+}
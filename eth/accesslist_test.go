@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// accessListHandler answers eth_createAccessList with a slot of batchToken, the calls to batchReverted fail
+func accessListHandler(t *testing.T) rpcHandler {
+	return func(method string, params []json.RawMessage) (interface{}, error) {
+		if method != "eth_createAccessList" {
+			return nil, &rpcError{code: -32601, message: "method not found"}
+		}
+		var arg struct {
+			To common.Address `json:"to"`
+		}
+		assert.Nil(t, json.Unmarshal(params[0], &arg), "decode call failed")
+		result := map[string]interface{}{
+			"accessList": []interface{}{map[string]interface{}{
+				"address":     batchToken,
+				"storageKeys": []string{common.BigToHash(big.NewInt(3)).Hex()},
+			}},
+			"gasUsed": "0xafc8",
+		}
+		if arg.To == common.HexToAddress(batchReverted) {
+			result["error"] = "execution reverted"
+		}
+		return result, nil
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	server := newRPCServer(t, accessListHandler(t))
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{Endpoints: []string{server.URL}, ChainID: big.NewInt(1)})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	list, gas, err := c.CreateAccessList(&client.Transaction{From: batchOwner, To: batchToken, Data: []byte{1, 2, 3, 4}})
+	assert.Nil(t, err, "create access list failed")
+	assert.Equal(t, uint64(45000), gas)
+	assert.Equal(t, client.AccessList{{
+		Address:     common.HexToAddress(batchToken).Hex(),
+		StorageKeys: []string{common.BigToHash(big.NewInt(3)).Hex()},
+	}}, list)
+
+	_, _, err = c.CreateAccessList(&client.Transaction{From: batchOwner, To: batchReverted, Data: []byte{1, 2, 3, 4}})
+	assert.ErrorIs(t, err, client.ErrReverted)
+
+	sim, err := NewSimulatedEthClient(core.GenesisAlloc{})
+	assert.Nil(t, err, "create client failed")
+	_, _, err = sim.CreateAccessList(&client.Transaction{From: batchOwner, To: batchToken})
+	assert.ErrorIs(t, err, ErrAccessListUnsupported)
+}
+
+func TestAccessListTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	// the chain without EIP-1559 sends an AccessListTx
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, sim)
+	assert.Nil(t, err, "create client failed")
+
+	td := &client.Transaction{
+		From:   owner.Hex(),
+		To:     batchOwner,
+		Amount: big.NewInt(1000),
+		AccessList: client.AccessList{{
+			Address:     batchToken,
+			StorageKeys: []string{common.BigToHash(big.NewInt(1)).Hex()},
+		}},
+	}
+	td.Fee, err = c.GetSuggestFee(td)
+	assert.Nil(t, err, "suggest fee failed")
+	// 2400 for the address and 1900 for the slot
+	assert.Equal(t, big.NewInt((21000+2400+1900)*120/100), td.Fee.Gas)
+	message, hash, err := c.GetTransaction(td)
+	assert.Nil(t, err, "get transaction failed")
+	sig, err := crypto.Sign(hash, key)
+	assert.Nil(t, err, "sign failed")
+	txHash, err := c.BroadcastTransaction(message, sig)
+	assert.Nil(t, err, "broadcast failed")
+
+	tx, _, err := sim.TransactionByHash(context.Background(), common.BytesToHash(txHash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, uint8(types.AccessListTxType), tx.Type())
+	assert.Equal(t, toAccessList(td.AccessList), tx.AccessList())
+	receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+	assert.Nil(t, err, "get receipt failed")
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+}
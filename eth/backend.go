@@ -0,0 +1,212 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// simulatedGasLimit is the block gas limit of the simulated chain
+const simulatedGasLimit = 30000000
+
+// Backend is the chain access used by EthClient
+// *ethclient.Client, *backends.SimulatedBackend and the node pool created by NewEthClient all satisfy it
+type Backend interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// NewEthClientWithBackend creates the client on top of backend, the errors of backend are
+// converted into the errors defined in client package
+func NewEthClientWithBackend(config *client.ChainConfiguration, backend Backend) (*EthClient, error) {
+	e, err := newEthClient(config)
+	if err != nil {
+		return nil, err
+	}
+	e.client = wrapBackend(backend)
+	return e, nil
+}
+
+// NewSimulatedEthClient creates a client on an in-memory chain with the accounts in alloc
+// The transactions are mined once they are broadcasted, EIP-1559 is supported
+func NewSimulatedEthClient(alloc core.GenesisAlloc) (*EthClient, error) {
+	config := &client.ChainConfiguration{
+		ChainID:        new(big.Int).Set(params.AllEthashProtocolChanges.ChainID),
+		ChainName:      "Simulated",
+		SupportEIP1559: true,
+	}
+	return NewEthClientWithBackend(config, backends.NewSimulatedBackend(alloc, simulatedGasLimit))
+}
+
+func wrapBackend(backend Backend) Backend {
+	switch b := backend.(type) {
+	case *nodePool, *errorBackend:
+		// the errors are converted already
+		return b
+	case *backends.SimulatedBackend:
+		return &errorBackend{backend: &simulatedBackend{b}, local: true}
+	}
+	return &errorBackend{backend: backend}
+}
+
+// simulatedBackend commits a block for every transaction sent, so the transaction
+// can be found as soon as it's broadcasted like on a real chain
+type simulatedBackend struct {
+	*backends.SimulatedBackend
+}
+
+func (s *simulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := s.SimulatedBackend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	s.Commit()
+	return nil
+}
+
+// errorBackend converts the errors of backend into the errors defined in client package
+// local is set for the simulated chain, whose errors are never transport errors
+type errorBackend struct {
+	backend Backend
+	local   bool
+}
+
+func (b *errorBackend) convert(ctx context.Context, err error) error {
+	err = toClientError(ctx, err)
+	var clientErr *client.Error
+	if b.local && errors.As(err, &clientErr) && clientErr.Kind == client.ErrTransport {
+		return clientErr.Err
+	}
+	return err
+}
+
+// Close closes the backend if it can be closed
+func (b *errorBackend) Close() error {
+	switch c := b.backend.(type) {
+	case interface{ Close() error }:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
+}
+
+func (b *errorBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	result, err := b.backend.BalanceAt(ctx, account, blockNumber)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	result, err := b.backend.CallContract(ctx, msg, blockNumber)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	result, err := b.backend.CodeAt(ctx, account, blockNumber)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	result, err := b.backend.PendingNonceAt(ctx, account)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	result, err := b.backend.NonceAt(ctx, account, blockNumber)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	result, err := b.backend.SuggestGasPrice(ctx)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	result, err := b.backend.SuggestGasTipCap(ctx)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	result, err := b.backend.EstimateGas(ctx, msg)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	result, err := b.backend.HeaderByNumber(ctx, number)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	h, ok := b.backend.(headerByHashBackend)
+	if !ok {
+		return nil, errHeaderByHashUnsupported
+	}
+	result, err := h.HeaderByHash(ctx, hash)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.convert(ctx, b.backend.SendTransaction(ctx, tx))
+}
+
+func (b *errorBackend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	tx, isPending, err := b.backend.TransactionByHash(ctx, hash)
+	return tx, isPending, b.convert(ctx, err)
+}
+
+func (b *errorBackend) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	result, err := b.backend.TransactionReceipt(ctx, hash)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	result, err := b.backend.FilterLogs(ctx, q)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	s, ok := b.backend.(subscribeBackend)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	result, err := s.SubscribeNewHead(ctx, ch)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	s, ok := b.backend.(subscribeBackend)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	result, err := s.SubscribeFilterLogs(ctx, q, ch)
+	return result, b.convert(ctx, err)
+}
+
+func (b *errorBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	f, ok := b.backend.(feeHistoryBackend)
+	if !ok {
+		return nil, errFeeHistoryUnsupported
+	}
+	result, err := f.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+	return result, b.convert(ctx, err)
+}
@@ -0,0 +1,92 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	bclient "git.bipal.space/shared-lib/blockchain/client"
+)
+
+func TestSimulatedEthClient(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x715d2B5aD8821BCabDE74EcEea85eA0296328Cb5")
+	client, err := NewSimulatedEthClient(core.GenesisAlloc{
+		from: {Balance: big.NewInt(1e18)},
+	})
+	assert.Nil(t, err, "create client failed")
+	defer client.Close()
+
+	balance, err := client.BalanceAt(from.Hex())
+	assert.Nil(t, err, "get balance failed")
+	assert.Equal(t, big.NewInt(1e18), balance, "balance not match")
+
+	td := &bclient.Transaction{From: from.Hex(), To: to.Hex(), Amount: big.NewInt(1000)}
+	td.Nonce, err = client.GetNonce(td.From)
+	assert.Nil(t, err, "get nonce failed")
+	td.Fee, err = client.GetSuggestFee(td)
+	assert.Nil(t, err, "get fee failed")
+	message, hash, err := client.GetTransaction(td)
+	assert.Nil(t, err, "get transaction failed")
+	sig, err := crypto.Sign(hash, key)
+	assert.Nil(t, err, "sign failed")
+	txHash, err := client.BroadcastTransaction(message, sig)
+	assert.Nil(t, err, "broadcast failed")
+
+	info, err := client.GetTransactionByHash(hexutil.Encode(txHash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.False(t, info.IsPending, "transaction should be mined")
+	assert.Equal(t, bclient.TransactionStatusSuccess, info.Status, "transaction should succeed")
+	assert.Equal(t, from.Hex(), info.Tx.From, "from not match")
+
+	balance, err = client.BalanceAt(to.Hex())
+	assert.Nil(t, err, "get balance failed")
+	assert.Equal(t, big.NewInt(1000), balance, "balance of receiver not match")
+	number, err := client.GetLatestBlockNumber()
+	assert.Nil(t, err, "get block number failed")
+	assert.Equal(t, big.NewInt(1), number, "block should be mined")
+
+	// the nonce is used already
+	_, err = client.BroadcastTransaction(message, sig)
+	assert.NotNil(t, err, "resending should fail")
+	_, err = client.GetTransactionByHash(common.Hash{}.Hex())
+	assert.ErrorIs(t, err, bclient.ErrNotFound, "not found expected")
+}
+
+func TestSignAndSend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := bclient.NewKeySigner(key)
+	to := common.HexToAddress("0x715d2B5aD8821BCabDE74EcEea85eA0296328Cb5")
+	client, err := NewSimulatedEthClient(core.GenesisAlloc{signer.Address(): {Balance: big.NewInt(1e18)}})
+	assert.Nil(t, err, "create client failed")
+	defer client.Close()
+
+	td := &bclient.Transaction{To: to.Hex(), Amount: big.NewInt(1000)}
+	txHash, err := client.SignAndSend(td, signer)
+	assert.Nil(t, err, "sign and send failed")
+	assert.Equal(t, signer.Address().Hex(), td.From, "from should be the signer")
+	info, err := client.GetTransactionByHash(hexutil.Encode(txHash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, bclient.TransactionStatusSuccess, info.Status, "transaction should succeed")
+
+	// the second transaction from the signer uses the next nonce
+	td = &bclient.Transaction{To: to.Hex(), Amount: big.NewInt(1000)}
+	txHash, err = client.SignAndSend(td, signer)
+	assert.Nil(t, err, "sign and send again failed")
+	assert.Equal(t, uint64(1), td.Nonce, "nonce should be the next one")
+	info, err = client.GetTransactionByHash(hexutil.Encode(txHash))
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, bclient.TransactionStatusSuccess, info.Status, "transaction should succeed")
+	balance, err := client.BalanceAt(to.Hex())
+	assert.Nil(t, err, "get balance failed")
+	assert.Equal(t, big.NewInt(2000), balance, "balance of receiver not match")
+
+	_, err = client.SignAndSend(&bclient.Transaction{From: to.Hex(), To: to.Hex(), Amount: big.NewInt(1)}, signer)
+	assert.NotNil(t, err, "sender not match should fail")
+}
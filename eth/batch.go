@@ -0,0 +1,234 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// maxBatchSize is the number of calls in one json-rpc batch, most providers reject larger batches
+const maxBatchSize = 100
+
+// batchBackend is implemented by the backends supporting json-rpc batch, such as the node pool
+type batchBackend interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// read is a call in a batch, it's eth_call of data to contract if contract is set,
+// otherwise eth_getBalance of account
+type read struct {
+	account  common.Address
+	contract *common.Address
+	data     []byte
+
+	output  []byte
+	balance *big.Int
+	err     error
+}
+
+func (r *read) elem() rpc.BatchElem {
+	if r.contract == nil {
+		return rpc.BatchElem{Method: "eth_getBalance", Args: []interface{}{r.account, "latest"}, Result: new(hexutil.Big)}
+	}
+	arg := map[string]interface{}{"from": r.account, "to": r.contract, "data": hexutil.Bytes(r.data)}
+	return rpc.BatchElem{Method: "eth_call", Args: []interface{}{arg, "latest"}, Result: new(hexutil.Bytes)}
+}
+
+// readAll executes the reads in json-rpc batches, or one by one if the backend doesn't support batch
+// the error is returned only when a whole batch failed, the errors of the reads are set in the reads
+func (e *EthClient) readAll(ctx context.Context, reads []*read) error {
+	b, ok := e.client.(batchBackend)
+	if !ok {
+		// the reads after ctx is done fail with the error of ctx, the reads done are kept
+		for _, r := range reads {
+			if err := ctx.Err(); err != nil {
+				r.err = err
+				continue
+			}
+			if r.contract == nil {
+				r.balance, r.err = e.client.BalanceAt(ctx, r.account, nil)
+			} else {
+				r.output, r.err = e.client.CallContract(ctx, ethereum.CallMsg{From: r.account, To: r.contract, Data: r.data}, nil)
+			}
+		}
+		return nil
+	}
+	for start := 0; start < len(reads); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(reads) {
+			end = len(reads)
+		}
+		elems := make([]rpc.BatchElem, 0, end-start)
+		for _, r := range reads[start:end] {
+			elems = append(elems, r.elem())
+		}
+		if err := b.BatchCallContext(ctx, elems); err != nil {
+			return fmt.Errorf("batch call failed, err=%w", err)
+		}
+		for i, r := range reads[start:end] {
+			if elems[i].Error != nil {
+				r.err = toClientError(ctx, elems[i].Error)
+				continue
+			}
+			switch result := elems[i].Result.(type) {
+			case *hexutil.Big:
+				r.balance = result.ToInt()
+			case *hexutil.Bytes:
+				r.output = *result
+			}
+		}
+	}
+	return nil
+}
+
+// erc20Read creates the read calling method of the erc20 contract
+func (e *EthClient) erc20Read(contract, from string, method string, args ...interface{}) *read {
+	contractAddr := common.HexToAddress(contract)
+	r := &read{account: common.HexToAddress(from), contract: &contractAddr}
+	r.data, r.err = e.GetTransactionDataByABI(method, erc20ABIName, args...)
+	return r
+}
+
+// unpack returns the first output of the erc20 method
+func (e *EthClient) unpack(r *read, method string) (interface{}, error) {
+	if r.err != nil {
+		return nil, fmt.Errorf("call %s failed, err=%w", method, r.err)
+	}
+	res, err := e.UnpackByABI(method, erc20ABIName, r.output)
+	if err != nil {
+		return nil, fmt.Errorf("unpack %s failed, err=%w", method, err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("wrong return format")
+	}
+	return res[0], nil
+}
+
+// pending returns the reads not failed yet, the reads failed to pack are not sent
+func pending(reads []*read) []*read {
+	result := make([]*read, 0, len(reads))
+	for _, r := range reads {
+		if r.err == nil {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// BalancesOf reads the balances of the owners in json-rpc batches
+func (e *EthClient) BalancesOf(pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	return e.BalancesOfContext(context.Background(), pairs)
+}
+
+// BalancesOfContext reads the balances of the owners in json-rpc batches
+func (e *EthClient) BalancesOfContext(ctx context.Context, pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	reads := make([]*read, len(pairs))
+	for i, p := range pairs {
+		if p.Token == "" {
+			reads[i] = &read{account: common.HexToAddress(p.Owner)}
+		} else {
+			reads[i] = e.erc20Read(p.Token, p.Owner, "balanceOf", common.HexToAddress(p.Owner))
+		}
+	}
+	if err := e.readAll(ctx, pending(reads)); err != nil {
+		return nil, err
+	}
+	results := make([]client.BalanceResult, len(pairs))
+	for i, r := range reads {
+		results[i].OwnerToken = pairs[i]
+		if r.contract == nil {
+			results[i].Balance, results[i].Err = r.balance, r.err
+			continue
+		}
+		value, err := e.unpack(r, "balanceOf")
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Balance = e.AbiConvertToInt(value)
+	}
+	return results, nil
+}
+
+// Allowances reads the allowances in json-rpc batches
+func (e *EthClient) Allowances(queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	return e.AllowancesContext(context.Background(), queries)
+}
+
+// AllowancesContext reads the allowances in json-rpc batches
+func (e *EthClient) AllowancesContext(ctx context.Context, queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	reads := make([]*read, len(queries))
+	for i, q := range queries {
+		reads[i] = e.erc20Read(q.Token, q.Owner, "allowance", common.HexToAddress(q.Owner), common.HexToAddress(q.Spender))
+	}
+	if err := e.readAll(ctx, pending(reads)); err != nil {
+		return nil, err
+	}
+	results := make([]client.AllowanceResult, len(queries))
+	for i, r := range reads {
+		results[i].AllowanceQuery = queries[i]
+		value, err := e.unpack(r, "allowance")
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Allowance = e.AbiConvertToInt(value)
+	}
+	return results, nil
+}
+
+// TokenMetadata reads the symbol, decimals and total supply of the contracts in json-rpc batches
+func (e *EthClient) TokenMetadata(contracts []string) ([]client.TokenMetadata, error) {
+	return e.TokenMetadataContext(context.Background(), contracts)
+}
+
+// TokenMetadataContext reads the symbol, decimals and total supply of the contracts in json-rpc batches
+func (e *EthClient) TokenMetadataContext(ctx context.Context, contracts []string) ([]client.TokenMetadata, error) {
+	methods := []string{"symbol", "decimals", "totalSupply"}
+	reads := make([]*read, 0, len(contracts)*len(methods))
+	for _, contract := range contracts {
+		for _, method := range methods {
+			reads = append(reads, e.erc20Read(contract, nativeAsset, method))
+		}
+	}
+	if err := e.readAll(ctx, pending(reads)); err != nil {
+		return nil, err
+	}
+	results := make([]client.TokenMetadata, len(contracts))
+	for i, contract := range contracts {
+		results[i].Contract = contract
+		values := make([]interface{}, len(methods))
+		for j, method := range methods {
+			value, err := e.unpack(reads[i*len(methods)+j], method)
+			if err != nil {
+				results[i].Err = err
+				break
+			}
+			values[j] = value
+		}
+		if results[i].Err != nil {
+			continue
+		}
+		results[i].Symbol = *abi.ConvertType(values[0], new(string)).(*string)
+		results[i].Decimals = toDecimals(values[1])
+		results[i].TotalSupply = e.AbiConvertToInt(values[2])
+	}
+	return results, nil
+}
+
+// toDecimals converts the output of decimals, some tokens return uint256 instead of uint8
+func toDecimals(value interface{}) uint8 {
+	if reflect.ValueOf(value).Kind() == reflect.Ptr {
+		return uint8((*abi.ConvertType(value, new(*big.Int)).(**big.Int)).Uint64())
+	}
+	return *abi.ConvertType(value, new(uint8)).(*uint8)
+}
@@ -0,0 +1,77 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	batchToken    = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	batchReverted = "0x0000000000000000000000000000000000000bad"
+	batchOwner    = "0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8"
+)
+
+// batchHandler answers eth_getBalance with 100 and eth_call of batchToken with 40, the calls to batchReverted revert
+func batchHandler(method string, params []json.RawMessage) (interface{}, error) {
+	switch {
+	case method == "eth_getBalance":
+		return "0x64", nil
+	case strings.Contains(strings.ToLower(string(params[0])), strings.ToLower(batchReverted)):
+		return nil, &rpcError{code: 3, message: "execution reverted"}
+	default:
+		return hexutil.Encode(common.LeftPadBytes(big.NewInt(40).Bytes(), 32)), nil
+	}
+}
+
+func TestBalancesOfBatch(t *testing.T) {
+	var requests int32
+	handler := rpcHTTPHandler(t, batchHandler)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{Endpoints: []string{server.URL}, ChainID: big.NewInt(1)})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	results, err := c.BalancesOf([]client.OwnerToken{
+		{Owner: batchOwner},
+		{Owner: batchOwner, Token: batchToken},
+		{Owner: batchOwner, Token: batchReverted},
+	})
+	assert.Nil(t, err, "batch failed")
+	assert.Len(t, results, 3)
+	assert.Equal(t, big.NewInt(100), results[0].Balance)
+	assert.Equal(t, big.NewInt(40), results[1].Balance)
+	assert.Equal(t, batchToken, results[1].Token)
+	assert.ErrorIs(t, results[2].Err, client.ErrReverted)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "reads should be sent in one batch")
+
+	allowances, err := c.Allowances([]client.AllowanceQuery{{Token: batchToken, Owner: batchOwner, Spender: batchOwner}})
+	assert.Nil(t, err, "batch failed")
+	assert.Equal(t, big.NewInt(40), allowances[0].Allowance)
+}
+
+func TestTokenMetadataBatch(t *testing.T) {
+	c, err := NewSimulatedEthClient(nil)
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+	// the simulated backend doesn't support batch, the reads are sent one by one
+	metadata, err := c.TokenMetadata([]string{batchToken})
+	assert.Nil(t, err, "batch failed")
+	assert.Len(t, metadata, 1)
+	assert.Equal(t, batchToken, metadata[0].Contract)
+	assert.NotNil(t, metadata[0].Err, "no contract deployed")
+}
@@ -0,0 +1,175 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// errorKinds maps the error messages of geth compatible nodes to the error kinds
+var errorKinds = []struct {
+	message string
+	kind    error
+}{
+	{"nonce too low", client.ErrNonceTooLow},
+	{"nonce too high", client.ErrNonceTooHigh},
+	{"insufficient funds", client.ErrInsufficientFunds},
+	{"underpriced", client.ErrUnderpriced},
+	{"max fee per gas less than block base fee", client.ErrUnderpriced},
+	{"fee cap less than block base fee", client.ErrUnderpriced},
+	{"rate limit", client.ErrRateLimited},
+	{"too many requests", client.ErrRateLimited},
+}
+
+// toClientError converts the error returned by node into the errors defined in client package
+func toClientError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var clientErr *client.Error
+	var revertErr *client.RevertError
+	if errors.As(err, &clientErr) || errors.As(err, &revertErr) {
+		return err
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return client.NewError(client.ErrNotFound, err)
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return client.NewError(client.ErrRateLimited, err)
+	}
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "execution reverted") {
+		return newRevertError(err)
+	}
+	for _, e := range errorKinds {
+		if strings.Contains(message, e.message) {
+			return client.NewError(e.kind, err)
+		}
+	}
+	if isTransportError(ctx, err) {
+		return client.NewError(client.ErrTransport, err)
+	}
+	return err
+}
+
+// newRevertError extracts the revert data from the json-rpc error
+func newRevertError(err error) *client.RevertError {
+	var data []byte
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hexData, ok := dataErr.ErrorData().(string); ok {
+			data, _ = hexutil.Decode(hexData)
+		}
+	}
+	revertErr := revertFromData(data)
+	if revertErr.Reason != "" {
+		return revertErr
+	}
+	if idx := strings.Index(err.Error(), "execution reverted: "); idx >= 0 {
+		revertErr.Reason = err.Error()[idx+len("execution reverted: "):]
+	}
+	return revertErr
+}
+
+// panicReasons are the descriptions of the Panic(uint256) codes of solidity
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized function",
+}
+
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// revertFromData creates the RevertError from the revert data returned by the contract
+// Error(string) and Panic(uint256) are decoded, the custom errors are decoded by EthClient.decodeRevert
+func revertFromData(data []byte) *client.RevertError {
+	revertErr := &client.RevertError{Data: data}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		revertErr.Reason, revertErr.ErrorName, revertErr.Args = reason, "Error", []interface{}{reason}
+		return revertErr
+	}
+	if len(data) == 4+32 && bytes.Equal(data[:4], panicSelector) {
+		code := new(big.Int).SetBytes(data[4:])
+		description, ok := panicReasons[code.Uint64()]
+		if !ok || !code.IsUint64() {
+			description = "unknown panic"
+		}
+		revertErr.Reason = fmt.Sprintf("panic: %s (0x%x)", description, code)
+		revertErr.ErrorName, revertErr.Args = "Panic", []interface{}{code}
+	}
+	return revertErr
+}
+
+// decodeRevert decodes the custom error of the RevertError in err by the errors of the registered abis
+// err is returned as it is
+func (e *EthClient) decodeRevert(err error) error {
+	var revertErr *client.RevertError
+	if !errors.As(err, &revertErr) || revertErr.ErrorName != "" || len(revertErr.Data) < 4 {
+		return err
+	}
+	e.abiMap.Range(func(_, value interface{}) bool {
+		parsed, ok := value.(*abi.ABI)
+		if !ok {
+			return true
+		}
+		for name, abiErr := range parsed.Errors {
+			if !bytes.Equal(abiErr.ID[:4], revertErr.Data[:4]) {
+				continue
+			}
+			unpacked, err := abiErr.Unpack(revertErr.Data)
+			if err != nil {
+				continue
+			}
+			args, _ := unpacked.([]interface{})
+			values := make([]string, 0, len(args))
+			for _, arg := range args {
+				values = append(values, fmt.Sprint(arg))
+			}
+			revertErr.ErrorName, revertErr.Args = name, args
+			revertErr.Reason = fmt.Sprintf("%s(%s)", name, strings.Join(values, ", "))
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// errStateUnavailable means the state to replay a transaction on is not available
+var errStateUnavailable = errors.New("state unavailable")
+
+// isStateUnavailable tells if err is returned because the node doesn't keep the state of the block
+func isStateUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errStateUnavailable) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, s := range []string{"missing trie node", "header not found", "historical state", "cannot access blocks other than the latest"} {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
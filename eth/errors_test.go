@@ -0,0 +1,171 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// dataError mocks the json-rpc error carrying revert data
+type dataError struct {
+	message string
+	data    string
+}
+
+func (e dataError) Error() string          { return e.message }
+func (e dataError) ErrorCode() int         { return 3 }
+func (e dataError) ErrorData() interface{} { return e.data }
+
+func TestToClientError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{errors.New("nonce too low"), client.ErrNonceTooLow},
+		{errors.New("nonce too high"), client.ErrNonceTooHigh},
+		{errors.New("insufficient funds for gas * price + value"), client.ErrInsufficientFunds},
+		{errors.New("replacement transaction underpriced"), client.ErrUnderpriced},
+		{errors.New("max fee per gas less than block base fee"), client.ErrUnderpriced},
+		{errors.New("daily request count exceeded, request rate limited"), client.ErrRateLimited},
+		{fmt.Errorf("get receipt failed, err=%w", ethereum.NotFound), client.ErrNotFound},
+		{errors.New("execution reverted"), client.ErrReverted},
+	}
+	for _, c := range cases {
+		err := toClientError(context.Background(), c.err)
+		assert.ErrorIs(t, err, c.kind, "kind of %s not match", c.err)
+		assert.Equal(t, c.err.Error(), err.Error(), "message should be kept")
+	}
+
+	err := toClientError(context.Background(), dataError{
+		message: "execution reverted: not allowed",
+		data: "0x08c379a00000000000000000000000000000000000000000000000000000000000000020" +
+			"000000000000000000000000000000000000000000000000000000000000000b6e6f7420616c6c6f776564000000000000000000000000000000000000000000",
+	})
+	var revertErr *client.RevertError
+	assert.True(t, errors.As(err, &revertErr), "revert error expected")
+	assert.Equal(t, "not allowed", revertErr.Reason, "revert reason not match")
+	assert.NotEmpty(t, revertErr.Data, "revert data should be kept")
+
+	// nothing listens on the port, the connection is refused
+	_, err = NewEthClient(&client.ChainConfiguration{Endpoints: []string{"ws://127.0.0.1:1"}, ChainID: big.NewInt(1)})
+	assert.ErrorIs(t, err, client.ErrTransport, "dial error should be transport")
+}
+
+func TestDecodeRevert(t *testing.T) {
+	c, err := NewSimulatedEthClient(core.GenesisAlloc{})
+	assert.Nil(t, err, "create client failed")
+
+	// Panic(0x11)
+	panicData := append(crypto.Keccak256([]byte("Panic(uint256)"))[:4], common.LeftPadBytes([]byte{0x11}, 32)...)
+	revertErr := revertFromData(panicData)
+	assert.Equal(t, "Panic", revertErr.ErrorName)
+	assert.Equal(t, "panic: arithmetic underflow or overflow (0x11)", revertErr.Reason)
+
+	// InsufficientBalance(uint256,uint256) of a registered abi
+	customABI := `[{"inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}],"name":"InsufficientBalance","type":"error"}]`
+	assert.Nil(t, c.RegisterABI("vault", customABI), "register abi failed")
+	parsed, _ := abi.JSON(strings.NewReader(customABI))
+	args, _ := parsed.Errors["InsufficientBalance"].Inputs.Pack(big.NewInt(100), big.NewInt(200))
+	id := parsed.Errors["InsufficientBalance"].ID
+	data := append(id[:4], args...)
+	err = c.decodeRevert(fmt.Errorf("call failed, err=%w", revertFromData(data)))
+	assert.ErrorIs(t, err, client.ErrReverted)
+	assert.True(t, errors.As(err, &revertErr), "revert error expected")
+	assert.Equal(t, "InsufficientBalance", revertErr.ErrorName)
+	assert.Equal(t, "InsufficientBalance(100, 200)", revertErr.Reason)
+	assert.Equal(t, big.NewInt(200), revertErr.Args[1])
+
+	// unknown custom error keeps the data only
+	revertErr = revertFromData([]byte{1, 2, 3, 4})
+	assert.Nil(t, c.decodeRevert(nil))
+	assert.Equal(t, "", c.decodeRevert(revertErr).(*client.RevertError).Reason)
+}
+
+func TestFailedTransactionRevert(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	parsed, _ := abi.JSON(strings.NewReader(strABI))
+	_, _, contract, err := bind.DeployContract(opts, parsed, common.FromHex(strBIN), sim)
+	assert.Nil(t, err, "deploy failed")
+	sim.Commit()
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, sim)
+	assert.Nil(t, err, "create client failed")
+
+	// transfer more than the balance with a fixed gas limit, so it's mined and fails
+	opts.GasLimit = 100000
+	tx, err := contract.Transact(opts, "transfer", common.HexToAddress(batchOwner), big.NewInt(1))
+	assert.Nil(t, err, "transfer failed")
+	sim.Commit()
+	info, err := c.GetTransactionByHash(tx.Hash().Hex())
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, client.TransactionStatusFailed, info.Status)
+	assert.NotNil(t, info.Revert, "revert expected")
+	assert.Equal(t, "ERC20: transfer amount exceeds balance", info.Revert.Reason)
+	assert.Contains(t, info.Error, "transfer amount exceeds balance")
+}
+
+func TestFailedTransactionRevertBeforeBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignNewTx(key, types.NewLondonSigner(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(2e9), GasFeeCap: big.NewInt(50e9),
+		Gas: 100000, To: &common.Address{1}, Data: []byte{1, 2, 3, 4},
+	})
+	assert.Nil(t, err, "sign transaction failed")
+	receipt := &types.Receipt{
+		Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, GasUsed: 30000, Logs: []*types.Log{},
+		TxHash: tx.Hash(), BlockNumber: big.NewInt(10), EffectiveGasPrice: big.NewInt(30e9),
+	}
+	server := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, error) {
+		switch method {
+		case "eth_getTransactionByHash":
+			var fields map[string]interface{}
+			data, _ := tx.MarshalJSON()
+			json.Unmarshal(data, &fields)
+			fields["blockNumber"], fields["blockHash"] = "0xa", common.Hash{2}.Hex()
+			return fields, nil
+		case "eth_getTransactionReceipt":
+			return receipt, nil
+		case "eth_call":
+			var call map[string]interface{}
+			assert.Nil(t, json.Unmarshal(params[0], &call), "decode call failed")
+			assert.Nil(t, call["gasPrice"], "dynamic fee transaction should not set gas price")
+			assert.Equal(t, "0xba43b7400", call["maxFeePerGas"])
+			assert.Equal(t, "0x77359400", call["maxPriorityFeePerGas"])
+			// the transaction reverts on the state before its block, but not after it
+			if string(params[1]) == `"0x9"` {
+				return nil, &rpcError{code: 3, message: "execution reverted: not allowed", data: revertNotAllowed}
+			}
+			return "0x", nil
+		}
+		return nil, &rpcError{code: -32601, message: "method not found"}
+	})
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{Endpoints: []string{server.URL}, ChainID: big.NewInt(1)})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	info, err := c.GetTransactionByHash(tx.Hash().Hex())
+	assert.Nil(t, err, "get transaction failed")
+	assert.Equal(t, client.TransactionStatusFailed, info.Status)
+	assert.NotNil(t, info.Revert, "revert expected")
+	assert.Equal(t, "not allowed", info.Revert.Reason)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -12,23 +13,29 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	ecrypto "github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"math/big"
-	"reflect"
 	"strings"
 	"sync"
 
 	"git.bipal.space/shared-lib/blockchain/client"
 )
 
+var (
+	_ client.BlockChainClient    = (*EthClient)(nil)
+	_ client.BlockChainClientCtx = (*EthClient)(nil)
+	_ client.MessageVerifier     = (*EthClient)(nil)
+)
+
 // EthClient implements BlockChain interface
 type EthClient struct {
-	client   *ethclient.Client
+	client   Backend
 	abiMap   sync.Map
 	erc20Abi *abi.ABI
 
 	chainID        *big.Int
-	debugClient    *backends.SimulatedBackend
+	multicall3     common.Address
+	minGasTipCap   *big.Int
+	maxGasFeeCap   *big.Int
 	SupportEIP1559 bool
 }
 
@@ -37,27 +44,56 @@ const (
 	maticNativeAsset    = "0x0000000000000000000000000000000000001010"
 	erc20ABIName        = "erc20"
 	nativeAssetDecimals = 18
+	// finalityConfirmations is the number of blocks after which a reorganization is unlikely
+	finalityConfirmations = 12
 	// erc20Abi is generate from erc20.abi file, just remove spaces and escape the double quotes
 	erc20Abi = "[{\"inputs\":[],\"name\":\"totalSupply\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"account\",\"type\":\"address\"}],\"name\":\"balanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"spender\",\"type\":\"address\"}],\"name\":\"allowance\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"symbol\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"decimals\",\"outputs\":[{\"internalType\":\"uint8\",\"name\":\"\",\"type\":\"uint8\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"spender\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"approve\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"transferFrom\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"name\":\"_to\",\"type\":\"address\"},{\"name\":\"_value\",\"type\":\"uint256\"}],\"name\":\"transfer\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
 )
 
 // NewEthClient creates and init the client for ethereum
 func NewEthClient(config *client.ChainConfiguration) (*EthClient, error) {
-	client := &EthClient{}
-	ethClient, err := ethclient.Dial(config.Endpoints[0])
+	pool, err := newNodePool(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to endpoint=%s", config.Endpoints[0])
+		return nil, err
+	}
+	client, err := newEthClient(config)
+	if err != nil {
+		pool.Close()
+		return nil, err
 	}
-	client.client = ethClient
+	client.client = pool
+	return client, nil
+}
+
+// newEthClient creates the client without backend
+func newEthClient(config *client.ChainConfiguration) (*EthClient, error) {
+	client := &EthClient{}
 	client.abiMap = sync.Map{}
 	if err := client.RegisterABI(erc20ABIName, erc20Abi); err != nil {
-		return nil, fmt.Errorf("register erc20 abi failed, err=%s", err)
+		return nil, fmt.Errorf("register erc20 abi failed, err=%w", err)
 	}
 	erc20, err := abi.JSON(strings.NewReader(erc20Abi))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse the abi, err=%s", err)
+		return nil, fmt.Errorf("failed to parse the abi, err=%w", err)
+	}
+	if err := client.RegisterABI(multicall3ABIName, multicall3Abi); err != nil {
+		return nil, fmt.Errorf("register multicall3 abi failed, err=%w", err)
+	}
+	if err := client.RegisterABI(permitABIName, permitAbi); err != nil {
+		return nil, fmt.Errorf("register permit abi failed, err=%w", err)
+	}
+	if err := client.RegisterABI(permit2ABIName, permit2Abi); err != nil {
+		return nil, fmt.Errorf("register permit2 abi failed, err=%w", err)
+	}
+	client.multicall3 = common.HexToAddress(defaultMulticall3Address)
+	if config.Multicall3Address != "" {
+		if !common.IsHexAddress(config.Multicall3Address) {
+			return nil, fmt.Errorf("invalid multicall3 address=%s", config.Multicall3Address)
+		}
+		client.multicall3 = common.HexToAddress(config.Multicall3Address)
 	}
 	client.SupportEIP1559 = config.SupportEIP1559
+	client.minGasTipCap, client.maxGasFeeCap = config.MinGasTipCap, config.MaxGasFeeCap
 	client.erc20Abi = &erc20
 	client.chainID = config.ChainID
 	return client, nil
@@ -95,36 +131,72 @@ func (e *EthClient) GetABIByName(name string) (*abi.ABI, error) {
 	return compiled, nil
 }
 
-// SetClient can be used for mock purpose
+// NodeStatus returns the health information of every configured endpoint
+// the endpoint with Active set is the one serving the calls, nil if the client is not created by NewEthClient
+func (e *EthClient) NodeStatus() []NodeStatus {
+	if pool, ok := e.client.(*nodePool); ok {
+		return pool.Status()
+	}
+	return nil
+}
+
+// Finality returns the finality rule used by client.TxTracker, a block is final after 12 confirmations
+func (e *EthClient) Finality() client.Finality {
+	return client.Finality{Confirmations: finalityConfirmations}
+}
+
+// Close stops the health checks and closes the connections to the endpoints
+func (e *EthClient) Close() error {
+	switch c := e.client.(type) {
+	case *nodePool:
+		c.Close()
+	case *errorBackend:
+		return c.Close()
+	}
+	return nil
+}
+
+// SetClient can be used for mock purpose, all the calls go to the simulated chain after it's set
+// the transactions are mined once they are broadcasted
 func (e *EthClient) SetClient(cli *backends.SimulatedBackend) {
-	e.debugClient = cli
+	e.Close()
+	e.client = wrapBackend(cli)
 }
 
 // BalanceAt reads the balance of eth
 func (e *EthClient) BalanceAt(address string) (*big.Int, error) {
+	return e.BalanceAtContext(context.Background(), address)
+}
+
+// BalanceAtContext reads the balance of eth
+func (e *EthClient) BalanceAtContext(ctx context.Context, address string) (*big.Int, error) {
 	addr := common.HexToAddress(address)
-	value, err := e.client.BalanceAt(context.Background(), addr, nil)
+	value, err := e.client.BalanceAt(ctx, addr, nil)
 	return value, err
 }
 
 func (e *EthClient) Allowance(contract, owner, address string) (*big.Int, error) {
+	return e.AllowanceContext(context.Background(), contract, owner, address)
+}
+
+func (e *EthClient) AllowanceContext(ctx context.Context, contract, owner, address string) (*big.Int, error) {
 	method := "allowance"
 	contractAddr := common.HexToAddress(contract)
 	ownerAddr := common.HexToAddress(owner)
 	addressAddr := common.HexToAddress(address)
 	input, err := e.GetTransactionDataByABI(method, erc20ABIName, ownerAddr, addressAddr)
 	if err != nil {
-		return nil, fmt.Errorf("pack message failed, err=%s", err)
+		return nil, fmt.Errorf("pack message failed, err=%w", err)
 	}
 	msg := ethereum.CallMsg{From: ownerAddr, To: &contractAddr, Data: input}
-	output, err := e.client.CallContract(context.Background(), msg, nil)
+	output, err := e.client.CallContract(ctx, msg, nil)
 	if err != nil {
-		return nil, fmt.Errorf("call contract failed, err=%s", err)
+		return nil, fmt.Errorf("call contract failed, err=%w", err)
 	}
 	// unpack result
 	result, err := e.UnpackByABI(method, erc20ABIName, output)
 	if err != nil {
-		return nil, fmt.Errorf("unpack result failed, err=%s", err)
+		return nil, fmt.Errorf("unpack result failed, err=%w", err)
 	}
 	if len(result) != 1 {
 		return nil, fmt.Errorf("invalid result, result=%v", result)
@@ -140,6 +212,11 @@ func (e *EthClient) ApproveData(contract, owner, spender string, amount *big.Int
 
 // BalanceOf reads the balance of
 func (e *EthClient) BalanceOf(contract, from string) (*big.Int, error) {
+	return e.BalanceOfContext(context.Background(), contract, from)
+}
+
+// BalanceOfContext reads the balance of
+func (e *EthClient) BalanceOfContext(ctx context.Context, contract, from string) (*big.Int, error) {
 	method := "balanceOf"
 	// convert address
 	fromAddr := common.HexToAddress(from)
@@ -147,20 +224,20 @@ func (e *EthClient) BalanceOf(contract, from string) (*big.Int, error) {
 	// pack params
 	input, err := e.GetTransactionDataByABI(method, erc20ABIName, fromAddr)
 	if err != nil {
-		return nil, fmt.Errorf("pack message failed, err=%s", err)
+		return nil, fmt.Errorf("pack message failed, err=%w", err)
 	}
 	// call contract
 	msg := ethereum.CallMsg{From: fromAddr, To: &contractAddr, Data: input}
-	output, err := e.client.CallContract(context.Background(), msg, nil)
+	output, err := e.client.CallContract(ctx, msg, nil)
 	if err != nil {
-		return nil, fmt.Errorf("call contract failed, err=%s", err)
+		return nil, fmt.Errorf("call contract failed, err=%w", err)
 	}
 	// parse result
 	// res, err := e.erc20Abi.Unpack(method, output)
 	fmt.Println("output: ", hex.EncodeToString(output))
 	res, err := e.UnpackByABI(method, erc20ABIName, output)
 	if err != nil {
-		return nil, fmt.Errorf("unpack returned message failed, err=%s", err)
+		return nil, fmt.Errorf("unpack returned message failed, err=%w", err)
 	}
 	if len(res) == 0 {
 		return nil, fmt.Errorf("wrong return format")
@@ -169,20 +246,20 @@ func (e *EthClient) BalanceOf(contract, from string) (*big.Int, error) {
 	return balance, nil
 }
 
-func (e *EthClient) callERC20(contract, method string) ([]interface{}, error) {
+func (e *EthClient) callERC20(ctx context.Context, contract, method string) ([]interface{}, error) {
 	contractAddr := common.HexToAddress(contract)
 	input, err := e.GetTransactionDataByABI(method, erc20ABIName)
 	if err != nil {
-		return nil, fmt.Errorf("pack message failed, err=%s", err)
+		return nil, fmt.Errorf("pack message failed, err=%w", err)
 	}
 	msg := ethereum.CallMsg{From: common.Address{}, To: &contractAddr, Data: input}
-	output, err := e.client.CallContract(context.Background(), msg, nil)
+	output, err := e.client.CallContract(ctx, msg, nil)
 	if err != nil {
-		return nil, fmt.Errorf("call contract failed, err=%s", err)
+		return nil, fmt.Errorf("call contract failed, err=%w", err)
 	}
 	res, err := e.UnpackByABI(method, erc20ABIName, output)
 	if err != nil {
-		return nil, fmt.Errorf("unpack returned message failed, err=%s", err)
+		return nil, fmt.Errorf("unpack returned message failed, err=%w", err)
 	}
 	if len(res) == 0 {
 		return nil, fmt.Errorf("wrong return format")
@@ -192,23 +269,28 @@ func (e *EthClient) callERC20(contract, method string) ([]interface{}, error) {
 
 // DecimalOf returns the decimals of a contract
 func (e *EthClient) DecimalsOf(contract string) (uint8, error) {
+	return e.DecimalsOfContext(context.Background(), contract)
+}
+
+// DecimalsOfContext returns the decimals of a contract
+func (e *EthClient) DecimalsOfContext(ctx context.Context, contract string) (uint8, error) {
 	method := "decimals"
-	res, err := e.callERC20(contract, method)
+	res, err := e.callERC20(ctx, contract, method)
 	if err != nil {
 		return 0, err
 	}
-	if reflect.ValueOf(res[0]).Kind() == reflect.Ptr {
-		value := *abi.ConvertType(res[0], new(*big.Int)).(**big.Int)
-		return uint8(value.Uint64()), nil
-	}
-	decimals := *abi.ConvertType(res[0], new(uint8)).(*uint8)
-	return decimals, nil
+	return toDecimals(res[0]), nil
 }
 
 // TotalSupplyOf returns the total supply of a contract
 func (e *EthClient) TotalSupplyOf(contract string) (*big.Int, error) {
+	return e.TotalSupplyOfContext(context.Background(), contract)
+}
+
+// TotalSupplyOfContext returns the total supply of a contract
+func (e *EthClient) TotalSupplyOfContext(ctx context.Context, contract string) (*big.Int, error) {
 	method := "totalSupply"
-	res, err := e.callERC20(contract, method)
+	res, err := e.callERC20(ctx, contract, method)
 	if err != nil {
 		return nil, err
 	}
@@ -218,8 +300,13 @@ func (e *EthClient) TotalSupplyOf(contract string) (*big.Int, error) {
 
 // SymbolOf returns the symbol of a contract
 func (e *EthClient) SymbolOf(contract string) (string, error) {
+	return e.SymbolOfContext(context.Background(), contract)
+}
+
+// SymbolOfContext returns the symbol of a contract
+func (e *EthClient) SymbolOfContext(ctx context.Context, contract string) (string, error) {
 	method := "symbol"
-	res, err := e.callERC20(contract, method)
+	res, err := e.callERC20(ctx, contract, method)
 	if err != nil {
 		return "", err
 	}
@@ -229,35 +316,63 @@ func (e *EthClient) SymbolOf(contract string) (string, error) {
 
 // GetNonce returns the nonce for an address
 func (e *EthClient) GetNonce(address string) (uint64, error) {
+	return e.GetNonceContext(context.Background(), address)
+}
+
+// GetNonceContext returns the nonce for an address
+func (e *EthClient) GetNonceContext(ctx context.Context, address string) (uint64, error) {
 	addr := common.HexToAddress(address)
-	return e.client.PendingNonceAt(context.Background(), addr)
+	return e.client.PendingNonceAt(ctx, addr)
 }
 
 // GetNonceByNumber returns the nonce for an address at a block number
 func (e *EthClient) GetNonceByNumber(address string, blockNumber *big.Int) (uint64, error) {
+	return e.GetNonceByNumberContext(context.Background(), address, blockNumber)
+}
+
+// GetNonceByNumberContext returns the nonce for an address at a block number
+func (e *EthClient) GetNonceByNumberContext(ctx context.Context, address string, blockNumber *big.Int) (uint64, error) {
 	addr := common.HexToAddress(address)
-	return e.client.NonceAt(context.Background(), addr, blockNumber)
+	return e.client.NonceAt(ctx, addr, blockNumber)
 }
 
 // GetSuggestFee returns the suggested fee for a transaction
 func (e *EthClient) GetSuggestFee(td *client.Transaction) (*client.FeeLimit, error) {
+	return e.GetSuggestFeeContext(context.Background(), td)
+}
+
+// GetSuggestFeeContext returns the suggested fee for a transaction
+// the fee caps come from SuggestFeeTiersContext if td.FeeTier is set, otherwise from the node
+func (e *EthClient) GetSuggestFeeContext(ctx context.Context, td *client.Transaction) (*client.FeeLimit, error) {
 	feeLimit := &client.FeeLimit{}
-	// tip cap
-	tipCap, err := e.client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("get suggest gas tip failed, err=%s", err)
-	}
-	feeCap, err := e.client.SuggestGasPrice(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("get suggest gas price failed, err=%s", err)
+	var tipCap, feeCap *big.Int
+	if td.FeeTier != client.FeeTierDefault {
+		tiers, err := e.SuggestFeeTiersContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get fee tiers failed, err=%w", err)
+		}
+		fee := tiers.Tier(td.FeeTier)
+		if fee == nil {
+			return nil, fmt.Errorf("invalid fee tier=%d", td.FeeTier)
+		}
+		tipCap, feeCap = fee.GasTipCap, fee.GasFeeCap
+	} else {
+		var err error
+		// tip cap
+		if tipCap, err = e.client.SuggestGasTipCap(ctx); err != nil {
+			return nil, fmt.Errorf("get suggest gas tip failed, err=%w", err)
+		}
+		if feeCap, err = e.client.SuggestGasPrice(ctx); err != nil {
+			return nil, fmt.Errorf("get suggest gas price failed, err=%w", err)
+		}
 	}
 	contractAddr := common.HexToAddress(td.To)
 	// gas limit
 	fromAddress := common.HexToAddress(td.From)
-	gas, err := e.client.EstimateGas(context.Background(), ethereum.CallMsg{From: fromAddress, To: &contractAddr,
-		Data: td.Data, Value: td.Amount})
+	gas, err := e.client.EstimateGas(ctx, ethereum.CallMsg{From: fromAddress, To: &contractAddr,
+		Data: td.Data, Value: td.Amount, AccessList: toAccessList(td.AccessList)})
 	if err != nil {
-		return nil, fmt.Errorf("estimate gas failed, err=%s", err)
+		return nil, fmt.Errorf("estimate gas failed, err=%w", e.decodeRevert(err))
 	}
 
 	//gas limit = gas * 120% / 100%
@@ -271,6 +386,10 @@ func (e *EthClient) GetSuggestFee(td *client.Transaction) (*client.FeeLimit, err
 }
 
 func (e *EthClient) EstimateGas(td *client.Transaction) (uint64, error) {
+	return e.EstimateGasContext(context.Background(), td)
+}
+
+func (e *EthClient) EstimateGasContext(ctx context.Context, td *client.Transaction) (uint64, error) {
 	//todo::后面优化，主币的gas_limit 限制为21000
 	if len(td.Data) <= 0 {
 		return 21000, nil
@@ -282,10 +401,10 @@ func (e *EthClient) EstimateGas(td *client.Transaction) (uint64, error) {
 		toAddr = &contractAddr
 	}
 
-	gas, err := e.client.EstimateGas(context.Background(), ethereum.CallMsg{From: common.HexToAddress(td.From),
-		To: toAddr, Data: td.Data, Value: td.Amount})
+	gas, err := e.client.EstimateGas(ctx, ethereum.CallMsg{From: common.HexToAddress(td.From),
+		To: toAddr, Data: td.Data, Value: td.Amount, AccessList: toAccessList(td.AccessList)})
 	if err != nil {
-		return 0, err
+		return 0, e.decodeRevert(err)
 	}
 
 	//强制增加20%的gas_limit
@@ -295,15 +414,21 @@ func (e *EthClient) EstimateGas(td *client.Transaction) (uint64, error) {
 // DeployContract generate the transactions that deploy an contract
 // The address can be calculated by calling ContractAddressOf function
 func (e *EthClient) DeployContract(contractAbi, contractBin string, td *client.Transaction) (
+	[]byte, []byte, string, error) {
+	return e.DeployContractContext(context.Background(), contractAbi, contractBin, td)
+}
+
+// DeployContractContext generate the transactions that deploy an contract, nothing is sent to the chain
+func (e *EthClient) DeployContractContext(ctx context.Context, contractAbi, contractBin string, td *client.Transaction) (
 	[]byte, []byte, string, error) {
 	parsed, err := abi.JSON(strings.NewReader(contractAbi))
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("parse abi failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("parse abi failed, err=%w", err)
 	}
 	byteCode := common.FromHex(contractBin)
 	input, err := parsed.Pack("")
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("pack message failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("pack message failed, err=%w", err)
 	}
 	data := append(byteCode, input...)
 	baseTx := &types.DynamicFeeTx{
@@ -319,13 +444,13 @@ func (e *EthClient) DeployContract(contractAbi, contractBin string, td *client.T
 	tx := types.NewTx(baseTx)
 	message, err := tx.MarshalBinary()
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("encode message failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("encode message failed, err=%w", err)
 	}
 	signer := types.NewLondonSigner(e.chainID)
 	hash := signer.Hash(tx)
 	contractAddr, err := e.contractAddressOf(contractAbi, contractBin, td)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("calculate contract address failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("calculate contract address failed, err=%w", err)
 	}
 	return message, hash.Bytes(), contractAddr, nil
 }
@@ -337,7 +462,7 @@ func (e *EthClient) GetTransactionData(method string, abiDesc string, args ...in
 	}
 	methodAbi, err := abi.JSON(strings.NewReader(abiDesc))
 	if err != nil {
-		return nil, fmt.Errorf("parse abi failed, err=%s", err)
+		return nil, fmt.Errorf("parse abi failed, err=%w", err)
 	}
 	return methodAbi.Pack(method, args...)
 }
@@ -351,18 +476,32 @@ func (e *EthClient) GetTransactionDataByABI(method string, abiName string, args
 	return compiled.Pack(method, args...)
 }
 
+// generateTx creates DynamicFeeTx on the chains with EIP-1559, otherwise AccessListTx if td has an access list or LegacyTx
 func (e *EthClient) generateTx(toAddr *common.Address, td *client.Transaction) *types.Transaction {
 	var tx *types.Transaction
 	if e.SupportEIP1559 {
 		baseTx := &types.DynamicFeeTx{
-			ChainID:   e.chainID,
-			Nonce:     td.Nonce,
-			GasFeeCap: td.Fee.GasFeeCap,
-			GasTipCap: td.Fee.GasTipCap,
-			Gas:       td.Fee.Gas.Uint64(),
-			To:        toAddr,
-			Value:     td.Amount,
-			Data:      td.Data,
+			ChainID:    e.chainID,
+			Nonce:      td.Nonce,
+			GasFeeCap:  td.Fee.GasFeeCap,
+			GasTipCap:  td.Fee.GasTipCap,
+			Gas:        td.Fee.Gas.Uint64(),
+			To:         toAddr,
+			Value:      td.Amount,
+			Data:       td.Data,
+			AccessList: toAccessList(td.AccessList),
+		}
+		tx = types.NewTx(baseTx)
+	} else if len(td.AccessList) > 0 {
+		baseTx := &types.AccessListTx{
+			ChainID:    e.chainID,
+			Nonce:      td.Nonce,
+			GasPrice:   td.Fee.GasFeeCap,
+			Gas:        td.Fee.Gas.Uint64(),
+			To:         toAddr,
+			Value:      td.Amount,
+			Data:       td.Data,
+			AccessList: toAccessList(td.AccessList),
 		}
 		tx = types.NewTx(baseTx)
 	} else {
@@ -380,12 +519,24 @@ func (e *EthClient) generateTx(toAddr *common.Address, td *client.Transaction) *
 }
 
 func (e *EthClient) GetLatestBlockNumber() (*big.Int, error) {
-	header, err := e.client.HeaderByNumber(context.Background(), nil)
-	return header.Number, err
+	return e.GetLatestBlockNumberContext(context.Background())
+}
+
+func (e *EthClient) GetLatestBlockNumberContext(ctx context.Context) (*big.Int, error) {
+	header, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get latest header failed, err=%w", err)
+	}
+	return header.Number, nil
 }
 
 // GetTransaction generate a transaction for transfer
 func (e *EthClient) GetTransaction(td *client.Transaction) ([]byte, []byte, error) {
+	return e.GetTransactionContext(context.Background(), td)
+}
+
+// GetTransactionContext generate a transaction for transfer, nothing is sent to the chain
+func (e *EthClient) GetTransactionContext(ctx context.Context, td *client.Transaction) ([]byte, []byte, error) {
 	toAddr := common.HexToAddress(td.To)
 	tx := e.generateTx(&toAddr, td)
 	var hash common.Hash
@@ -393,21 +544,27 @@ func (e *EthClient) GetTransaction(td *client.Transaction) ([]byte, []byte, erro
 		signer := types.NewLondonSigner(e.chainID)
 		hash = signer.Hash(tx)
 	} else {
-		signer := types.NewEIP155Signer(e.chainID)
+		// EIP2930Signer hashes both the legacy and access list transactions
+		signer := types.NewEIP2930Signer(e.chainID)
 		hash = signer.Hash(tx)
 	}
 	message, err := tx.MarshalBinary()
 	if err != nil {
-		return nil, nil, fmt.Errorf("encode message failed, err=%s", err)
+		return nil, nil, fmt.Errorf("encode message failed, err=%w", err)
 	}
 	return message, hash.Bytes(), nil
 }
 
 // BroadcastTransaction will broad the signed transaction to chain
 func (e *EthClient) BroadcastTransaction(trans []byte, signature []byte) ([]byte, error) {
+	return e.BroadcastTransactionContext(context.Background(), trans, signature)
+}
+
+// BroadcastTransactionContext will broad the signed transaction to chain
+func (e *EthClient) BroadcastTransactionContext(ctx context.Context, trans []byte, signature []byte) ([]byte, error) {
 	tx := &types.Transaction{}
 	if err := tx.UnmarshalBinary(trans); err != nil {
-		return nil, fmt.Errorf("parse transaction failed, err=%s", err)
+		return nil, fmt.Errorf("parse transaction failed, err=%w", err)
 	}
 	if len(signature) != crypto.SignatureLength {
 		return nil, fmt.Errorf("invalid signature length, expect=%d, got=%d", crypto.SignatureLength, len(signature))
@@ -415,21 +572,39 @@ func (e *EthClient) BroadcastTransaction(trans []byte, signature []byte) ([]byte
 	signer := types.NewLondonSigner(e.chainID)
 	signedTx, err := tx.WithSignature(signer, signature)
 	if err != nil {
-		return nil, fmt.Errorf("combine with signature failed, err=%s", err)
+		return nil, fmt.Errorf("combine with signature failed, err=%w", err)
 	}
 	hash := signedTx.Hash()
-	// if debugClient is set then go debug logic only
-	if e.debugClient != nil {
-		return hash[:], e.debugClient.SendTransaction(context.Background(), signedTx)
+	return hash[:], e.client.SendTransaction(ctx, signedTx)
+}
+
+// SignAndSend generates the transaction of td, signs it by signer and broadcasts it
+func (e *EthClient) SignAndSend(td *client.Transaction, signer client.Signer, opts ...client.SendOption) ([]byte, error) {
+	return e.SignAndSendContext(context.Background(), td, signer, opts...)
+}
+
+// SignAndSendContext generates the transaction of td, signs it by signer and broadcasts it, the hash of the transaction is returned
+// td.From is set to the address of signer if it's empty, the fee is suggested if td.Fee is nil
+// td.Nonce is set to the pending nonce of the signer, unless client.WithNonce or client.WithNonceManager is given
+func (e *EthClient) SignAndSendContext(ctx context.Context, td *client.Transaction, signer client.Signer, opts ...client.SendOption) ([]byte, error) {
+	if td.From == "" {
+		td.From = e.AddressToString(signer.Address())
+	} else if common.HexToAddress(td.From) != signer.Address() {
+		return nil, fmt.Errorf("signer=%s is not the sender=%s", signer.Address().Hex(), td.From)
 	}
-	return hash[:], e.client.SendTransaction(context.Background(), signedTx)
+	return client.SignAndSendContext(ctx, e, td, signer, opts...)
 }
 
 func (e *EthClient) CallContract(td *client.Transaction) ([]byte, error) {
+	return e.CallContractContext(context.Background(), td)
+}
+
+func (e *EthClient) CallContractContext(ctx context.Context, td *client.Transaction) ([]byte, error) {
 	from := common.HexToAddress(td.From)
 	to := common.HexToAddress(td.To)
 	msg := ethereum.CallMsg{From: from, To: &to, Value: td.Amount, Data: td.Data}
-	return e.client.CallContract(context.Background(), msg, nil)
+	output, err := e.client.CallContract(ctx, msg, nil)
+	return output, e.decodeRevert(err)
 }
 
 // Helper functions these functions may different on different chains
@@ -459,18 +634,21 @@ func (e *EthClient) AbiConvertToAddress(v interface{}) string {
 
 // GetTransactionByHash gets the transaction information from chain
 func (e *EthClient) GetTransactionByHash(transactionHash string) (*client.TransactionInfo, error) {
+	return e.GetTransactionByHashContext(context.Background(), transactionHash)
+}
+
+// GetTransactionByHashContext gets the transaction information from chain
+func (e *EthClient) GetTransactionByHashContext(ctx context.Context, transactionHash string) (*client.TransactionInfo, error) {
 	hash := common.HexToHash(transactionHash)
-	tx, isPending, err := e.client.TransactionByHash(context.Background(), hash)
-	if err != nil {
-		return nil, fmt.Errorf("get transaction failed, hash=%s, err=%s", transactionHash, err)
-	}
-	txReceipt, err := e.client.TransactionReceipt(context.Background(), hash)
+	tx, isPending, err := e.client.TransactionByHash(ctx, hash)
 	if err != nil {
-		return nil, fmt.Errorf("get transaction receipt failed, hash=%s, err=%s", transactionHash, err)
+		return nil, fmt.Errorf("get transaction failed, hash=%s, err=%w", transactionHash, err)
 	}
 	info := client.TransactionInfo{}
 	transaction := client.Transaction{}
-	transaction.To = tx.To().Hex()
+	if tx.To() != nil {
+		transaction.To = tx.To().Hex()
+	}
 	transaction.Nonce = tx.Nonce()
 	transaction.ChainID = tx.ChainId()
 	transaction.Amount = tx.Value()
@@ -480,6 +658,21 @@ func (e *EthClient) GetTransactionByHash(transactionHash string) (*client.Transa
 	fee.Gas = big.NewInt(0).SetUint64(tx.Gas())
 	fee.GasFeeCap = tx.GasPrice()
 	fee.GasTipCap = tx.GasTipCap()
+	transaction.Fee = &fee
+	info.Tx = &transaction
+	if isPending {
+		// no receipt until the transaction is mined
+		info.IsPending = true
+		if sender, err := types.Sender(types.NewLondonSigner(tx.ChainId()), tx); err == nil {
+			transaction.From = sender.String()
+		}
+		return &info, nil
+	}
+
+	txReceipt, err := e.client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("get transaction receipt failed, hash=%s, err=%w", transactionHash, err)
+	}
 
 	// gasPrice 优先从receipt中获取
 	gasPrice := tx.GasPrice()
@@ -494,9 +687,7 @@ func (e *EthClient) GetTransactionByHash(transactionHash string) (*client.Transa
 		GasUsed:  gasUsed,
 	}
 
-	transaction.Fee = &fee
-	info.Tx = &transaction
-	info.IsPending = isPending
+	info.BlockNumber = txReceipt.BlockNumber
 	if txReceipt.Status == 1 {
 		info.Status = client.TransactionStatusSuccess
 	} else {
@@ -505,41 +696,54 @@ func (e *EthClient) GetTransactionByHash(transactionHash string) (*client.Transa
 	sender, err := types.Sender(types.NewLondonSigner(tx.ChainId()), tx)
 	if err != nil {
 		info.Status, info.Error = client.TransactionStatusInvalid, "get_from_failed"
-		return nil, fmt.Errorf("get from failed, err=%s", err)
+		return nil, fmt.Errorf("get from failed, err=%w", err)
 	}
 	transaction.From = sender.String()
 	events := make([]*client.EventLog, 0, len(txReceipt.Logs))
 	for i := range txReceipt.Logs {
-		event := client.EventLog{
-			Address: txReceipt.Logs[i].Address.Hex(),
-			Data:    txReceipt.Logs[i].Data,
-			Removed: txReceipt.Logs[i].Removed,
-			Topics:  make([][]byte, 0, len(txReceipt.Logs[i].Topics)),
-		}
-		for j := range txReceipt.Logs[i].Topics {
-			topic := txReceipt.Logs[i].Topics[j]
-			event.Topics = append(event.Topics, topic[:])
-		}
-		events = append(events, &event)
+		events = append(events, toEventLog(txReceipt.Logs[i]))
 	}
 	info.Logs = events
 	if info.Status != client.TransactionStatusSuccess {
-		_, err := e.getRevertReason(sender, tx, txReceipt)
-		info.Error = err.Error()
+		info.Revert, info.Error = e.getRevertReason(ctx, sender, tx, txReceipt)
 	}
 	return &info, nil
 }
 
-func (e *EthClient) getRevertReason(sender common.Address, tx *types.Transaction, reciept *types.Receipt) ([]byte, error) {
+// getRevertReason replays the failed transaction on the state before its block to find the revert reason
+// the transactions before it in the same block are not replayed, if the state before the block is not
+// available, such as on the simulated backend, it's replayed on the state after the block
+func (e *EthClient) getRevertReason(ctx context.Context, sender common.Address, tx *types.Transaction, reciept *types.Receipt) (*client.RevertError, string) {
 	msg := ethereum.CallMsg{
-		From:     sender,
-		To:       tx.To(),
-		Gas:      tx.Gas(),
-		GasPrice: tx.GasPrice(),
-		Value:    tx.Value(),
-		Data:     tx.Data(),
+		From:       sender,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasFeeCap, msg.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	} else {
+		msg.GasPrice = tx.GasPrice()
+	}
+	err := errStateUnavailable
+	if reciept.BlockNumber.Sign() > 0 {
+		_, err = e.client.CallContract(ctx, msg, new(big.Int).Sub(reciept.BlockNumber, common.Big1))
+	}
+	if isStateUnavailable(err) {
+		_, err = e.client.CallContract(ctx, msg, reciept.BlockNumber)
 	}
-	return e.client.CallContract(context.Background(), msg, reciept.BlockNumber)
+	var revertErr *client.RevertError
+	switch {
+	case errors.As(e.decodeRevert(err), &revertErr):
+		return revertErr, revertErr.Error()
+	case err != nil:
+		return nil, err.Error()
+	case reciept.GasUsed >= tx.Gas():
+		return nil, "out of gas"
+	}
+	return nil, client.ErrReverted.Error()
 }
 
 func (e *EthClient) ParseEventLog(abiName string, eventLog *client.EventLog) ([]interface{}, error) {
@@ -554,7 +758,7 @@ func (e *EthClient) ParseEventLog(abiName string, eventLog *client.EventLog) ([]
 	eventID.SetBytes(eventLog.Topics[0])
 	event, err := compiled.EventByID(eventID)
 	if err != nil {
-		return nil, fmt.Errorf("get event from id failed, err=%s", err)
+		return nil, fmt.Errorf("get event from id failed, err=%w", err)
 	}
 	return event.Inputs.Unpack(eventLog.Data)
 }
@@ -565,7 +769,7 @@ func (e *EthClient) AddressFromPrivateKey(privateKey string) (string, error) {
 	}
 	key, err := ecrypto.HexToECDSA(privateKey)
 	if err != nil {
-		return "", fmt.Errorf("wrong private key=%s, err=%s", privateKey, err)
+		return "", fmt.Errorf("wrong private key=%s, err=%w", privateKey, err)
 	}
 	return e.AddressFromPublicKey(&key.PublicKey)
 }
@@ -594,9 +798,13 @@ func (e *EthClient) AddressToString(addr common.Address) string {
 }
 
 func (e *EthClient) ContractAddress(addr common.Address) (bool, error) {
-	code, err := e.client.CodeAt(context.Background(), addr, nil)
+	return e.ContractAddressContext(context.Background(), addr)
+}
+
+func (e *EthClient) ContractAddressContext(ctx context.Context, addr common.Address) (bool, error) {
+	code, err := e.client.CodeAt(ctx, addr, nil)
 	if err != nil {
-		return false, fmt.Errorf("get code failed, err=%s", err)
+		return false, fmt.Errorf("get code failed, err=%w", err)
 	}
 	return len(code) > 0, nil
 }
@@ -614,13 +822,17 @@ func (e *EthClient) NormalizeAddress(address string) string {
 
 // GetGasPrice  Deprecate!! 尽量使用 GetSuggestGasPrice 替代
 func (e *EthClient) GetGasPrice() (*big.Int, *big.Int, error) {
-	feeCap, err := e.client.SuggestGasPrice(context.Background())
+	return e.GetGasPriceContext(context.Background())
+}
+
+func (e *EthClient) GetGasPriceContext(ctx context.Context) (*big.Int, *big.Int, error) {
+	feeCap, err := e.client.SuggestGasPrice(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("get gas price failed, err=%s", err)
+		return nil, nil, fmt.Errorf("get gas price failed, err=%w", err)
 	}
-	tipCap, err := e.client.SuggestGasTipCap(context.Background())
+	tipCap, err := e.client.SuggestGasTipCap(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("get gas tip failed, err=%s", err)
+		return nil, nil, fmt.Errorf("get gas tip failed, err=%w", err)
 	}
 	return feeCap, tipCap, nil
 }
@@ -628,10 +840,14 @@ func (e *EthClient) GetGasPrice() (*big.Int, *big.Int, error) {
 // GetSuggestGasPrice 获取建议的gasPrice, tipCap 以及返回当前最新块的baseFee。
 // todo:: 这里需要优化为并发请求2次接口
 func (e *EthClient) GetSuggestGasPrice() (*big.Int, *big.Int, *big.Int, error) {
+	return e.GetSuggestGasPriceContext(context.Background())
+}
+
+func (e *EthClient) GetSuggestGasPriceContext(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
 	//获取建议的gas
-	gasPrice, err := e.client.SuggestGasPrice(context.Background())
+	gasPrice, err := e.client.SuggestGasPrice(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("get gas price failed, err=%s", err)
+		return nil, nil, nil, fmt.Errorf("get gas price failed, err=%w", err)
 	}
 
 	//如果不支持EIP1559，直接返回
@@ -640,15 +856,15 @@ func (e *EthClient) GetSuggestGasPrice() (*big.Int, *big.Int, *big.Int, error) {
 	}
 
 	//获取建议的tip
-	tipCap, err := e.client.SuggestGasTipCap(context.Background())
+	tipCap, err := e.client.SuggestGasTipCap(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("get gas tip failed, err=%s", err)
+		return nil, nil, nil, fmt.Errorf("get gas tip failed, err=%w", err)
 	}
 
-	//根据建议推算当前的basePrice
-	baseFee := &big.Int{}
-	if gasPrice.Cmp(tipCap) > 0 {
-		baseFee = big.NewInt(0).Sub(gasPrice, tipCap)
+	//下一个块的baseFee, 来自eth_feeHistory或者最新块
+	baseFee, _, err := e.feeHistory(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 	return baseFee, tipCap, gasPrice, nil
 }
@@ -660,20 +876,38 @@ func (e *EthClient) NativeAssetAddress() string {
 func (e *EthClient) PublicKeyHexToAddress(key string) (string, error) {
 	buffer, err := hex.DecodeString(key)
 	if err != nil {
-		return "", fmt.Errorf("decode public key failed, err=%s", err)
+		return "", fmt.Errorf("decode public key failed, err=%w", err)
 	}
 	pubKey, err := ecrypto.UnmarshalPubkey(buffer)
 	if err != nil {
-		return "", fmt.Errorf("unmarshal public key failed, err=%s", err)
+		return "", fmt.Errorf("unmarshal public key failed, err=%w", err)
 	}
 	addr := ecrypto.PubkeyToAddress(*pubKey)
 	return addr.Hex(), nil
 }
 
+// VerifyMessage checks the message is signed by address with personal_sign of EIP-191, which prefixes the message
+// with "\x19Ethereum Signed Message:\n" and its length
+func (e *EthClient) VerifyMessage(address string, message, signature []byte) (bool, error) {
+	addr, err := e.AddressFromString(address)
+	if err != nil {
+		return false, err
+	}
+	signer, err := recoverAddress(client.HashMessage(client.EthereumMessagePrefix, message), signature)
+	if err != nil {
+		return false, err
+	}
+	return common.HexToAddress(signer) == addr, nil
+}
+
 func (e *EthClient) GetLackedGas(address string, gas uint64, gasPrice *big.Int, txSize uint64) (*big.Int, error) {
-	balance, err := e.BalanceAt(address)
+	return e.GetLackedGasContext(context.Background(), address, gas, gasPrice, txSize)
+}
+
+func (e *EthClient) GetLackedGasContext(ctx context.Context, address string, gas uint64, gasPrice *big.Int, txSize uint64) (*big.Int, error) {
+	balance, err := e.BalanceAtContext(ctx, address)
 	if err != nil {
-		return big.NewInt(0), fmt.Errorf("get balance failed, err=%s", err)
+		return big.NewInt(0), fmt.Errorf("get balance failed, err=%w", err)
 	}
 	need := new(big.Int).Mul(gasPrice, big.NewInt(int64(gas)))
 	if balance.Cmp(need) >= 0 {
@@ -2,11 +2,13 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"git.bipal.space/shared-lib/blockchain/client"
 	bclient "git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/replay/replaytest"
 	"git.bipal.space/shared-lib/blockchain/utils"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -17,9 +19,12 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -129,7 +134,7 @@ var UPoolUSDTAddress = map[uint64]map[string]interface{}{
 }
 
 func TestBalanceOf(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	// address of USDC
 	erc20Address := "0xCA1d7dE02439eec7727AeE15cD8bF36cCD9728c7"
@@ -141,7 +146,7 @@ func TestBalanceOf(t *testing.T) {
 }
 
 func TestBalanceAt(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	from := "0xC8bD5B1aD2FD42Ef9D92B32F38E9b0DFAC875Be4"
 	value, err := client.BalanceAt(from)
@@ -151,7 +156,7 @@ func TestBalanceAt(t *testing.T) {
 }
 
 func TestDecimalsOf(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	usdcAddress := "0x3506424F91fD33084466F402d5D97f05F8e3b4AF"
 	decimals, err := client.DecimalsOf(usdcAddress)
@@ -160,7 +165,7 @@ func TestDecimalsOf(t *testing.T) {
 }
 
 func TestTotalSupplyOf(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	erc20Address := "0xCA1d7dE02439eec7727AeE15cD8bF36cCD9728c7"
 	supply, err := client.TotalSupplyOf(erc20Address)
@@ -170,7 +175,7 @@ func TestTotalSupplyOf(t *testing.T) {
 }
 
 func TestSymbolOf(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	usdcAddress := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
 	symbol, err := client.SymbolOf(usdcAddress)
@@ -179,7 +184,7 @@ func TestSymbolOf(t *testing.T) {
 }
 
 func TestGetNonde(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	from := "0xC8bD5B1aD2FD42Ef9D92B32F38E9b0DFAC875Be4"
 	nonce, err := client.GetNonce(from)
@@ -262,7 +267,7 @@ func TestDeployContract(t *testing.T) {
 }
 
 func TestCallContract(t *testing.T) {
-	client, err := NewEthClient(&config)
+	client, err := NewEthClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	tokenIn := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
 	tokenOut := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
@@ -290,7 +295,7 @@ func TestAbiPack(t *testing.T) {
 }
 
 func TestGetOnChainTransaction(t *testing.T) {
-	client, _ := NewEthClient(&testConfig)
+	client, _ := NewEthClient(replaytest.Config(t, testConfig))
 	//info, err := client.GetTransactionByHash("0x292de558a3490160f106c12ca5e98164fa78c8533f11ef6984bca8b4248a8b85")
 	info, err := client.GetTransactionByHash("0xfda97728d22c89bb23c58a051ae8278beeeb7c6cadc324f866c28435fda2b245")
 	fmt.Println("---", info.Logs)
@@ -335,7 +340,7 @@ func TestGetOnChainTransaction(t *testing.T) {
 }
 
 func TestTransferEvent(t *testing.T) {
-	client, _ := NewEthClient(&config)
+	client, _ := NewEthClient(replaytest.Config(t, config))
 	info, err := client.GetTransactionByHash("0xfda97728d22c89bb23c58a051ae8278beeeb7c6cadc324f866c28435fda2b245")
 	assert.Nil(t, err, "get transaction error")
 	assert.Greater(t, len(info.Logs), 0, "no logs found")
@@ -362,7 +367,7 @@ func TestNoramlizeAddress(t *testing.T) {
 }
 
 func TestGetGasPrice(t *testing.T) {
-	client, _ := NewEthClient(&testConfig)
+	client, _ := NewEthClient(replaytest.Config(t, testConfig))
 	base, tip, err := client.GetGasPrice()
 	assert.Nil(t, err, "get gas price failed")
 	fmt.Println(base, tip)
@@ -387,7 +392,7 @@ func TestTransferData(t *testing.T) {
 }
 
 func TestAllowance(t *testing.T) {
-	client, _ := NewEthClient(&config)
+	client, _ := NewEthClient(replaytest.Config(t, config))
 	amount, err := client.Allowance("0xdAC17F958D2ee523a2206206994597C13D831ec7", "0xf1D7BEe92F49EAfc36b09b9953C05a2F4673cB40",
 		"0x90fcDAE23d01e916b5FF0ce36CA9E4887DAEBDb5")
 	assert.Nil(t, err, "should success")
@@ -409,9 +414,36 @@ func TestIsValidAddress(t *testing.T) {
 	assert.False(t, valid, "address should be valid")
 }
 
+func TestVerifyMessage(t *testing.T) {
+	c := newMainnetClient(t)
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := []byte("Sign in to example.com\nNonce: 42")
+	signature, err := crypto.Sign(client.HashMessage(client.EthereumMessagePrefix, message), key)
+	assert.Nil(t, err, "sign message failed")
+
+	ok, err := c.VerifyMessage(address, message, signature)
+	assert.Nil(t, err, "verify message failed")
+	assert.True(t, ok, "signature of V 0 or 1 should match")
+	signature[crypto.RecoveryIDOffset] += 27
+	ok, err = c.VerifyMessage(strings.ToLower(address), message, signature)
+	assert.Nil(t, err, "verify message failed")
+	assert.True(t, ok, "signature of V 27 or 28 should match")
+	ok, _ = c.VerifyMessage(address, []byte("Sign in to example.com\nNonce: 43"), signature)
+	assert.False(t, ok, "signature of other message should not match")
+
+	// the message signed by TronLink is not a personal_sign message
+	tronSignature, _ := crypto.Sign(client.HashMessage(client.TronMessagePrefix, message), key)
+	ok, _ = c.VerifyMessage(address, message, tronSignature)
+	assert.False(t, ok, "signature of tron message should not match")
+
+	_, err = c.VerifyMessage("0x1234", message, signature)
+	assert.NotNil(t, err, "invalid address should fail")
+}
+
 // TestUxuyCall is used for debuging contracts, can leave this function commented
 func TestUxuyCall(t *testing.T) {
-	client, _ := NewEthClient(&testConfig)
+	client, _ := NewEthClient(replaytest.Config(t, testConfig))
 	//data, err := hex.DecodeString("893419ca00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000002a13907000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002a0000000000000000000000000cef4a0531b24319d7df091967b94c6b33716b1670000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001ccbf0000000000000000000000000000000000000000000000000000000000000380000000000000000000000000000000000000000000000000000000006431b1ea00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000020aa443a48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000005338640ff4849900000000000000000000000000000000000000000000000000000000000000e00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000ff970a61a04b1ca14834a43f5de4533ebddb5cc8000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000001f40000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000020aa443a4800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008000000000000000000000000000000000000000000000000000003691d6afc00000000000000000000000000000000000000000000000000000000000000000e00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000ff970a61a04b1ca14834a43f5de4533ebddb5cc8000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000001f4")
 	data, err := hex.DecodeString("9fbf10fc000000000000000000000000000000000000000000000000000000000000279400000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001000000000000000000000000f1d7bee92f49eafc36b09b9953c05a2f4673cb400000000000000000000000000000000000000000000000000000000001312d0000000000000000000000000000000000000000000000000000000000013042a0000000000000000000000000000000000000000000000000000000000000012000000000000000000000000000000000000000000000000000000000000001a000000000000000000000000000000000000000000000000000000000000001e000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000014f1d7bee92f49eafc36b09b9953c05a2f4673cb400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000")
 	assert.Nil(t, err, "failed")
@@ -429,7 +461,7 @@ func TestUxuyCall(t *testing.T) {
 }
 
 func TestBSC(t *testing.T) {
-	client, err := NewEthClient(&bscConfig)
+	client, err := NewEthClient(replaytest.Config(t, bscConfig))
 	assert.Nil(t, err, "failed")
 	data, err := client.ApproveData("0x1af3f329e8be154074d8769d1ffa4ee058b1dbc3", "0x3ea040d8c646A3BF91914121f6e9594b172d6BaF", "0xf1D7BEe92F49EAfc36b09b9953C05a2F4673cB40", big.NewInt(1000000000000000000))
 	assert.Nil(t, err, "failed")
@@ -494,7 +526,7 @@ const stargateFeeLibraryABI = `[{
 	}]`
 
 func TestStargateFee(t *testing.T) {
-	c, err := NewEthClient(&testConfig)
+	c, err := NewEthClient(replaytest.Config(t, testConfig))
 	assert.Nil(t, err, "create client failed")
 	err = c.RegisterABI("stargateFee", stargateFeeLibraryABI)
 	assert.Nil(t, err, "register abi failed")
@@ -548,3 +580,21 @@ func TestParseCalldata(t *testing.T) {
 	assert.Equal(t, addr, "0xf1D7BEe92F49EAfc36b09b9953C05a2F4673cB40")
 	assert.Equal(t, amount, big.NewInt(1000))
 }
+
+func TestContextCancel(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	defer close(block)
+	c, err := NewEthClient(&client.ChainConfiguration{
+		Endpoints: []string{server.URL},
+		ChainID:   big.NewInt(1),
+	})
+	assert.Nil(t, err, "create client failed")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.BalanceAtContext(ctx, "0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "deadline should be propagated")
+}
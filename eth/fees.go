@@ -0,0 +1,197 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	// feeHistoryBlocks is the number of recent blocks the fee tiers are calculated from
+	feeHistoryBlocks = 20
+	// methodNotFound is the json-rpc error code of the methods the node doesn't support
+	methodNotFound = -32601
+)
+
+// feeTiers are the reward percentiles and the headroom over the next base fee in percent of the tiers
+var feeTiers = []struct {
+	tier       client.FeeTier
+	percentile float64
+	headroom   int64
+}{
+	{client.FeeTierSlow, 10, 125},
+	{client.FeeTierStandard, 50, 150},
+	{client.FeeTierFast, 90, 200},
+}
+
+// errFeeHistoryUnsupported is returned by the backends without eth_feeHistory, such as the simulated chain
+var errFeeHistoryUnsupported = errors.New("fee history not supported")
+
+// feeHistoryBackend is implemented by the backends supporting eth_feeHistory, such as the node pool
+type feeHistoryBackend interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// FeeTiers are the fees suggested for the speeds of inclusion
+// BaseFee is the projected base fee of the next block, it's zero on the chains without EIP-1559
+// GasTipCap of a tier is the percentile of the priority fees paid in the recent blocks, GasFeeCap leaves headroom
+// for the base fee rising, the faster the tier the higher both are
+type FeeTiers struct {
+	BaseFee  *big.Int
+	Slow     *client.FeeLimit
+	Standard *client.FeeLimit
+	Fast     *client.FeeLimit
+}
+
+// Tier returns the fee of tier, nil for FeeTierDefault
+func (f *FeeTiers) Tier(tier client.FeeTier) *client.FeeLimit {
+	switch tier {
+	case client.FeeTierSlow:
+		return f.Slow
+	case client.FeeTierStandard:
+		return f.Standard
+	case client.FeeTierFast:
+		return f.Fast
+	}
+	return nil
+}
+
+// SuggestFeeTiers returns the slow, standard and fast fees
+func (e *EthClient) SuggestFeeTiers() (*FeeTiers, error) {
+	return e.SuggestFeeTiersContext(context.Background())
+}
+
+// SuggestFeeTiersContext returns the slow, standard and fast fees calculated from eth_feeHistory of the recent blocks,
+// the fees are bounded by MinGasTipCap and MaxGasFeeCap of the chain configuration
+// the tip suggested by the node is used for all tiers if the node doesn't support eth_feeHistory
+// on the chains without EIP-1559 GasFeeCap and GasTipCap are both the gas price
+func (e *EthClient) SuggestFeeTiersContext(ctx context.Context) (*FeeTiers, error) {
+	percentiles := make([]float64, 0, len(feeTiers))
+	for _, t := range feeTiers {
+		percentiles = append(percentiles, t.percentile)
+	}
+	baseFee, tips, err := e.feeHistory(ctx, percentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FeeTiers{BaseFee: baseFee}
+	for i, t := range feeTiers {
+		tip := tips[i]
+		if e.minGasTipCap != nil && tip.Cmp(e.minGasTipCap) < 0 {
+			tip = new(big.Int).Set(e.minGasTipCap)
+		}
+		feeCap := new(big.Int).Mul(baseFee, big.NewInt(t.headroom))
+		feeCap.Div(feeCap, big.NewInt(100))
+		if !e.SupportEIP1559 {
+			// the gas price is paid in full without refund
+			feeCap.Set(baseFee)
+		}
+		feeCap.Add(feeCap, tip)
+		if e.maxGasFeeCap != nil && feeCap.Cmp(e.maxGasFeeCap) > 0 {
+			feeCap.Set(e.maxGasFeeCap)
+		}
+		if tip.Cmp(feeCap) > 0 || !e.SupportEIP1559 {
+			tip = new(big.Int).Set(feeCap)
+		}
+		fee := &client.FeeLimit{GasFeeCap: feeCap, GasTipCap: tip}
+		switch t.tier {
+		case client.FeeTierSlow:
+			result.Slow = fee
+		case client.FeeTierStandard:
+			result.Standard = fee
+		case client.FeeTierFast:
+			result.Fast = fee
+		}
+	}
+	return result, nil
+}
+
+// feeHistory returns the base fee of the next block and the median of the rewards at each percentile in the recent blocks
+func (e *EthClient) feeHistory(ctx context.Context, percentiles []float64) (*big.Int, []*big.Int, error) {
+	tips := make([]*big.Int, len(percentiles))
+	var history *ethereum.FeeHistory
+	var err error
+	if b, ok := e.client.(feeHistoryBackend); ok {
+		history, err = b.FeeHistory(ctx, feeHistoryBlocks, nil, percentiles)
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFound {
+			err = errFeeHistoryUnsupported
+		}
+		if err != nil && !errors.Is(err, errFeeHistoryUnsupported) {
+			return nil, nil, fmt.Errorf("get fee history failed, err=%w", err)
+		}
+	}
+	if history == nil || len(history.BaseFee) == 0 {
+		header, err := e.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get latest header failed, err=%w", err)
+		}
+		baseFee := header.BaseFee
+		if baseFee == nil {
+			baseFee = big.NewInt(0)
+		}
+		for i := range tips {
+			if i == 0 {
+				if tips[i], err = e.suggestTip(ctx, baseFee); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			tips[i] = tips[0]
+		}
+		return baseFee, tips, nil
+	}
+
+	// the last base fee is of the block after the newest block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+	for i := range percentiles {
+		rewards := make([]*big.Int, 0, len(history.Reward))
+		for j, reward := range history.Reward {
+			// the empty blocks have no reward to learn from
+			if i < len(reward) && j < len(history.GasUsedRatio) && history.GasUsedRatio[j] > 0 {
+				rewards = append(rewards, reward[i])
+			}
+		}
+		if len(rewards) == 0 {
+			tip, err := e.suggestTip(ctx, baseFee)
+			if err != nil {
+				return nil, nil, err
+			}
+			tips[i] = tip
+			continue
+		}
+		sort.Slice(rewards, func(a, b int) bool { return rewards[a].Cmp(rewards[b]) < 0 })
+		tips[i] = new(big.Int).Set(rewards[len(rewards)/2])
+	}
+	return baseFee, tips, nil
+}
+
+// suggestTip returns the tip suggested by the node, or the gas price above baseFee on the chains without EIP-1559
+func (e *EthClient) suggestTip(ctx context.Context, baseFee *big.Int) (*big.Int, error) {
+	if e.SupportEIP1559 {
+		tip, err := e.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get suggest gas tip failed, err=%w", err)
+		}
+		return tip, nil
+	}
+	gasPrice, err := e.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get suggest gas price failed, err=%w", err)
+	}
+	if gasPrice.Cmp(baseFee) <= 0 {
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).Sub(gasPrice, baseFee), nil
+}
@@ -0,0 +1,77 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// feeHandler answers eth_feeHistory with 4 blocks whose next base fee is 100 gwei, the third block is empty
+func feeHandler(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "eth_feeHistory":
+		return map[string]interface{}{
+			"oldestBlock":   "0x10",
+			"baseFeePerGas": []string{"0x1", "0x1", "0x1", "0x1", "0x174876e800"},
+			"gasUsedRatio":  []float64{0.5, 0.9, 0, 0.3},
+			"reward": [][]string{
+				{"0x3b9aca00", "0x77359400", "0xb2d05e00"},  // 1, 2, 3 gwei
+				{"0x77359400", "0xb2d05e00", "0x12a05f200"}, // 2, 3, 5 gwei
+				{"0x0", "0x0", "0x0"},
+				{"0x3b9aca00", "0x77359400", "0x1dcd65000"}, // 1, 2, 8 gwei
+			},
+		}, nil
+	case "eth_estimateGas":
+		return "0x5208", nil
+	default:
+		return nil, &rpcError{code: -32601, message: "method not found"}
+	}
+}
+
+func TestSuggestFeeTiers(t *testing.T) {
+	server := newRPCServer(t, feeHandler)
+	defer server.Close()
+	gwei := func(v int64) *big.Int { return new(big.Int).Mul(big.NewInt(v), big.NewInt(1e9)) }
+	c, err := NewEthClient(&client.ChainConfiguration{
+		Endpoints:      []string{server.URL},
+		ChainID:        big.NewInt(1),
+		SupportEIP1559: true,
+		MinGasTipCap:   gwei(2),
+		MaxGasFeeCap:   gwei(200),
+	})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	tiers, err := c.SuggestFeeTiers()
+	assert.Nil(t, err, "suggest fee tiers failed")
+	assert.Equal(t, gwei(100), tiers.BaseFee)
+	// the median of 1, 2, 1 gwei is raised to the floor
+	assert.Equal(t, gwei(2), tiers.Slow.GasTipCap)
+	assert.Equal(t, gwei(127), tiers.Slow.GasFeeCap)
+	assert.Equal(t, gwei(2), tiers.Standard.GasTipCap)
+	assert.Equal(t, gwei(152), tiers.Standard.GasFeeCap)
+	// 200 + 5 gwei is capped
+	assert.Equal(t, gwei(5), tiers.Fast.GasTipCap)
+	assert.Equal(t, gwei(200), tiers.Fast.GasFeeCap)
+
+	fee, err := c.GetSuggestFee(&client.Transaction{From: batchOwner, To: batchOwner, FeeTier: client.FeeTierFast})
+	assert.Nil(t, err, "suggest fee failed")
+	assert.Equal(t, tiers.Fast.GasFeeCap, fee.GasFeeCap)
+	assert.Equal(t, tiers.Fast.GasTipCap, fee.GasTipCap)
+	assert.Equal(t, big.NewInt(25200), fee.Gas)
+}
+
+func TestSuggestFeeTiersFallback(t *testing.T) {
+	c, err := NewSimulatedEthClient(core.GenesisAlloc{})
+	assert.Nil(t, err, "create client failed")
+	tiers, err := c.SuggestFeeTiers()
+	assert.Nil(t, err, "suggest fee tiers failed")
+	assert.Equal(t, 1, tiers.BaseFee.Sign(), "base fee of the latest block expected")
+	assert.Equal(t, tiers.Slow.GasTipCap, tiers.Fast.GasTipCap, "tip suggested by the node expected")
+	assert.Equal(t, -1, tiers.Slow.GasFeeCap.Cmp(tiers.Fast.GasFeeCap), "fast tier should be higher")
+}
@@ -0,0 +1,249 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	defaultFollowInterval = 3 * time.Second
+	defaultMaxReorgDepth  = 64
+)
+
+// BlockEventType is the type of BlockEvent
+type BlockEventType int
+
+const (
+	// BlockAdded is emitted when a block is appended to the followed chain
+	BlockAdded BlockEventType = iota
+	// BlockRemoved is emitted when a block is removed from the followed chain by a reorganization
+	BlockRemoved
+)
+
+func (t BlockEventType) String() string {
+	if t == BlockRemoved {
+		return "removed"
+	}
+	return "added"
+}
+
+// BlockEvent is a block added to or removed from the followed chain
+// Header is nil for a removed block before the checkpoint whose header is not found by the node
+type BlockEvent struct {
+	Type   BlockEventType
+	Number uint64
+	Hash   common.Hash
+	Header *types.Header
+}
+
+// Checkpoint is the last block processed by the follower, the following starts from the block after it
+// a zero Hash skips the check of the parent hash for the first block
+type Checkpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// FollowerOptions configures the BlockFollower, the zero values use the defaults
+// Confirmations is how many blocks a block waits on top of it before it's added, 0 adds the latest block
+// PollInterval is how often the latest block is queried, 3s by default
+// MaxReorgDepth is how many blocks are kept to handle a reorganization, 64 by default
+type FollowerOptions struct {
+	Confirmations uint64
+	PollInterval  time.Duration
+	MaxReorgDepth int
+}
+
+// headerByHashBackend is implemented by the backends finding the blocks off the canonical chain by hash,
+// such as the node pool and the simulated chain
+type headerByHashBackend interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// errHeaderByHashUnsupported is returned by the backends without HeaderByHash
+var errHeaderByHashUnsupported = errors.New("header by hash not supported")
+
+// BlockFollower walks the blocks of an evm chain in order from a checkpoint
+// reorganizations are detected by the parent hashes, the blocks on the abandoned fork are
+// removed from the newest to the oldest before the blocks on the new fork are added
+type BlockFollower struct {
+	client  Backend
+	options FollowerOptions
+
+	mu    sync.Mutex
+	chain []*BlockEvent
+	// rewound is the number of blocks removed before the first block known, see rewind
+	rewound int
+}
+
+// NewBlockFollower creates the follower starting after the checkpoint
+func (e *EthClient) NewBlockFollower(from Checkpoint, options FollowerOptions) *BlockFollower {
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultFollowInterval
+	}
+	if options.MaxReorgDepth <= 0 {
+		options.MaxReorgDepth = defaultMaxReorgDepth
+	}
+	return &BlockFollower{
+		client:  e.client,
+		options: options,
+		chain:   []*BlockEvent{{Type: BlockAdded, Number: from.Number, Hash: from.Hash}},
+	}
+}
+
+// Checkpoint returns the last block processed, it's used to restart the follower
+func (f *BlockFollower) Checkpoint() Checkpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tip := f.chain[len(f.chain)-1]
+	return Checkpoint{Number: tip.Number, Hash: tip.Hash}
+}
+
+// Run follows the chain until ctx is done or handler returns an error, the events are passed to handler in order
+// a block is processed after handler returns nil for it, so Checkpoint can be saved in handler
+// the errors of the node are logged and retried in the next round
+func (f *BlockFollower) Run(ctx context.Context, handler func(BlockEvent) error) error {
+	ticker := time.NewTicker(f.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := f.follow(ctx, handler); err != nil {
+			var nodeErr *followError
+			if !errors.As(err, &nodeErr) {
+				return err
+			}
+			if ctx.Err() == nil {
+				log.Printf("follow blocks failed, err=%s\n", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// followError is an error of the node, which is retried by Run
+type followError struct {
+	err error
+}
+
+func (e *followError) Error() string { return e.err.Error() }
+func (e *followError) Unwrap() error { return e.err }
+
+// follow processes the blocks until the latest confirmed block
+func (f *BlockFollower) follow(ctx context.Context, handler func(BlockEvent) error) error {
+	latest, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return &followError{fmt.Errorf("get latest header failed, err=%w", err)}
+	}
+	if latest.Number.Uint64() < f.options.Confirmations {
+		return nil
+	}
+	target := latest.Number.Uint64() - f.options.Confirmations
+	for {
+		f.mu.Lock()
+		tip := f.chain[len(f.chain)-1]
+		f.mu.Unlock()
+		if tip.Number >= target {
+			return nil
+		}
+		header, err := f.client.HeaderByNumber(ctx, new(big.Int).SetUint64(tip.Number+1))
+		if err != nil {
+			if errors.Is(err, client.ErrNotFound) {
+				// the node serving the call is behind the node returned the latest block
+				return nil
+			}
+			return &followError{fmt.Errorf("get header=%d failed, err=%w", tip.Number+1, err)}
+		}
+		if tip.Hash != (common.Hash{}) && header.ParentHash != tip.Hash {
+			if err := f.remove(ctx, tip, handler); err != nil {
+				return err
+			}
+			continue
+		}
+		event := BlockEvent{Type: BlockAdded, Number: header.Number.Uint64(), Hash: header.Hash(), Header: header}
+		if err := handler(event); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.rewound = 0
+		f.chain = append(f.chain, &event)
+		if len(f.chain) > f.options.MaxReorgDepth {
+			f.chain = f.chain[len(f.chain)-f.options.MaxReorgDepth:]
+		}
+		f.mu.Unlock()
+	}
+}
+
+// remove removes the tip which is not the parent of the next block any more
+func (f *BlockFollower) remove(ctx context.Context, tip *BlockEvent, handler func(BlockEvent) error) error {
+	f.mu.Lock()
+	depth := len(f.chain)
+	f.mu.Unlock()
+	if depth == 1 {
+		return f.rewind(ctx, tip, handler)
+	}
+	removed := *tip
+	removed.Type = BlockRemoved
+	if err := handler(removed); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.chain = f.chain[:len(f.chain)-1]
+	f.mu.Unlock()
+	return nil
+}
+
+// rewind removes the tip when it's the only block known, such as the checkpoint after a restart, and replaces it
+// by its parent, which is checked against the next block again, so the abandoned blocks are walked back one by one
+// up to MaxReorgDepth blocks. The parent is read from the header of tip, which is kept by the node for a while after
+// the reorganization. If it's not found, the parent of the block at the height of tip on the new chain is taken as
+// the common ancestor, so the following goes on, but the removal of the blocks before tip is not emitted
+func (f *BlockFollower) rewind(ctx context.Context, tip *BlockEvent, handler func(BlockEvent) error) error {
+	if tip.Number == 0 || f.rewound >= f.options.MaxReorgDepth {
+		return fmt.Errorf("reorganization deeper than %d blocks at block=%d", f.options.MaxReorgDepth, tip.Number)
+	}
+	removed := *tip
+	removed.Type = BlockRemoved
+	var err error
+	if removed.Header == nil {
+		removed.Header, err = f.headerByHash(ctx, tip.Hash)
+	}
+	parent := &BlockEvent{Type: BlockAdded, Number: tip.Number - 1}
+	if removed.Header != nil {
+		parent.Hash = removed.Header.ParentHash
+	} else {
+		header, err2 := f.client.HeaderByNumber(ctx, new(big.Int).SetUint64(tip.Number))
+		if err2 != nil {
+			return &followError{fmt.Errorf("get header=%d failed, err=%w", tip.Number, err2)}
+		}
+		log.Printf("header of removed block=%d not found, err=%s, the blocks before it are taken as not removed\n", tip.Number, err)
+		parent.Hash = header.ParentHash
+	}
+	if err := handler(removed); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.chain = []*BlockEvent{parent}
+	f.rewound++
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *BlockFollower) headerByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	b, ok := f.client.(headerByHashBackend)
+	if !ok {
+		return nil, errHeaderByHashUnsupported
+	}
+	return b.HeaderByHash(ctx, hash)
+}
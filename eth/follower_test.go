@@ -0,0 +1,136 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+func TestBlockFollower(t *testing.T) {
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{}, simulatedGasLimit)
+	defer sim.Close()
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, sim)
+	assert.Nil(t, err, "create client failed")
+	genesis, _ := sim.HeaderByNumber(context.Background(), big.NewInt(0))
+	for i := 0; i < 4; i++ {
+		sim.Commit()
+	}
+
+	events := make(chan BlockEvent, 16)
+	follower := c.NewBlockFollower(Checkpoint{Number: 0, Hash: genesis.Hash()}, FollowerOptions{
+		Confirmations: 1,
+		PollInterval:  5 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- follower.Run(ctx, func(event BlockEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+	next := func() BlockEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no block event received")
+		}
+		return BlockEvent{}
+	}
+
+	// block 4 is not confirmed yet
+	var old common.Hash
+	for i := uint64(1); i <= 3; i++ {
+		event := next()
+		assert.Equal(t, BlockAdded, event.Type)
+		assert.Equal(t, i, event.Number)
+		old = event.Hash
+	}
+
+	// replace the blocks after block 1 by a longer fork with different hashes
+	block1, _ := sim.HeaderByNumber(context.Background(), big.NewInt(1))
+	assert.Nil(t, sim.Fork(context.Background(), block1.Hash()), "fork failed")
+	assert.Nil(t, sim.AdjustTime(time.Second), "adjust time failed")
+	for i := 0; i < 4; i++ {
+		sim.Commit()
+	}
+	event := next()
+	assert.Equal(t, BlockRemoved, event.Type)
+	assert.Equal(t, uint64(3), event.Number)
+	assert.Equal(t, old, event.Hash)
+	event = next()
+	assert.Equal(t, BlockRemoved, event.Type)
+	assert.Equal(t, uint64(2), event.Number)
+	for i := uint64(2); i <= 4; i++ {
+		event = next()
+		assert.Equal(t, BlockAdded, event.Type)
+		assert.Equal(t, i, event.Number)
+		header, _ := sim.HeaderByNumber(context.Background(), new(big.Int).SetUint64(i))
+		assert.Equal(t, header.Hash(), event.Hash, "block not on the new fork")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, Checkpoint{Number: 4, Hash: event.Hash}, follower.Checkpoint())
+}
+
+func TestBlockFollowerCheckpointReorg(t *testing.T) {
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{}, simulatedGasLimit)
+	defer sim.Close()
+	for i := 0; i < 3; i++ {
+		sim.Commit()
+	}
+	block1, _ := sim.HeaderByNumber(context.Background(), big.NewInt(1))
+	block2, _ := sim.HeaderByNumber(context.Background(), big.NewInt(2))
+	block3, _ := sim.HeaderByNumber(context.Background(), big.NewInt(3))
+	// the blocks 2 and 3 are replaced while the follower is stopped at the checkpoint of block 3
+	assert.Nil(t, sim.Fork(context.Background(), block1.Hash()), "fork failed")
+	assert.Nil(t, sim.AdjustTime(time.Second), "adjust time failed")
+	for i := 0; i < 4; i++ {
+		sim.Commit()
+	}
+
+	run := func(backend Backend) []BlockEvent {
+		c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, backend)
+		assert.Nil(t, err, "create client failed")
+		follower := c.NewBlockFollower(Checkpoint{Number: 3, Hash: block3.Hash()}, FollowerOptions{PollInterval: 5 * time.Millisecond})
+		var events []BlockEvent
+		ctx, cancel := context.WithCancel(context.Background())
+		err = follower.Run(ctx, func(event BlockEvent) error {
+			events = append(events, event)
+			if event.Type == BlockAdded && event.Number == 5 {
+				cancel()
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled, "follower should run until canceled")
+		return events
+	}
+
+	events := run(sim)
+	assert.Len(t, events, 6)
+	assert.Equal(t, BlockEvent{Type: BlockRemoved, Number: 3, Hash: block3.Hash(), Header: events[0].Header}, events[0])
+	assert.Equal(t, BlockEvent{Type: BlockRemoved, Number: 2, Hash: block2.Hash(), Header: events[1].Header}, events[1])
+	assert.Equal(t, block2.Hash(), events[1].Header.Hash(), "header of removed block not match")
+	for i, event := range events[2:] {
+		header, _ := sim.HeaderByNumber(context.Background(), big.NewInt(int64(i+2)))
+		assert.Equal(t, BlockAdded, event.Type)
+		assert.Equal(t, header.Hash(), event.Hash, "block not on the new fork")
+	}
+
+	// without the headers of the removed blocks, the following goes on from the parent on the new chain
+	events = run(struct{ Backend }{sim})
+	assert.Len(t, events, 4)
+	assert.Equal(t, BlockRemoved, events[0].Type)
+	assert.Equal(t, uint64(3), events[0].Number)
+	assert.Nil(t, events[0].Header)
+	assert.Equal(t, uint64(3), events[1].Number)
+}
@@ -0,0 +1,134 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// defaultLogRange is the number of blocks queried by one eth_getLogs at most
+const defaultLogRange = 5000
+
+// rangeErrors are the messages of the nodes rejecting eth_getLogs for too many results or too wide range
+var rangeErrors = []string{
+	"more than",             // geth, infura: query returned more than 10000 results
+	"too many",              // too many results, too many blocks
+	"block range",           // block range is too wide, exceed maximum block range
+	"range limit",           // range limit exceeded
+	"range too large",       // alchemy
+	"response size",         // response size exceeded
+	"limit exceeded",        // query limit exceeded
+	"query timeout",         // query timeout exceeded
+	"range is too large",    // quicknode
+	"exceeds the range",     // bsc
+	"exceed maximum blocks", // polygon
+}
+
+// LogFilter selects the logs of FilterLogs
+// FromBlock and ToBlock are inclusive, nil FromBlock is the genesis block and nil ToBlock is the latest block
+// Addresses are the contracts emitting the logs, empty matches all contracts
+// Topics follows the rules of eth_getLogs, Topics[i] matches any of the hashes at position i, empty matches any topic
+type LogFilter struct {
+	FromBlock *big.Int
+	ToBlock   *big.Int
+	Addresses []string
+	Topics    [][]common.Hash
+}
+
+// FilterLogs returns the logs matching filter in the order of the chain
+func (e *EthClient) FilterLogs(filter LogFilter) ([]*client.EventLog, error) {
+	return e.FilterLogsContext(context.Background(), filter)
+}
+
+// FilterLogsContext returns the logs matching filter in the order of the chain
+// the block range is split adaptively when the node rejects it for too many results or too wide range
+func (e *EthClient) FilterLogsContext(ctx context.Context, filter LogFilter) ([]*client.EventLog, error) {
+	query := ethereum.FilterQuery{Topics: filter.Topics}
+	for _, addr := range filter.Addresses {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid address=%s", addr)
+		}
+		query.Addresses = append(query.Addresses, common.HexToAddress(addr))
+	}
+	var from, to uint64
+	if filter.FromBlock != nil {
+		from = filter.FromBlock.Uint64()
+	}
+	if filter.ToBlock != nil {
+		to = filter.ToBlock.Uint64()
+	} else {
+		latest, err := e.GetLatestBlockNumberContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get latest block failed, err=%w", err)
+		}
+		to = latest.Uint64()
+	}
+
+	logs := make([]*client.EventLog, 0)
+	step := uint64(defaultLogRange)
+	for from <= to {
+		end := to
+		if end-from >= step {
+			end = from + step - 1
+		}
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(end)
+		result, err := e.client.FilterLogs(ctx, query)
+		if err != nil {
+			if !isRangeError(err) || end == from {
+				return nil, fmt.Errorf("filter logs of blocks %d-%d failed, err=%w", from, end, err)
+			}
+			// try the half of the range, the range grows again after the successful queries
+			step = (end - from + 1) / 2
+			continue
+		}
+		for i := range result {
+			logs = append(logs, toEventLog(&result[i]))
+		}
+		from = end + 1
+		if step < defaultLogRange {
+			step *= 2
+			if step > defaultLogRange {
+				step = defaultLogRange
+			}
+		}
+	}
+	return logs, nil
+}
+
+func isRangeError(err error) bool {
+	if errors.Is(err, client.ErrRateLimited) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, m := range rangeErrors {
+		if strings.Contains(message, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func toEventLog(log *types.Log) *client.EventLog {
+	event := client.EventLog{
+		Address:     log.Address.Hex(),
+		Data:        log.Data,
+		Removed:     log.Removed,
+		Topics:      make([][]byte, 0, len(log.Topics)),
+		BlockNumber: log.BlockNumber,
+		TxHash:      log.TxHash.Hex(),
+		LogIndex:    log.Index,
+	}
+	for j := range log.Topics {
+		topic := log.Topics[j]
+		event.Topics = append(event.Topics, topic[:])
+	}
+	return &event
+}
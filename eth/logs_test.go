@@ -0,0 +1,77 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// rangeLimitedBackend rejects eth_getLogs querying more than maxRange blocks like the public nodes
+type rangeLimitedBackend struct {
+	*backends.SimulatedBackend
+	maxRange uint64
+	queries  int
+}
+
+func (b *rangeLimitedBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	b.queries++
+	if q.ToBlock.Uint64()-q.FromBlock.Uint64()+1 > b.maxRange {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	return b.SimulatedBackend.FilterLogs(ctx, q)
+}
+
+func TestFilterLogs(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	parsed, _ := abi.JSON(strings.NewReader(strABI))
+	token, _, contract, err := bind.DeployContract(opts, parsed, common.FromHex(strBIN), sim)
+	assert.Nil(t, err, "deploy failed")
+	sim.Commit()
+	// one Transfer log in each of the blocks 2-6
+	for i := 0; i < 5; i++ {
+		_, err = contract.Transact(opts, "mint", big.NewInt(int64(i+1)))
+		assert.Nil(t, err, "mint failed")
+		sim.Commit()
+	}
+
+	backend := &rangeLimitedBackend{SimulatedBackend: sim, maxRange: 2}
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, backend)
+	assert.Nil(t, err, "create client failed")
+	assert.Nil(t, c.RegisterABI("token", strABI), "register abi failed")
+	logs, err := c.FilterLogs(LogFilter{
+		Addresses: []string{token.Hex()},
+		Topics:    [][]common.Hash{{parsed.Events["Transfer"].ID}},
+	})
+	assert.Nil(t, err, "filter logs failed")
+	assert.Len(t, logs, 5)
+	assert.Greater(t, backend.queries, 1, "range should be split")
+	for i, log := range logs {
+		assert.Equal(t, uint64(i+2), log.BlockNumber, "block number not match")
+		assert.NotEqual(t, common.Hash{}.Hex(), log.TxHash, "tx hash expected")
+		fields, err := c.ParseEventLog("token", log)
+		assert.Nil(t, err, "parse log failed")
+		assert.Equal(t, big.NewInt(int64(i+1)), c.AbiConvertToInt(fields[0]), "value not match")
+	}
+
+	logs, err = c.FilterLogs(LogFilter{FromBlock: big.NewInt(4), ToBlock: big.NewInt(4), Addresses: []string{token.Hex()}})
+	assert.Nil(t, err, "filter logs failed")
+	assert.Len(t, logs, 1)
+}
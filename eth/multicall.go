@@ -0,0 +1,182 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	// defaultMulticall3Address is the address of Multicall3 on most evm chains, see https://www.multicall3.com
+	defaultMulticall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11"
+	multicall3ABIName        = "multicall3"
+	multicall3Abi            = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+	// maxMulticallCalls and maxMulticallSize bound one aggregate3 call, larger batches are split before sending
+	maxMulticallCalls = 500
+	maxMulticallSize  = 100 * 1024
+)
+
+// Call is an entry of Multicall, Data is the calldata sent to Target
+// the call may fail without failing the others if AllowFailure is set
+// Method and ABI are optional, the return data is unpacked by UnpackByABI(Method, ABI, ...) when they are set
+type Call struct {
+	Target       string
+	Data         []byte
+	AllowFailure bool
+	Method       string
+	ABI          string
+}
+
+// CallResult is the result of a Call, Values is set when the call succeeded and Method and ABI are set
+// Err is set when the call failed or the return data failed to unpack
+type CallResult struct {
+	Success    bool
+	ReturnData []byte
+	Values     []interface{}
+	Err        error
+}
+
+// call3 and result3 are the Call3 and Result structs of Multicall3
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result3 struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Multicall executes the calls by Multicall3 aggregate3 in as few eth_call as possible
+func (e *EthClient) Multicall(calls []Call) ([]CallResult, error) {
+	return e.MulticallContext(context.Background(), calls)
+}
+
+// MulticallContext executes the calls by Multicall3 aggregate3 in as few eth_call as possible
+// the calls are split when a batch exceeds the limits of size or gas. The error is returned
+// when a call without AllowFailure failed or a batch can't be executed
+func (e *EthClient) MulticallContext(ctx context.Context, calls []Call) ([]CallResult, error) {
+	results := make([]CallResult, 0, len(calls))
+	for start := 0; start < len(calls); {
+		end, size := start, 0
+		for end < len(calls) && end-start < maxMulticallCalls && (end == start || size+len(calls[end].Data) <= maxMulticallSize) {
+			size += len(calls[end].Data)
+			end++
+		}
+		batch, err := e.aggregate3(ctx, calls[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+		start = end
+	}
+	for i := range results {
+		e.decodeCall(&calls[i], &results[i])
+	}
+	return results, nil
+}
+
+// aggregate3 executes the calls in one eth_call, and splits them in halves when the call
+// runs out of gas or is too large for the node
+func (e *EthClient) aggregate3(ctx context.Context, calls []Call) ([]CallResult, error) {
+	input := make([]call3, len(calls))
+	for i, c := range calls {
+		if !common.IsHexAddress(c.Target) {
+			return nil, fmt.Errorf("invalid target address=%s", c.Target)
+		}
+		input[i] = call3{Target: common.HexToAddress(c.Target), AllowFailure: c.AllowFailure, CallData: c.Data}
+	}
+	data, err := e.GetTransactionDataByABI("aggregate3", multicall3ABIName, input)
+	if err != nil {
+		return nil, fmt.Errorf("pack aggregate3 failed, err=%w", err)
+	}
+	output, err := e.client.CallContract(ctx, ethereum.CallMsg{To: &e.multicall3, Data: data}, nil)
+	if err != nil {
+		if len(calls) > 1 && splittable(ctx, err) {
+			half := len(calls) / 2
+			first, err := e.aggregate3(ctx, calls[:half])
+			if err != nil {
+				return nil, err
+			}
+			second, err := e.aggregate3(ctx, calls[half:])
+			if err != nil {
+				return nil, err
+			}
+			return append(first, second...), nil
+		}
+		return nil, fmt.Errorf("call multicall3 failed, err=%w", err)
+	}
+	fields, err := e.UnpackByABI("aggregate3", multicall3ABIName, output)
+	if err != nil {
+		return nil, fmt.Errorf("unpack aggregate3 failed, err=%w", err)
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("wrong return format")
+	}
+	returned := *abi.ConvertType(fields[0], new([]result3)).(*[]result3)
+	if len(returned) != len(calls) {
+		return nil, fmt.Errorf("multicall3 returned %d results for %d calls", len(returned), len(calls))
+	}
+	results := make([]CallResult, len(calls))
+	for i, r := range returned {
+		results[i] = CallResult{Success: r.Success, ReturnData: r.ReturnData}
+	}
+	return results, nil
+}
+
+// splitErrors are the messages of the errors caused by the gas or the size of a batch
+var splitErrors = []string{
+	"out of gas",
+	"gas required exceeds",
+	"exceeds block gas limit",
+	"response size",
+	"response too large",
+}
+
+// splittable returns whether the failed batch may succeed in smaller batches, which is only when
+// it ran out of gas or its request or response is too large, the other errors persist after splitting
+func splittable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusRequestEntityTooLarge {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, s := range splitErrors {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *EthClient) decodeCall(c *Call, r *CallResult) {
+	if !r.Success {
+		if len(r.ReturnData) > 0 {
+			r.Err = e.decodeRevert(revertFromData(r.ReturnData))
+		} else {
+			r.Err = client.NewError(client.ErrReverted, fmt.Errorf("call to %s reverted", c.Target))
+		}
+		return
+	}
+	if c.Method == "" || c.ABI == "" {
+		return
+	}
+	r.Values, r.Err = e.UnpackByABI(c.Method, c.ABI, r.ReturnData)
+	if r.Err != nil {
+		r.Err = fmt.Errorf("unpack %s failed, err=%w", c.Method, r.Err)
+	}
+}
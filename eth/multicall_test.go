@@ -0,0 +1,115 @@
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const testMulticall3 = "0x00000000000000000000000000000000000Ca113"
+
+// multicallHandler executes aggregate3 at testMulticall3, the calls to batchReverted revert with "bad"
+// and a batch with more than maxCalls calls runs out of gas
+func multicallHandler(t *testing.T, maxCalls int, requests *int32) rpcHandler {
+	multicall, _ := abi.JSON(strings.NewReader(multicall3Abi))
+	errorABI, _ := abi.NewType("string", "", nil)
+	return func(method string, params []json.RawMessage) (interface{}, error) {
+		atomic.AddInt32(requests, 1)
+		var arg struct {
+			To    common.Address `json:"to"`
+			Input hexutil.Bytes  `json:"input"`
+			Data  hexutil.Bytes  `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(params[0], &arg), "decode call failed")
+		assert.Equal(t, common.HexToAddress(testMulticall3), arg.To, "multicall3 address not match")
+		input := arg.Input
+		if len(input) == 0 {
+			input = arg.Data
+		}
+		args, err := multicall.Methods["aggregate3"].Inputs.Unpack(input[4:])
+		assert.Nil(t, err, "unpack aggregate3 failed")
+		calls := *abi.ConvertType(args[0], new([]call3)).(*[]call3)
+		if len(calls) > maxCalls {
+			return nil, errors.New("out of gas")
+		}
+		results := make([]result3, len(calls))
+		for i, c := range calls {
+			if c.Target == common.HexToAddress(batchReverted) {
+				reason, _ := abi.Arguments{{Type: errorABI}}.Pack("bad")
+				results[i] = result3{ReturnData: append(hexutil.MustDecode("0x08c379a0"), reason...)}
+				continue
+			}
+			results[i] = result3{Success: true, ReturnData: common.LeftPadBytes(big.NewInt(40).Bytes(), 32)}
+		}
+		output, err := multicall.Methods["aggregate3"].Outputs.Pack(results)
+		assert.Nil(t, err, "pack results failed")
+		return hexutil.Encode(output), nil
+	}
+}
+
+func TestMulticall(t *testing.T) {
+	var requests int32
+	server := newRPCServer(t, multicallHandler(t, 2, &requests))
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{
+		Endpoints:         []string{server.URL},
+		ChainID:           big.NewInt(1),
+		Multicall3Address: testMulticall3,
+	})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	data, err := c.GetTransactionDataByABI("balanceOf", erc20ABIName, common.HexToAddress(batchOwner))
+	assert.Nil(t, err, "pack balanceOf failed")
+	calls := []Call{
+		{Target: batchToken, Data: data, Method: "balanceOf", ABI: erc20ABIName},
+		{Target: batchToken, Data: data},
+		{Target: batchToken, Data: data, Method: "balanceOf", ABI: erc20ABIName},
+		{Target: batchReverted, Data: data, AllowFailure: true},
+	}
+	results, err := c.Multicall(calls)
+	assert.Nil(t, err, "multicall failed")
+	assert.Len(t, results, 4)
+	// the batch of 4 calls runs out of gas and is split into 2 batches
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, big.NewInt(40), c.AbiConvertToInt(results[0].Values[0]))
+	assert.Nil(t, results[1].Values, "no abi to unpack")
+	assert.Len(t, results[1].ReturnData, 32)
+	assert.False(t, results[3].Success)
+	assert.ErrorIs(t, results[3].Err, client.ErrReverted)
+	assert.Contains(t, results[3].Err.Error(), "bad")
+}
+
+func TestMulticallNotSplit(t *testing.T) {
+	var requests int32
+	server := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, error) {
+		atomic.AddInt32(&requests, 1)
+		return nil, errors.New("invalid opcode: INVALID")
+	})
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{
+		Endpoints:         []string{server.URL},
+		ChainID:           big.NewInt(1),
+		Multicall3Address: testMulticall3,
+	})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	calls := make([]Call, 8)
+	for i := range calls {
+		calls[i] = Call{Target: batchToken, Data: []byte{1, 2, 3, 4}}
+	}
+	_, err = c.Multicall(calls)
+	assert.NotNil(t, err, "multicall should fail")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "the batch should not be split on a persistent error")
+}
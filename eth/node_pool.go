@@ -0,0 +1,639 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	defaultHealthCheckInterval = 15 * time.Second
+	defaultMaxBlockLag         = 5
+	healthCheckTimeout         = 5 * time.Second
+	// a node is unhealthy when more than half of the calls since last check failed on transport
+	maxErrorRate      = 0.5
+	minErrorRateCalls = 10
+)
+
+// NodeStatus is the health information of an endpoint in the pool
+type NodeStatus struct {
+	Endpoint    string
+	Active      bool
+	Healthy     bool
+	BlockNumber uint64
+	BlockLag    uint64
+	Latency     time.Duration
+	Requests    uint64
+	Failures    uint64
+	LastError   string
+	LastCheck   time.Time
+}
+
+type node struct {
+	endpoint   string
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	rpc         *rpc.Client
+	client      *ethclient.Client
+	healthy     bool
+	blockNumber uint64
+	blockLag    uint64
+	latency     time.Duration
+	requests    uint64
+	failures    uint64
+	// calls and errors since last health check, used to calculate the error rate
+	windowCalls  uint64
+	windowErrors uint64
+	lastError    string
+	lastCheck    time.Time
+}
+
+// nodePool holds the connections to all the endpoints of a chain
+// calls are sent to the active node and fail over to other nodes on transport errors, except SendTransaction
+type nodePool struct {
+	nodes       []*node
+	maxBlockLag uint64
+
+	mu     sync.RWMutex
+	active int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newNodePool(config *client.ChainConfiguration) (*nodePool, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint configured")
+	}
+	p := &nodePool{
+		nodes:       make([]*node, 0, len(config.Endpoints)),
+		maxBlockLag: config.MaxBlockLag,
+		stop:        make(chan struct{}),
+	}
+	if p.maxBlockLag == 0 {
+		p.maxBlockLag = defaultMaxBlockLag
+	}
+	connected := false
+	var dialErr error
+	for _, endpoint := range config.Endpoints {
+		n := &node{endpoint: endpoint, httpClient: &http.Client{Timeout: config.Timeout, Transport: config.Transport}, healthy: true}
+		if err := n.dial(context.Background()); err != nil {
+			n.healthy, n.lastError = false, err.Error()
+			if dialErr == nil {
+				dialErr = err
+			}
+		} else {
+			connected = true
+		}
+		p.nodes = append(p.nodes, n)
+	}
+	if !connected {
+		return nil, dialErr
+	}
+	for i, n := range p.nodes {
+		if n.healthy {
+			p.active = i
+			break
+		}
+	}
+	// nothing to fail over to with a single endpoint
+	if len(p.nodes) > 1 {
+		interval := config.HealthCheckInterval
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		p.wg.Add(1)
+		go p.healthCheckLoop(interval)
+	}
+	return p, nil
+}
+
+func (n *node) dial(ctx context.Context) error {
+	rpcClient, err := rpc.DialOptions(ctx, n.endpoint, rpc.WithHTTPClient(n.httpClient))
+	if err != nil {
+		return toClientError(ctx, fmt.Errorf("failed to connect to endpoint=%s, err=%w", n.endpoint, err))
+	}
+	n.mu.Lock()
+	n.rpc = rpcClient
+	n.client = ethclient.NewClient(rpcClient)
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *node) conn() (*rpc.Client, *ethclient.Client) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.rpc, n.client
+}
+
+func (n *node) record(ctx context.Context, err error) bool {
+	transport := isTransportError(ctx, err)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.requests++
+	n.windowCalls++
+	if transport {
+		n.failures++
+		n.windowErrors++
+		n.healthy = false
+		n.lastError = err.Error()
+	}
+	return transport
+}
+
+func (n *node) status() NodeStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return NodeStatus{
+		Endpoint:    n.endpoint,
+		Healthy:     n.healthy,
+		BlockNumber: n.blockNumber,
+		BlockLag:    n.blockLag,
+		Latency:     n.latency,
+		Requests:    n.requests,
+		Failures:    n.failures,
+		LastError:   n.lastError,
+		LastCheck:   n.lastCheck,
+	}
+}
+
+func (n *node) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.rpc != nil {
+		n.rpc.Close()
+	}
+}
+
+// isTransportError tells if the error is caused by the connection to the node instead of the request itself
+// errors returned by the node in json-rpc response are not transport errors
+func isTransportError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// candidates returns the nodes to try in order: the active node, then other healthy nodes,
+// then the unhealthy nodes as the last resort
+func (p *nodePool) candidates() []*node {
+	p.mu.RLock()
+	active := p.active
+	p.mu.RUnlock()
+	result := make([]*node, 0, len(p.nodes))
+	result = append(result, p.nodes[active])
+	unhealthy := make([]*node, 0)
+	for i, n := range p.nodes {
+		if i == active {
+			continue
+		}
+		if n.status().Healthy {
+			result = append(result, n)
+		} else {
+			unhealthy = append(unhealthy, n)
+		}
+	}
+	return append(result, unhealthy...)
+}
+
+// do runs the call on the active node, and fails over to the next node when a transport error happens
+func (p *nodePool) do(ctx context.Context, call func(rpcClient *rpc.Client, ethClient *ethclient.Client) error) error {
+	var lastErr error
+	for _, n := range p.candidates() {
+		rpcClient, ethClient := n.conn()
+		if ethClient == nil {
+			continue
+		}
+		err := call(rpcClient, ethClient)
+		if !p.record(ctx, n, err) {
+			return toClientError(ctx, err)
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return client.NewError(client.ErrTransport, fmt.Errorf("no endpoint available"))
+	}
+	return toClientError(ctx, lastErr)
+}
+
+// record records the result of a call on n and fails over if it's a transport error, which is returned
+func (p *nodePool) record(ctx context.Context, n *node, err error) bool {
+	if n.record(ctx, err) {
+		p.failover()
+		return true
+	}
+	if err == nil && len(p.nodes) == 1 {
+		// no health check for a single endpoint, recover it by successful calls
+		n.mu.Lock()
+		n.healthy = true
+		n.mu.Unlock()
+	}
+	return false
+}
+
+// isDialError tells if the connection to the node is not made, so the request is never received by the node
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// failover switches the active node to the healthy node with the lowest latency
+func (p *nodePool) failover() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.nodes[p.active].status().Healthy {
+		return
+	}
+	best := -1
+	var bestLatency time.Duration
+	for i, n := range p.nodes {
+		status := n.status()
+		if !status.Healthy {
+			continue
+		}
+		if best < 0 || status.Latency < bestLatency {
+			best, bestLatency = i, status.Latency
+		}
+	}
+	if best >= 0 {
+		p.active = best
+	}
+}
+
+func (p *nodePool) healthCheckLoop(interval time.Duration) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth queries the latest block of every node, a node is unhealthy when the call fails,
+// it falls behind the highest block by more than maxBlockLag, or too many calls failed since last check
+func (p *nodePool) checkHealth() {
+	heights := make([]uint64, len(p.nodes))
+	errs := make([]error, len(p.nodes))
+	latencies := make([]time.Duration, len(p.nodes))
+	var wg sync.WaitGroup
+	for i := range p.nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			heights[i], latencies[i], errs[i] = p.nodes[i].check()
+		}(i)
+	}
+	wg.Wait()
+
+	var highest uint64
+	for i := range p.nodes {
+		if errs[i] == nil && heights[i] > highest {
+			highest = heights[i]
+		}
+	}
+	now := time.Now()
+	for i, n := range p.nodes {
+		n.mu.Lock()
+		n.lastCheck = now
+		n.latency = latencies[i]
+		healthy := errs[i] == nil
+		if errs[i] != nil {
+			n.lastError = errs[i].Error()
+		} else {
+			n.blockNumber = heights[i]
+			n.blockLag = highest - heights[i]
+			if n.blockLag > p.maxBlockLag {
+				healthy = false
+				n.lastError = fmt.Sprintf("block lag=%d exceeds limit=%d", n.blockLag, p.maxBlockLag)
+			}
+		}
+		if n.windowCalls >= minErrorRateCalls && float64(n.windowErrors)/float64(n.windowCalls) > maxErrorRate {
+			healthy = false
+			n.lastError = fmt.Sprintf("error rate=%d/%d too high", n.windowErrors, n.windowCalls)
+		}
+		n.windowCalls, n.windowErrors = 0, 0
+		n.healthy = healthy
+		n.mu.Unlock()
+	}
+	p.failover()
+}
+
+func (n *node) check() (uint64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	_, ethClient := n.conn()
+	if ethClient == nil {
+		if err := n.dial(ctx); err != nil {
+			return 0, 0, err
+		}
+		_, ethClient = n.conn()
+	}
+	start := time.Now()
+	header, err := ethClient.HeaderByNumber(ctx, nil)
+	latency := time.Since(start)
+	if err != nil {
+		return 0, latency, err
+	}
+	return header.Number.Uint64(), latency, nil
+}
+
+// Status returns the status of all nodes in the configured order
+func (p *nodePool) Status() []NodeStatus {
+	p.mu.RLock()
+	active := p.active
+	p.mu.RUnlock()
+	result := make([]NodeStatus, 0, len(p.nodes))
+	for i, n := range p.nodes {
+		status := n.status()
+		status.Active = i == active
+		result = append(result, status)
+	}
+	return result
+}
+
+// Close stops the health check and closes all connections
+func (p *nodePool) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+	for _, n := range p.nodes {
+		n.close()
+	}
+}
+
+// CallContext performs a raw json-rpc call
+func (p *nodePool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return p.do(ctx, func(rpcClient *rpc.Client, _ *ethclient.Client) error {
+		return rpcClient.CallContext(ctx, result, method, args...)
+	})
+}
+
+// BatchCallContext sends the json-rpc calls in one request, the errors of the calls are set in b
+func (p *nodePool) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return p.do(ctx, func(rpcClient *rpc.Client, _ *ethclient.Client) error {
+		return rpcClient.BatchCallContext(ctx, b)
+	})
+}
+
+func (p *nodePool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result *big.Int
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// CallContract sends eth_call with the fee caps and the access list of msg, which ethclient drops
+func (p *nodePool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	err := p.do(ctx, func(rpcClient *rpc.Client, _ *ethclient.Client) error {
+		return rpcClient.CallContext(ctx, &result, "eth_call", toCallMsgArg(msg), toBlockNumArg(blockNumber))
+	})
+	return result, err
+}
+
+// toCallMsgArg is the json-rpc call object of msg
+func toCallMsgArg(msg ethereum.CallMsg) map[string]interface{} {
+	arg := map[string]interface{}{"from": msg.From, "to": msg.To}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	if len(msg.AccessList) > 0 {
+		arg["accessList"] = msg.AccessList
+	}
+	return arg
+}
+
+// toBlockNumArg is the json-rpc block parameter of number, nil is the latest block
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	switch number.Int64() {
+	case int64(rpc.PendingBlockNumber):
+		return "pending"
+	case int64(rpc.FinalizedBlockNumber):
+		return "finalized"
+	case int64(rpc.SafeBlockNumber):
+		return "safe"
+	}
+	return hexutil.EncodeBig(number)
+}
+
+func (p *nodePool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result uint64
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.PendingNonceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	var result uint64
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.SuggestGasPrice(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.SuggestGasTipCap(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var result uint64
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.EstimateGas(ctx, msg)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var result *types.Header
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.HeaderByHash(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var result *types.Header
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// SendTransaction sends tx to the active node, it's not resent to other nodes on a transport error such as a timeout,
+// since the node may have received tx and the other nodes would reject it by "already known" or "nonce too low"
+// ErrTransport means tx may be sent or not, which is checked by the hash of tx before sending it again
+// tx is sent to the next node only if the connection to the node is not made
+func (p *nodePool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	var lastErr error
+	for _, n := range p.candidates() {
+		_, ethClient := n.conn()
+		if ethClient == nil {
+			continue
+		}
+		err := ethClient.SendTransaction(ctx, tx)
+		if p.record(ctx, n, err) && isDialError(err) {
+			lastErr = err
+			continue
+		}
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "already known") {
+			// tx is in the mempool of the node already, such as when it's sent again after ErrTransport
+			return nil
+		}
+		return toClientError(ctx, err)
+	}
+	if lastErr == nil {
+		return client.NewError(client.ErrTransport, fmt.Errorf("no endpoint available"))
+	}
+	return toClientError(ctx, lastErr)
+}
+
+func (p *nodePool) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var result *types.Transaction
+	var isPending bool
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, isPending, err = c.TransactionByHash(ctx, hash)
+		return err
+	})
+	return result, isPending, err
+}
+
+func (p *nodePool) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	var result *types.Receipt
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+func (p *nodePool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var result []types.Log
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.FilterLogs(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+// subscribe runs the subscription on the active node, it fails over like other calls but the error of an
+// endpoint not supporting notifications, such as http, is returned as it is without marking the node unhealthy
+func (p *nodePool) subscribe(ctx context.Context, call func(c *ethclient.Client) (ethereum.Subscription, error)) (ethereum.Subscription, error) {
+	var result ethereum.Subscription
+	var unsupported error
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = call(c)
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			unsupported = err
+			return nil
+		}
+		return err
+	})
+	if unsupported != nil {
+		return nil, unsupported
+	}
+	return result, err
+}
+
+func (p *nodePool) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return p.subscribe(ctx, func(c *ethclient.Client) (ethereum.Subscription, error) {
+		return c.SubscribeNewHead(ctx, ch)
+	})
+}
+
+func (p *nodePool) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return p.subscribe(ctx, func(c *ethclient.Client) (ethereum.Subscription, error) {
+		return c.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+func (p *nodePool) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	var result *ethereum.FeeHistory
+	err := p.do(ctx, func(_ *rpc.Client, c *ethclient.Client) (err error) {
+		result, err = c.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+		return err
+	})
+	return result, err
+}
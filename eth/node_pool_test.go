@@ -0,0 +1,210 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// rpcHandler returns the result for a method or an error, an *rpcError sets the code of the error
+type rpcHandler func(method string, params []json.RawMessage) (interface{}, error)
+
+// rpcError is a json-rpc error with a code other than -32000, data is set for the reverts
+type rpcError struct {
+	code    int
+	message string
+	data    string
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+// newRPCServer starts a json-rpc server answering the requests and the batches with handler
+func newRPCServer(t *testing.T, handler rpcHandler) *httptest.Server {
+	return httptest.NewServer(rpcHTTPHandler(t, handler))
+}
+
+// rpcHTTPHandler serves the json-rpc requests and the batches with handler
+func rpcHTTPHandler(t *testing.T, handler rpcHandler) http.Handler {
+	type request struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	answer := func(req request) map[string]interface{} {
+		result, err := handler(req.Method, req.Params)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		var rpcErr *rpcError
+		switch {
+		case errors.As(err, &rpcErr):
+			errResp := map[string]interface{}{"code": rpcErr.code, "message": rpcErr.message}
+			if rpcErr.data != "" {
+				errResp["data"] = rpcErr.data
+			}
+			resp["error"] = errResp
+		case err != nil:
+			resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+		default:
+			resp["result"] = result
+		}
+		return resp
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err, "read request failed")
+		w.Header().Set("Content-Type", "application/json")
+		if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+			var reqs []request
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			responses := make([]map[string]interface{}, 0, len(reqs))
+			for _, req := range reqs {
+				responses = append(responses, answer(req))
+			}
+			json.NewEncoder(w).Encode(responses)
+			return
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(answer(req))
+	})
+}
+
+func testHeader(number int64) *types.Header {
+	return &types.Header{
+		Number:     big.NewInt(number),
+		Difficulty: big.NewInt(0),
+		Time:       uint64(time.Now().Unix()),
+	}
+}
+
+func TestNodePoolFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, error) {
+		return hexutil.EncodeBig(big.NewInt(100)), nil
+	})
+	defer up.Close()
+
+	c, err := NewEthClient(&client.ChainConfiguration{
+		Endpoints:           []string{down.URL, up.URL},
+		ChainID:             big.NewInt(1),
+		HealthCheckInterval: time.Hour,
+	})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	balance, err := c.BalanceAt("0xa70fdFd8a32b6c0f32e246B53Fa45B3B372A73D8")
+	assert.Nil(t, err, "call should fail over to the healthy node")
+	assert.Equal(t, big.NewInt(100), balance, "balance not match")
+
+	status := c.NodeStatus()
+	assert.Len(t, status, 2, "status of all nodes")
+	assert.False(t, status[0].Healthy, "failed node should be unhealthy")
+	assert.False(t, status[0].Active, "failed node should not be active")
+	assert.Equal(t, uint64(1), status[0].Failures, "failure should be counted")
+	assert.True(t, status[1].Healthy, "node should be healthy")
+	assert.True(t, status[1].Active, "node should be active")
+}
+
+func TestNodePoolHealthCheck(t *testing.T) {
+	newServer := func(height int64) *httptest.Server {
+		return newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, error) {
+			return testHeader(height), nil
+		})
+	}
+	lagging := newServer(100)
+	defer lagging.Close()
+	synced := newServer(200)
+	defer synced.Close()
+
+	pool, err := newNodePool(&client.ChainConfiguration{
+		Endpoints:           []string{lagging.URL, synced.URL},
+		HealthCheckInterval: time.Hour,
+		MaxBlockLag:         10,
+	})
+	assert.Nil(t, err, "create pool failed")
+	defer pool.Close()
+
+	pool.checkHealth()
+	status := pool.Status()
+	assert.False(t, status[0].Healthy, "lagging node should be unhealthy")
+	assert.Equal(t, uint64(100), status[0].BlockLag, "block lag not match")
+	assert.True(t, status[1].Healthy, "synced node should be healthy")
+	assert.True(t, status[1].Active, "synced node should be active")
+	assert.Equal(t, uint64(200), status[1].BlockNumber, "block number not match")
+}
+
+func TestNodePoolSendTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)}), types.HomesteadSigner{}, key)
+	assert.Nil(t, err, "sign failed")
+	var sent int32
+	newServer := func(delay time.Duration, sendErr error) *httptest.Server {
+		return newRPCServer(t, func(method string, params []json.RawMessage) (interface{}, error) {
+			atomic.AddInt32(&sent, 1)
+			time.Sleep(delay)
+			return tx.Hash(), sendErr
+		})
+	}
+	newPool := func(endpoints ...string) *nodePool {
+		pool, err := newNodePool(&client.ChainConfiguration{
+			Endpoints:           endpoints,
+			Timeout:             100 * time.Millisecond,
+			HealthCheckInterval: time.Hour,
+		})
+		assert.Nil(t, err, "create pool failed")
+		return pool
+	}
+	ok := newServer(0, nil)
+	defer ok.Close()
+
+	// the timed out node may have received tx, it's not resent to the other node
+	slow := newServer(300*time.Millisecond, nil)
+	defer slow.Close()
+	pool := newPool(slow.URL, ok.URL)
+	defer pool.Close()
+	err = pool.SendTransaction(context.Background(), tx)
+	assert.ErrorIs(t, err, client.ErrTransport, "timeout should be a transport error")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sent), "tx should be sent once")
+	assert.True(t, pool.Status()[1].Active, "next call should use the other node")
+
+	// tx sent again is known by the node
+	known := newServer(0, errors.New("already known"))
+	defer known.Close()
+	pool = newPool(known.URL)
+	defer pool.Close()
+	assert.Nil(t, pool.SendTransaction(context.Background(), tx), "known tx should succeed")
+
+	// tx is sent to the next node if the connection is not made
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	atomic.StoreInt32(&sent, 0)
+	pool = newPool(down.URL, ok.URL)
+	defer pool.Close()
+	assert.Nil(t, pool.SendTransaction(context.Background(), tx), "send should fail over")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&sent), "tx should be sent once")
+}
@@ -0,0 +1,245 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// Permit2Address is the address of Uniswap Permit2 on all evm chains, see https://github.com/Uniswap/permit2
+	Permit2Address = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+
+	permitABIName  = "permit"
+	permitAbi      = `[{"inputs":[{"name":"owner","type":"address"}],"name":"nonces","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"DOMAIN_SEPARATOR","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"version","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"name":"permit","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+	permit2ABIName = "permit2"
+	permit2Abi     = `[{"inputs":[{"components":[{"components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}],"name":"permitted","type":"tuple"},{"name":"nonce","type":"uint256"},{"name":"deadline","type":"uint256"}],"name":"permit","type":"tuple"},{"components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}],"name":"transferDetails","type":"tuple"},{"name":"owner","type":"address"},{"name":"signature","type":"bytes"}],"name":"permitTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+	// defaultPermitVersion is the version of the tokens without version(), such as the tokens of OpenZeppelin ERC20Permit
+	defaultPermitVersion = "1"
+)
+
+var (
+	permitDomainTypes = []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	permitTypes = []apitypes.Type{
+		{Name: "owner", Type: "address"},
+		{Name: "spender", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	}
+	permit2DomainTypes = []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	permitTransferFromTypes = []apitypes.Type{
+		{Name: "permitted", Type: "TokenPermissions"},
+		{Name: "spender", Type: "address"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	}
+	tokenPermissionsTypes = []apitypes.Type{
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	}
+)
+
+// PermitInfo is the EIP-2612 permit support of a token
+// Supported is set when the token has nonces and DOMAIN_SEPARATOR, and the domain separator matches the one built from
+// name, version and the chain, otherwise the permit signed by PermitTypedData would be rejected by the token
+// Version is "1" if the token has no version()
+type PermitInfo struct {
+	Supported       bool
+	Name            string
+	Version         string
+	Nonce           *big.Int
+	DomainSeparator []byte
+}
+
+// Permit2Transfer is the signature transfer of Uniswap Permit2, Spender is the contract calling permitTransferFrom
+// Nonce is any unused nonce of the owner, Permit2 nonces are unordered
+type Permit2Transfer struct {
+	Token    string
+	Amount   *big.Int
+	Spender  string
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// PermitInfo reads the permit support of contract and the permit nonce of owner
+func (e *EthClient) PermitInfo(contract, owner string) (*PermitInfo, error) {
+	return e.PermitInfoContext(context.Background(), contract, owner)
+}
+
+// PermitInfoContext reads the permit support of contract and the permit nonce of owner by nonces, DOMAIN_SEPARATOR,
+// name and version calls in one json-rpc batch, the tokens without permit are not an error
+func (e *EthClient) PermitInfoContext(ctx context.Context, contract, owner string) (*PermitInfo, error) {
+	contractAddr := common.HexToAddress(contract)
+	methods := []string{"nonces", "DOMAIN_SEPARATOR", "name", "version"}
+	reads := make([]*read, len(methods))
+	for i, method := range methods {
+		var args []interface{}
+		if method == "nonces" {
+			args = append(args, common.HexToAddress(owner))
+		}
+		reads[i] = &read{account: common.HexToAddress(owner), contract: &contractAddr}
+		reads[i].data, reads[i].err = e.GetTransactionDataByABI(method, permitABIName, args...)
+	}
+	if err := e.readAll(ctx, pending(reads)); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(methods))
+	for i, method := range methods {
+		if reads[i].err != nil || len(reads[i].output) == 0 {
+			continue
+		}
+		if res, err := e.UnpackByABI(method, permitABIName, reads[i].output); err == nil && len(res) > 0 {
+			values[i] = res[0]
+		}
+	}
+
+	info := &PermitInfo{Version: defaultPermitVersion}
+	nonce, ok1 := values[0].(*big.Int)
+	separator, ok2 := values[1].([32]byte)
+	name, ok3 := values[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return info, nil
+	}
+	if version, ok := values[3].(string); ok {
+		info.Version = version
+	}
+	info.Name, info.Nonce, info.DomainSeparator = name, nonce, separator[:]
+	typedData := e.permitTypedData(contract, info, apitypes.TypedDataMessage{})
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("hash domain failed, err=%w", err)
+	}
+	info.Supported = bytes.Equal(domainSeparator, info.DomainSeparator)
+	return info, nil
+}
+
+// PermitTypedData returns the EIP-2612 permit of value from owner to spender in typed data json
+func (e *EthClient) PermitTypedData(contract, owner, spender string, value, deadline *big.Int) ([]byte, error) {
+	return e.PermitTypedDataContext(context.Background(), contract, owner, spender, value, deadline)
+}
+
+// PermitTypedDataContext returns the EIP-2612 permit of value from owner to spender in typed data json, which is signed
+// by SignTypedData or eth_signTypedData_v4 of the wallets, the nonce of owner is read from contract
+// deadline is the unix timestamp after which the permit expires
+func (e *EthClient) PermitTypedDataContext(ctx context.Context, contract, owner, spender string, value, deadline *big.Int) ([]byte, error) {
+	info, err := e.PermitInfoContext(ctx, contract, owner)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Supported {
+		return nil, fmt.Errorf("permit not supported by contract=%s", contract)
+	}
+	typedData := e.permitTypedData(contract, info, apitypes.TypedDataMessage{
+		"owner":    common.HexToAddress(owner).Hex(),
+		"spender":  common.HexToAddress(spender).Hex(),
+		"value":    value.String(),
+		"nonce":    info.Nonce.String(),
+		"deadline": deadline.String(),
+	})
+	return json.Marshal(typedData)
+}
+
+func (e *EthClient) permitTypedData(contract string, info *PermitInfo, message apitypes.TypedDataMessage) *apitypes.TypedData {
+	return &apitypes.TypedData{
+		Types:       apitypes.Types{"EIP712Domain": permitDomainTypes, "Permit": permitTypes},
+		PrimaryType: "Permit",
+		Domain:      e.TypedDataDomain(info.Name, info.Version, contract),
+		Message:     message,
+	}
+}
+
+// PermitData generates the data of calling permit of the token with the signature of the permit typed data
+func (e *EthClient) PermitData(owner, spender string, value, deadline *big.Int, signature []byte) ([]byte, error) {
+	sig, err := normalizeSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	var r, s [32]byte
+	copy(r[:], sig[:32])
+	copy(s[:], sig[32:64])
+	return e.GetTransactionDataByABI("permit", permitABIName, common.HexToAddress(owner), common.HexToAddress(spender),
+		value, deadline, sig[ecrypto.RecoveryIDOffset], r, s)
+}
+
+// Permit2TypedData returns the Permit2 PermitTransferFrom of transfer in typed data json, the token must be approved to
+// Permit2 by the owner once
+func (e *EthClient) Permit2TypedData(transfer *Permit2Transfer) ([]byte, error) {
+	typedData := &apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain":       permit2DomainTypes,
+			"PermitTransferFrom": permitTransferFromTypes,
+			"TokenPermissions":   tokenPermissionsTypes,
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(e.chainID)),
+			VerifyingContract: Permit2Address,
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": map[string]interface{}{
+				"token":  common.HexToAddress(transfer.Token).Hex(),
+				"amount": transfer.Amount.String(),
+			},
+			"spender":  common.HexToAddress(transfer.Spender).Hex(),
+			"nonce":    transfer.Nonce.String(),
+			"deadline": transfer.Deadline.String(),
+		},
+	}
+	return json.Marshal(typedData)
+}
+
+// Permit2TransferFromData generates the data of calling permitTransferFrom of Permit2, which transfers
+// requestedAmount of the token from owner to to with the signature of the Permit2 typed data
+func (e *EthClient) Permit2TransferFromData(transfer *Permit2Transfer, owner, to string, requestedAmount *big.Int,
+	signature []byte) ([]byte, error) {
+	sig, err := normalizeSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	type tokenPermissions struct {
+		Token  common.Address
+		Amount *big.Int
+	}
+	permit := struct {
+		Permitted tokenPermissions
+		Nonce     *big.Int
+		Deadline  *big.Int
+	}{tokenPermissions{common.HexToAddress(transfer.Token), transfer.Amount}, transfer.Nonce, transfer.Deadline}
+	details := struct {
+		To              common.Address
+		RequestedAmount *big.Int
+	}{common.HexToAddress(to), requestedAmount}
+	return e.GetTransactionDataByABI("permitTransferFrom", permit2ABIName, permit, details, common.HexToAddress(owner), sig)
+}
+
+// normalizeSignature returns the copy of the 65 bytes signature with V of 27 or 28, which is expected by ecrecover
+func normalizeSignature(signature []byte) ([]byte, error) {
+	if len(signature) != ecrypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length, expect=%d, got=%d", ecrypto.SignatureLength, len(signature))
+	}
+	sig := make([]byte, ecrypto.SignatureLength)
+	copy(sig, signature)
+	if sig[ecrypto.RecoveryIDOffset] < 27 {
+		sig[ecrypto.RecoveryIDOffset] += 27
+	}
+	return sig, nil
+}
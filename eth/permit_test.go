@@ -0,0 +1,157 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// encodeWords concatenates the 32 bytes words of the values, like abi.encode of the static types
+func encodeWords(values ...interface{}) []byte {
+	var result []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case []byte:
+			result = append(result, common.LeftPadBytes(v, 32)...)
+		case common.Address:
+			result = append(result, common.LeftPadBytes(v.Bytes(), 32)...)
+		case *big.Int:
+			result = append(result, common.LeftPadBytes(v.Bytes(), 32)...)
+		}
+	}
+	return result
+}
+
+// usdcSeparator is the domain separator of the permit token at batchToken on chain 1, named "USD Coin" of version 2
+func usdcSeparator() []byte {
+	return crypto.Keccak256(encodeWords(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("USD Coin")), crypto.Keccak256([]byte("2")), big.NewInt(1), common.HexToAddress(batchToken)))
+}
+
+// permitHandler answers the permit reads of batchToken, the other contracts have no permit
+func permitHandler(t *testing.T) rpcHandler {
+	parsed, _ := abi.JSON(strings.NewReader(permitAbi))
+	return func(method string, params []json.RawMessage) (interface{}, error) {
+		var call struct {
+			To   common.Address `json:"to"`
+			Data hexutil.Bytes  `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(params[0], &call), "decode call failed")
+		if call.To != common.HexToAddress(batchToken) {
+			return nil, &rpcError{code: 3, message: "execution reverted"}
+		}
+		read, _ := parsed.MethodById(call.Data[:4])
+		var output []byte
+		switch read.Name {
+		case "nonces":
+			output, _ = read.Outputs.Pack(big.NewInt(5))
+		case "DOMAIN_SEPARATOR":
+			var separator [32]byte
+			copy(separator[:], usdcSeparator())
+			output, _ = read.Outputs.Pack(separator)
+		case "name":
+			output, _ = read.Outputs.Pack("USD Coin")
+		case "version":
+			output, _ = read.Outputs.Pack("2")
+		}
+		return hexutil.Encode(output), nil
+	}
+}
+
+func TestPermit(t *testing.T) {
+	server := newRPCServer(t, permitHandler(t))
+	defer server.Close()
+	c, err := NewEthClient(&client.ChainConfiguration{Endpoints: []string{server.URL}, ChainID: big.NewInt(1)})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	spender, value, deadline := common.HexToAddress(batchReverted), big.NewInt(1e6), big.NewInt(1700000000)
+
+	info, err := c.PermitInfo(batchToken, owner.Hex())
+	assert.Nil(t, err, "read permit info failed")
+	assert.True(t, info.Supported, "permit should be supported")
+	assert.Equal(t, "USD Coin", info.Name)
+	assert.Equal(t, "2", info.Version)
+	assert.Equal(t, big.NewInt(5), info.Nonce)
+	info, err = c.PermitInfo(batchReverted, owner.Hex())
+	assert.Nil(t, err, "read permit info failed")
+	assert.False(t, info.Supported, "permit should not be supported")
+
+	data, err := c.PermitTypedData(batchToken, owner.Hex(), spender.Hex(), value, deadline)
+	assert.Nil(t, err, "build permit failed")
+	hash, err := c.HashTypedData(data)
+	assert.Nil(t, err, "hash permit failed")
+	permitHash := crypto.Keccak256(encodeWords(
+		crypto.Keccak256([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)")),
+		owner, spender, value, big.NewInt(5), deadline))
+	assert.Equal(t, crypto.Keccak256(append(append([]byte{0x19, 0x01}, usdcSeparator()...), permitHash...)), hash)
+	_, err = c.PermitTypedData(batchReverted, owner.Hex(), spender.Hex(), value, deadline)
+	assert.NotNil(t, err, "permit not supported should fail")
+
+	signature, err := crypto.Sign(hash, key)
+	assert.Nil(t, err, "sign failed")
+	calldata, err := c.PermitData(owner.Hex(), spender.Hex(), value, deadline, signature)
+	assert.Nil(t, err, "pack permit failed")
+	parsed, _ := abi.JSON(strings.NewReader(permitAbi))
+	args, err := parsed.Methods["permit"].Inputs.Unpack(calldata[4:])
+	assert.Nil(t, err, "unpack permit failed")
+	assert.Equal(t, []interface{}{owner, spender, value, deadline}, args[:4])
+	assert.Equal(t, signature[64]+27, args[4], "v should be 27 or 28")
+	r := args[5].([32]byte)
+	assert.Equal(t, signature[:32], r[:])
+}
+
+func TestPermit2(t *testing.T) {
+	c := newMainnetClient(t)
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	transfer := &Permit2Transfer{
+		Token:    batchToken,
+		Amount:   big.NewInt(1e6),
+		Spender:  batchReverted,
+		Nonce:    big.NewInt(7),
+		Deadline: big.NewInt(1700000000),
+	}
+	data, err := c.Permit2TypedData(transfer)
+	assert.Nil(t, err, "build permit2 failed")
+	hash, err := c.HashTypedData(data)
+	assert.Nil(t, err, "hash permit2 failed")
+
+	separator := crypto.Keccak256(encodeWords(
+		crypto.Keccak256([]byte("EIP712Domain(string name,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("Permit2")), big.NewInt(1), common.HexToAddress(Permit2Address)))
+	permissions := crypto.Keccak256(encodeWords(crypto.Keccak256([]byte("TokenPermissions(address token,uint256 amount)")),
+		common.HexToAddress(batchToken), transfer.Amount))
+	permitHash := crypto.Keccak256(encodeWords(crypto.Keccak256([]byte(
+		"PermitTransferFrom(TokenPermissions permitted,address spender,uint256 nonce,uint256 deadline)TokenPermissions(address token,uint256 amount)")),
+		permissions, common.HexToAddress(batchReverted), transfer.Nonce, transfer.Deadline))
+	assert.Equal(t, crypto.Keccak256(append(append([]byte{0x19, 0x01}, separator...), permitHash...)), hash)
+
+	signature, err := crypto.Sign(hash, key)
+	assert.Nil(t, err, "sign failed")
+	calldata, err := c.Permit2TransferFromData(transfer, owner.Hex(), batchOwner, big.NewInt(5e5), signature)
+	assert.Nil(t, err, "pack permitTransferFrom failed")
+	parsed, _ := abi.JSON(strings.NewReader(permit2Abi))
+	method := parsed.Methods["permitTransferFrom"]
+	assert.Equal(t, method.ID, calldata[:4])
+	args, err := method.Inputs.Unpack(calldata[4:])
+	assert.Nil(t, err, "unpack failed")
+	assert.Equal(t, owner, args[2])
+	sig := args[3].([]byte)
+	assert.Equal(t, signature[:64], sig[:64])
+	assert.Equal(t, signature[64]+27, sig[64], "v should be 27 or 28")
+	signer, err := c.RecoverTypedData(data, sig)
+	assert.Nil(t, err, "recover failed")
+	assert.Equal(t, owner.Hex(), signer)
+}
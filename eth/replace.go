@@ -0,0 +1,135 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// minReplacementBump is the fee increase in percent required by geth's txpool to replace a pending transaction
+const minReplacementBump = 10
+
+// ReplaceTransaction generates the speed-up transaction of a pending transaction
+func (e *EthClient) ReplaceTransaction(hash string, bumpPercent int) ([]byte, []byte, error) {
+	return e.ReplaceTransactionContext(context.Background(), hash, bumpPercent)
+}
+
+// ReplaceTransactionContext generates the transaction replacing the pending transaction of hash, nothing is sent to the chain
+// it has the same nonce, recipient, value and data, the fees are raised by bumpPercent, at least 10%, and to the suggested fees
+// if they are higher, the transaction and its signing hash are returned like GetTransaction
+func (e *EthClient) ReplaceTransactionContext(ctx context.Context, hash string, bumpPercent int) ([]byte, []byte, error) {
+	tx, err := e.pendingTransaction(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.replace(ctx, tx, tx.To(), tx.Gas(), tx.Value(), tx.Data(), tx.AccessList(), bumpPercent)
+}
+
+// CancelTransaction generates the transaction cancelling a pending transaction
+func (e *EthClient) CancelTransaction(hash string) ([]byte, []byte, error) {
+	return e.CancelTransactionContext(context.Background(), hash)
+}
+
+// CancelTransactionContext generates the transaction cancelling the pending transaction of hash, nothing is sent to the chain
+// it's a zero value transfer from the sender to itself with the same nonce and the fees raised like ReplaceTransaction
+func (e *EthClient) CancelTransactionContext(ctx context.Context, hash string) ([]byte, []byte, error) {
+	tx, err := e.pendingTransaction(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender, err := types.Sender(types.NewLondonSigner(tx.ChainId()), tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get from failed, err=%w", err)
+	}
+	return e.replace(ctx, tx, &sender, params.TxGas, big.NewInt(0), nil, nil, minReplacementBump)
+}
+
+func (e *EthClient) pendingTransaction(ctx context.Context, hash string) (*types.Transaction, error) {
+	tx, isPending, err := e.client.TransactionByHash(ctx, common.HexToHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("get transaction failed, hash=%s, err=%w", hash, err)
+	}
+	if !isPending {
+		return nil, fmt.Errorf("transaction is not pending, hash=%s", hash)
+	}
+	return tx, nil
+}
+
+// replace generates the transaction with the nonce of tx and the fees of tx bumped, the type of tx is kept
+func (e *EthClient) replace(ctx context.Context, tx *types.Transaction, to *common.Address, gas uint64, value *big.Int,
+	data []byte, accessList types.AccessList, bumpPercent int) ([]byte, []byte, error) {
+	if bumpPercent < minReplacementBump {
+		bumpPercent = minReplacementBump
+	}
+	gasPrice, err := e.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get suggest gas price failed, err=%w", err)
+	}
+	var replacement *types.Transaction
+	switch tx.Type() {
+	case types.LegacyTxType:
+		replacement = types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: maxBig(bump(tx.GasPrice(), bumpPercent), gasPrice),
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	case types.AccessListTxType:
+		replacement = types.NewTx(&types.AccessListTx{
+			ChainID:    e.chainID,
+			Nonce:      tx.Nonce(),
+			GasPrice:   maxBig(bump(tx.GasPrice(), bumpPercent), gasPrice),
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	default:
+		tipCap, err := e.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get suggest gas tip failed, err=%w", err)
+		}
+		tipCap = maxBig(bump(tx.GasTipCap(), bumpPercent), tipCap)
+		replacement = types.NewTx(&types.DynamicFeeTx{
+			ChainID:    e.chainID,
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tipCap,
+			GasFeeCap:  maxBig(maxBig(bump(tx.GasFeeCap(), bumpPercent), gasPrice), tipCap),
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	}
+	message, err := replacement.MarshalBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode message failed, err=%w", err)
+	}
+	return message, types.NewLondonSigner(e.chainID).Hash(replacement).Bytes(), nil
+}
+
+// bump raises price by percent, rounded up so the increase is never lost
+func bump(price *big.Int, percent int) *big.Int {
+	result := new(big.Int).Mul(price, big.NewInt(int64(100+percent)))
+	result.Add(result, big.NewInt(99))
+	result.Div(result, big.NewInt(100))
+	if result.Cmp(price) <= 0 {
+		result.Add(price, big.NewInt(1))
+	}
+	return result
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,76 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// manualBackend is the simulated chain without committing a block for every transaction
+type manualBackend struct {
+	*backends.SimulatedBackend
+}
+
+func TestReplaceTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337), SupportEIP1559: true},
+		&manualBackend{sim})
+	assert.Nil(t, err, "create client failed")
+
+	to := common.HexToAddress(batchOwner)
+	signer := types.NewLondonSigner(big.NewInt(1337))
+	stuck, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     0,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e10),
+		Gas:       30000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+		Data:      []byte{1},
+	})
+	assert.Nil(t, err, "sign failed")
+	assert.Nil(t, sim.SendTransaction(context.Background(), stuck), "send failed")
+
+	message, hash, err := c.ReplaceTransaction(stuck.Hash().Hex(), 20)
+	assert.Nil(t, err, "replace failed")
+	replacement := &types.Transaction{}
+	assert.Nil(t, replacement.UnmarshalBinary(message), "decode failed")
+	assert.Equal(t, signer.Hash(replacement).Bytes(), hash)
+	assert.Equal(t, uint64(0), replacement.Nonce())
+	assert.Equal(t, &to, replacement.To())
+	assert.Equal(t, stuck.Value(), replacement.Value())
+	assert.Equal(t, stuck.Data(), replacement.Data())
+	assert.Equal(t, stuck.Gas(), replacement.Gas())
+	assert.Equal(t, big.NewInt(12e8), replacement.GasTipCap())
+	assert.Equal(t, big.NewInt(24e9), replacement.GasFeeCap())
+
+	message, _, err = c.CancelTransaction(stuck.Hash().Hex())
+	assert.Nil(t, err, "cancel failed")
+	cancel := &types.Transaction{}
+	assert.Nil(t, cancel.UnmarshalBinary(message), "decode failed")
+	assert.Equal(t, uint64(0), cancel.Nonce())
+	assert.Equal(t, &owner, cancel.To())
+	assert.Equal(t, int64(0), cancel.Value().Int64())
+	assert.Empty(t, cancel.Data())
+	assert.Equal(t, params.TxGas, cancel.Gas())
+	assert.Equal(t, big.NewInt(11e8), cancel.GasTipCap())
+	assert.Equal(t, big.NewInt(22e9), cancel.GasFeeCap())
+
+	sim.Commit()
+	_, _, err = c.ReplaceTransaction(stuck.Hash().Hex(), 20)
+	assert.NotNil(t, err, "mined transaction can't be replaced")
+}
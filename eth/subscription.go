@@ -0,0 +1,256 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	subscribeRetryInterval    = time.Second
+	maxSubscribeRetryInterval = 30 * time.Second
+	// subscriptionBuffer is the capacity of the channels receiving from the node
+	subscriptionBuffer = 128
+	// recentHeads is the number of blocks whose delivered hashes are kept to skip the duplicated headers
+	recentHeads = 128
+)
+
+// subscribeBackend is implemented by the backends supporting subscriptions,
+// such as the node pool on websocket endpoints and the simulated chain
+type subscribeBackend interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+}
+
+// SubscribeNewHead delivers the headers of the new blocks to ch
+func (e *EthClient) SubscribeNewHead(ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return e.SubscribeNewHeadContext(context.Background(), ch)
+}
+
+// SubscribeNewHeadContext delivers the headers of the new blocks to ch until ctx is done or it's unsubscribed
+// the subscription is renewed when the connection is lost, and the blocks missed meanwhile are delivered in order
+// it fails with rpc.ErrNotificationsUnsupported on http endpoints
+func (e *EthClient) SubscribeNewHeadContext(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	backend, ok := e.client.(subscribeBackend)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	latest, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get latest header failed, err=%w", err)
+	}
+	last := latest.Number.Uint64()
+	recent := make(map[uint64]common.Hash)
+	deliver := func(header *types.Header, quit <-chan struct{}) bool {
+		number := header.Number.Uint64()
+		if recent[number] == header.Hash() {
+			return true
+		}
+		select {
+		case ch <- header:
+		case <-quit:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+		recent[number] = header.Hash()
+		if number > recentHeads {
+			delete(recent, number-recentHeads)
+		}
+		last = number
+		return true
+	}
+
+	heads := make(chan *types.Header, subscriptionBuffer)
+	subscribe := func() (ethereum.Subscription, error) {
+		return backend.SubscribeNewHead(ctx, heads)
+	}
+	// the blocks produced while not subscribed
+	backfill := func(quit <-chan struct{}) error {
+		latest, err := e.client.HeaderByNumber(ctx, nil)
+		for number := last + 1; err == nil && number <= latest.Number.Uint64(); number++ {
+			header := latest
+			if number < latest.Number.Uint64() {
+				header, err = e.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+			}
+			if err == nil && !deliver(header, quit) {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("backfill headers from block=%d failed, err=%w", last+1, err)
+		}
+		return nil
+	}
+	forward := func(sub ethereum.Subscription, quit <-chan struct{}) error {
+		for {
+			select {
+			case header := <-heads:
+				deliver(header, quit)
+			case err := <-sub.Err():
+				return fmt.Errorf("new head subscription failed, err=%w", err)
+			case <-quit:
+				return nil
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return resubscribe(ctx, subscribe, backfill, forward)
+}
+
+// SubscribeFilterLogs delivers the new logs matching filter to ch
+func (e *EthClient) SubscribeFilterLogs(filter LogFilter, ch chan<- *client.EventLog) (ethereum.Subscription, error) {
+	return e.SubscribeFilterLogsContext(context.Background(), filter, ch)
+}
+
+// SubscribeFilterLogsContext delivers the new logs matching filter to ch until ctx is done or it's unsubscribed
+// the logs from filter.FromBlock are delivered first if it's set, filter.ToBlock is ignored
+// the subscription is renewed when the connection is lost, and the logs missed meanwhile are queried by FilterLogs,
+// so the logs are delivered without gaps or duplicates, the logs removed by reorganizations are delivered with Removed set
+// it fails with rpc.ErrNotificationsUnsupported on http endpoints
+func (e *EthClient) SubscribeFilterLogsContext(ctx context.Context, filter LogFilter, ch chan<- *client.EventLog) (ethereum.Subscription, error) {
+	backend, ok := e.client.(subscribeBackend)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	query := ethereum.FilterQuery{Topics: filter.Topics}
+	for _, addr := range filter.Addresses {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid address=%s", addr)
+		}
+		query.Addresses = append(query.Addresses, common.HexToAddress(addr))
+	}
+	// the logs before (block, index) are delivered already
+	var block uint64
+	var index uint
+	if filter.FromBlock != nil {
+		block = filter.FromBlock.Uint64()
+	} else {
+		latest, err := e.GetLatestBlockNumberContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get latest block failed, err=%w", err)
+		}
+		block = latest.Uint64() + 1
+	}
+	deliver := func(eventLog *client.EventLog, quit <-chan struct{}) bool {
+		delivered := eventLog.BlockNumber < block || (eventLog.BlockNumber == block && eventLog.LogIndex < index)
+		if !eventLog.Removed && delivered {
+			return true
+		}
+		select {
+		case ch <- eventLog:
+		case <-quit:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+		if eventLog.Removed {
+			// the logs at the same position on the new fork are delivered again
+			if delivered {
+				block, index = eventLog.BlockNumber, eventLog.LogIndex
+			}
+		} else {
+			block, index = eventLog.BlockNumber, eventLog.LogIndex+1
+		}
+		return true
+	}
+
+	logs := make(chan types.Log, subscriptionBuffer)
+	subscribe := func() (ethereum.Subscription, error) {
+		return backend.SubscribeFilterLogs(ctx, query, logs)
+	}
+	// the logs from filter.FromBlock or emitted while not subscribed
+	backfill := func(quit <-chan struct{}) error {
+		latest, err := e.GetLatestBlockNumberContext(ctx)
+		if err != nil {
+			return fmt.Errorf("get latest block failed, err=%w", err)
+		}
+		if block <= latest.Uint64() {
+			from := block
+			missed, err := e.FilterLogsContext(ctx, LogFilter{
+				FromBlock: new(big.Int).SetUint64(from),
+				ToBlock:   latest,
+				Addresses: filter.Addresses,
+				Topics:    filter.Topics,
+			})
+			for i := 0; err == nil && i < len(missed); i++ {
+				if !deliver(missed[i], quit) {
+					break
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("backfill logs from block=%d failed, err=%w", from, err)
+			}
+			if block <= latest.Uint64() {
+				block, index = latest.Uint64()+1, 0
+			}
+		}
+		return nil
+	}
+	forward := func(sub ethereum.Subscription, quit <-chan struct{}) error {
+		for {
+			select {
+			case received := <-logs:
+				deliver(toEventLog(&received), quit)
+			case err := <-sub.Err():
+				return fmt.Errorf("log subscription failed, err=%w", err)
+			case <-quit:
+				return nil
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return resubscribe(ctx, subscribe, backfill, forward)
+}
+
+// resubscribe creates the subscription by subscribe, and keeps it alive until ctx is done or it's unsubscribed
+// backfill delivers the values missed while not subscribed, then forward passes the received values until
+// the subscription fails, then subscribe is retried with a growing interval
+// backfill runs in the goroutine of the subscription, so the caller isn't blocked by a channel it reads
+// only after the subscription is returned
+func resubscribe(ctx context.Context, subscribe func() (ethereum.Subscription, error), backfill func(quit <-chan struct{}) error,
+	forward func(sub ethereum.Subscription, quit <-chan struct{}) error) (ethereum.Subscription, error) {
+	sub, err := subscribe()
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		for {
+			err := backfill(quit)
+			if err == nil {
+				err = forward(sub, quit)
+			}
+			sub.Unsubscribe()
+			if err == nil {
+				return ctx.Err()
+			}
+			log.Printf("%s, resubscribing\n", err)
+			interval := subscribeRetryInterval
+			for sub, err = subscribe(); err != nil; sub, err = subscribe() {
+				log.Printf("resubscribe failed, err=%s\n", err)
+				select {
+				case <-quit:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(interval):
+				}
+				if interval *= 2; interval > maxSubscribeRetryInterval {
+					interval = maxSubscribeRetryInterval
+				}
+			}
+		}
+	}), nil
+}
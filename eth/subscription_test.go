@@ -0,0 +1,178 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// flakyBackend is the simulated chain whose subscriptions can be broken like a lost websocket connection
+type flakyBackend struct {
+	*backends.SimulatedBackend
+
+	mu   sync.Mutex
+	down bool
+	subs []*flakySubscription
+}
+
+type flakySubscription struct {
+	ethereum.Subscription
+	err chan error
+}
+
+func (s *flakySubscription) Err() <-chan error { return s.err }
+
+func (b *flakyBackend) track(sub ethereum.Subscription, err error) (ethereum.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if b.down {
+		sub.Unsubscribe()
+		return nil, errors.New("connection refused")
+	}
+	s := &flakySubscription{Subscription: sub, err: make(chan error, 1)}
+	b.subs = append(b.subs, s)
+	return s, nil
+}
+
+func (b *flakyBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return b.track(b.SimulatedBackend.SubscribeNewHead(ctx, ch))
+}
+
+func (b *flakyBackend) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return b.track(b.SimulatedBackend.SubscribeFilterLogs(ctx, q, ch))
+}
+
+// setDown breaks the subscriptions and rejects the new ones while down
+func (b *flakyBackend) setDown(down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down = down
+	if !down {
+		return
+	}
+	for _, s := range b.subs {
+		s.Unsubscribe()
+		s.err <- errors.New("connection reset")
+	}
+	b.subs = nil
+}
+
+func TestSubscribe(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	parsed, _ := abi.JSON(strings.NewReader(strABI))
+	token, _, contract, err := bind.DeployContract(opts, parsed, common.FromHex(strBIN), sim)
+	assert.Nil(t, err, "deploy failed")
+	sim.Commit()
+
+	backend := &flakyBackend{SimulatedBackend: sim}
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, backend)
+	assert.Nil(t, err, "create client failed")
+	heads := make(chan *types.Header, 16)
+	headSub, err := c.SubscribeNewHead(heads)
+	assert.Nil(t, err, "subscribe new head failed")
+	defer headSub.Unsubscribe()
+	logs := make(chan *client.EventLog, 16)
+	logSub, err := c.SubscribeFilterLogs(LogFilter{Addresses: []string{token.Hex()}}, logs)
+	assert.Nil(t, err, "subscribe logs failed")
+	defer logSub.Unsubscribe()
+
+	mint := func(amount int64) {
+		_, err := contract.Transact(opts, "mint", big.NewInt(amount))
+		assert.Nil(t, err, "mint failed")
+		sim.Commit()
+	}
+	expect := func(block uint64) {
+		select {
+		case header := <-heads:
+			assert.Equal(t, block, header.Number.Uint64(), "header not match")
+		case <-time.After(3 * time.Second):
+			t.Fatalf("header=%d not received", block)
+		}
+		select {
+		case log := <-logs:
+			assert.Equal(t, block, log.BlockNumber, "log not match")
+		case <-time.After(3 * time.Second):
+			t.Fatalf("log of block=%d not received", block)
+		}
+	}
+
+	mint(1)
+	expect(2)
+	// the blocks 3 and 4 are produced while disconnected
+	backend.setDown(true)
+	mint(2)
+	mint(3)
+	backend.setDown(false)
+	expect(3)
+	expect(4)
+	mint(4)
+	expect(5)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, heads, 0, "duplicated headers")
+	assert.Len(t, logs, 0, "duplicated logs")
+}
+
+func TestSubscribeFromBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{owner: {Balance: big.NewInt(1e18)}}, simulatedGasLimit)
+	defer sim.Close()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	parsed, _ := abi.JSON(strings.NewReader(strABI))
+	token, _, contract, err := bind.DeployContract(opts, parsed, common.FromHex(strBIN), sim)
+	assert.Nil(t, err, "deploy failed")
+	sim.Commit()
+	for i := int64(1); i <= 2; i++ {
+		_, err := contract.Transact(opts, "mint", big.NewInt(i))
+		assert.Nil(t, err, "mint failed")
+		sim.Commit()
+	}
+
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1337)}, sim)
+	assert.Nil(t, err, "create client failed")
+	// the historical logs are delivered to the unbuffered channel after the subscription is returned
+	logs := make(chan *client.EventLog)
+	subscribed := make(chan ethereum.Subscription)
+	go func() {
+		sub, err := c.SubscribeFilterLogs(LogFilter{FromBlock: big.NewInt(1), Addresses: []string{token.Hex()}}, logs)
+		assert.Nil(t, err, "subscribe logs failed")
+		subscribed <- sub
+	}()
+	select {
+	case sub := <-subscribed:
+		defer sub.Unsubscribe()
+	case <-time.After(3 * time.Second):
+		t.Fatal("subscribe blocked by the backfill")
+	}
+	for _, block := range []uint64{2, 3} {
+		select {
+		case log := <-logs:
+			assert.Equal(t, block, log.BlockNumber, "log not match")
+		case <-time.After(3 * time.Second):
+			t.Fatalf("log of block=%d not received", block)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// ErrTraceUnsupported is returned by TraceTransaction and TraceCall when the node has no debug api or no callTracer
+var ErrTraceUnsupported = errors.New("trace not supported")
+
+// callTracerConfig selects the call tracer of geth, it's supported by geth, erigon and most providers with debug api
+var callTracerConfig = map[string]interface{}{"tracer": "callTracer"}
+
+// rawBackend is implemented by the backends able to call any json-rpc method, such as the node pool
+type rawBackend interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// CallFrame is a call in the call tree of a transaction
+// Type is CALL, STATICCALL, DELEGATECALL, CALLCODE, CREATE, CREATE2 or SELFDESTRUCT
+// Error is set when the call failed, Revert is the decoded revert data of a reverted call
+// Method and Args are decoded from Input when the selector matches a method of the registered abis
+type CallFrame struct {
+	Type    string
+	From    string
+	To      string
+	Value   *big.Int
+	Gas     uint64
+	GasUsed uint64
+	Input   []byte
+	Output  []byte
+	Error   string
+	Revert  *client.RevertError
+	Method  string
+	Args    []interface{}
+	Calls   []*CallFrame
+}
+
+// callFrame is the result of callTracer
+type callFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to"`
+	Value        *hexutil.Big    `json:"value"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output"`
+	Error        string          `json:"error"`
+	RevertReason string          `json:"revertReason"`
+	Calls        []*callFrame    `json:"calls"`
+}
+
+// TraceTransaction returns the call tree of the transaction
+func (e *EthClient) TraceTransaction(hash string) (*CallFrame, error) {
+	return e.TraceTransactionContext(context.Background(), hash)
+}
+
+// TraceTransactionContext returns the call tree of the transaction of hash by debug_traceTransaction
+// ErrTraceUnsupported is returned if the node doesn't support it
+func (e *EthClient) TraceTransactionContext(ctx context.Context, hash string) (*CallFrame, error) {
+	result, err := e.trace(ctx, "debug_traceTransaction", common.HexToHash(hash), callTracerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("trace transaction failed, hash=%s, err=%w", hash, err)
+	}
+	return result, nil
+}
+
+// TraceCall returns the call tree of td executed on the latest block
+func (e *EthClient) TraceCall(td *client.Transaction) (*CallFrame, error) {
+	return e.TraceCallContext(context.Background(), td)
+}
+
+// TraceCallContext returns the call tree of td executed on the latest block by debug_traceCall, nothing is sent to the chain
+// ErrTraceUnsupported is returned if the node doesn't support it
+func (e *EthClient) TraceCallContext(ctx context.Context, td *client.Transaction) (*CallFrame, error) {
+	result, err := e.trace(ctx, "debug_traceCall", callArg(td), "latest", callTracerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("trace call failed, err=%w", err)
+	}
+	return result, nil
+}
+
+// callArg is the json-rpc call object of td
+func callArg(td *client.Transaction) map[string]interface{} {
+	arg := map[string]interface{}{"from": common.HexToAddress(td.From), "data": hexutil.Bytes(td.Data)}
+	if td.To != "" {
+		arg["to"] = common.HexToAddress(td.To)
+	}
+	if td.Amount != nil {
+		arg["value"] = (*hexutil.Big)(td.Amount)
+	}
+	if td.Fee != nil && td.Fee.Gas != nil {
+		arg["gas"] = hexutil.Uint64(td.Fee.Gas.Uint64())
+	}
+	if len(td.AccessList) > 0 {
+		arg["accessList"] = toAccessList(td.AccessList)
+	}
+	return arg
+}
+
+func (e *EthClient) trace(ctx context.Context, method string, args ...interface{}) (*CallFrame, error) {
+	b, ok := e.client.(rawBackend)
+	if !ok {
+		return nil, ErrTraceUnsupported
+	}
+	var result *callFrame
+	if err := b.CallContext(ctx, &result, method, args...); err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFound {
+			return nil, ErrTraceUnsupported
+		}
+		return nil, err
+	}
+	if result == nil {
+		return nil, client.ErrNotFound
+	}
+	return e.decodeFrame(result), nil
+}
+
+// decodeFrame converts the frame of callTracer and decodes the inputs and reverts of the calls
+func (e *EthClient) decodeFrame(f *callFrame) *CallFrame {
+	frame := &CallFrame{
+		Type:    f.Type,
+		From:    f.From.Hex(),
+		Gas:     uint64(f.Gas),
+		GasUsed: uint64(f.GasUsed),
+		Input:   f.Input,
+		Output:  f.Output,
+		Error:   f.Error,
+		Value:   big.NewInt(0),
+	}
+	if f.To != nil {
+		frame.To = f.To.Hex()
+	}
+	if f.Value != nil {
+		frame.Value = f.Value.ToInt()
+	}
+	if f.Error != "" && (len(f.Output) > 0 || f.RevertReason != "") {
+		frame.Revert = revertFromData(f.Output)
+		_ = e.decodeRevert(frame.Revert)
+		if frame.Revert.Reason == "" {
+			frame.Revert.Reason = f.RevertReason
+		}
+	}
+	frame.Method, frame.Args = e.decodeInput(f.Input)
+	for _, call := range f.Calls {
+		frame.Calls = append(frame.Calls, e.decodeFrame(call))
+	}
+	return frame
+}
+
+// decodeInput finds the method of input in the registered abis, the args are nil if they can't be unpacked
+func (e *EthClient) decodeInput(input []byte) (string, []interface{}) {
+	if len(input) < 4 {
+		return "", nil
+	}
+	var name string
+	var args []interface{}
+	e.abiMap.Range(func(_, value interface{}) bool {
+		parsed, ok := value.(*abi.ABI)
+		if !ok {
+			return true
+		}
+		method, err := parsed.MethodById(input[:4])
+		if err != nil {
+			return true
+		}
+		name = method.Name
+		args, _ = method.Inputs.Unpack(input[4:])
+		return false
+	})
+	return name, args
+}
@@ -0,0 +1,78 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// revertNotAllowed is the revert data of Error("not allowed")
+const revertNotAllowed = "0x08c379a00000000000000000000000000000000000000000000000000000000000000020" +
+	"000000000000000000000000000000000000000000000000000000000000000b6e6f7420616c6c6f776564000000000000000000000000000000000000000000"
+
+// traceHandler answers debug_traceTransaction with a call to batchToken whose transfer reverted,
+// debug_traceCall is not supported
+func traceHandler(transfer []byte) rpcHandler {
+	return func(method string, params []json.RawMessage) (interface{}, error) {
+		if method != "debug_traceTransaction" {
+			return nil, &rpcError{code: -32601, message: "the method " + method + " does not exist/is not available"}
+		}
+		return map[string]interface{}{
+			"type": "CALL", "from": batchOwner, "to": batchReverted, "value": "0xde0b6b3a7640000",
+			"gas": "0x30d40", "gasUsed": "0x9c40", "input": "0x12345678", "output": revertNotAllowed,
+			"error": "execution reverted", "revertReason": "not allowed",
+			"calls": []interface{}{map[string]interface{}{
+				"type": "CALL", "from": batchReverted, "to": batchToken, "gas": "0x1d4c0", "gasUsed": "0x5208",
+				"input": hexutil.Encode(transfer), "output": revertNotAllowed, "error": "execution reverted",
+			}},
+		}, nil
+	}
+}
+
+func TestTraceTransaction(t *testing.T) {
+	c, err := NewSimulatedEthClient(core.GenesisAlloc{})
+	assert.Nil(t, err, "create client failed")
+	transfer, err := c.TransferData(batchOwner, big.NewInt(40))
+	assert.Nil(t, err, "pack transfer failed")
+
+	server := newRPCServer(t, traceHandler(transfer))
+	defer server.Close()
+	c, err = NewEthClient(&client.ChainConfiguration{Endpoints: []string{server.URL}, ChainID: big.NewInt(1)})
+	assert.Nil(t, err, "create client failed")
+	defer c.Close()
+
+	frame, err := c.TraceTransaction(common.Hash{1}.Hex())
+	assert.Nil(t, err, "trace transaction failed")
+	assert.Equal(t, "CALL", frame.Type)
+	assert.Equal(t, common.HexToAddress(batchReverted).Hex(), frame.To)
+	assert.Equal(t, big.NewInt(1e18), frame.Value)
+	assert.Equal(t, uint64(40000), frame.GasUsed)
+	assert.Equal(t, "", frame.Method, "unknown selector should not be decoded")
+	assert.Equal(t, "not allowed", frame.Revert.Reason)
+
+	assert.Len(t, frame.Calls, 1)
+	call := frame.Calls[0]
+	assert.Equal(t, common.HexToAddress(batchToken).Hex(), call.To)
+	assert.Equal(t, big.NewInt(0), call.Value)
+	assert.Equal(t, "transfer", call.Method)
+	assert.Equal(t, []interface{}{common.HexToAddress(batchOwner), big.NewInt(40)}, call.Args)
+	assert.Equal(t, "execution reverted", call.Error)
+	assert.Equal(t, "not allowed", call.Revert.Reason)
+
+	_, err = c.TraceCall(&client.Transaction{From: batchOwner, To: batchToken, Data: transfer})
+	assert.ErrorIs(t, err, ErrTraceUnsupported)
+}
+
+func TestTraceUnsupported(t *testing.T) {
+	c, err := NewSimulatedEthClient(core.GenesisAlloc{})
+	assert.Nil(t, err, "create client failed")
+	_, err = c.TraceTransaction(common.Hash{1}.Hex())
+	assert.ErrorIs(t, err, ErrTraceUnsupported)
+}
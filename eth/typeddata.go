@@ -0,0 +1,106 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// TypedDataDomain returns the EIP-712 domain of verifyingContract on the chain of the client
+func (e *EthClient) TypedDataDomain(name, version, verifyingContract string) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              name,
+		Version:           version,
+		ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(e.chainID)),
+		VerifyingContract: common.HexToAddress(verifyingContract).Hex(),
+	}
+}
+
+// HashTypedData returns the EIP-712 hash to sign of the typed data in json, the format of eth_signTypedData_v4:
+// {"types": {"EIP712Domain": [...], ...}, "primaryType": "...", "domain": {...}, "message": {...}}
+// the chainId of the domain is set to the chain of the client if it's declared but missing,
+// the typed data of other chains is rejected
+func (e *EthClient) HashTypedData(data []byte) ([]byte, error) {
+	typedData, err := e.parseTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		return nil, fmt.Errorf("hash typed data failed, err=%w", err)
+	}
+	return hash, nil
+}
+
+// SignTypedData signs the typed data in json by signer
+func (e *EthClient) SignTypedData(data []byte, signer client.Signer) ([]byte, error) {
+	return e.SignTypedDataContext(context.Background(), data, signer)
+}
+
+// SignTypedDataContext signs the typed data in json by signer, the signature is 65 bytes [R || S || V]
+// with V of 27 or 28 like eth_signTypedData_v4
+func (e *EthClient) SignTypedDataContext(ctx context.Context, data []byte, signer client.Signer) ([]byte, error) {
+	hash, err := e.HashTypedData(data)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signer.SignHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("sign typed data failed, err=%w", err)
+	}
+	signature[ecrypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// RecoverTypedData returns the address signing the typed data in json, V of signature is 0, 1, 27 or 28
+func (e *EthClient) RecoverTypedData(data, signature []byte) (string, error) {
+	hash, err := e.HashTypedData(data)
+	if err != nil {
+		return "", err
+	}
+	return recoverAddress(hash, signature)
+}
+
+// VerifyTypedData returns whether the typed data in json is signed by address
+func (e *EthClient) VerifyTypedData(data, signature []byte, address string) (bool, error) {
+	signer, err := e.RecoverTypedData(data, signature)
+	if err != nil {
+		return false, err
+	}
+	return common.HexToAddress(signer) == common.HexToAddress(address), nil
+}
+
+func (e *EthClient) parseTypedData(data []byte) (*apitypes.TypedData, error) {
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal(data, &typedData); err != nil {
+		return nil, fmt.Errorf("parse typed data failed, err=%w", err)
+	}
+	chainID := (*big.Int)(typedData.Domain.ChainId)
+	if chainID == nil {
+		for _, field := range typedData.Types["EIP712Domain"] {
+			if field.Name == "chainId" {
+				typedData.Domain.ChainId = (*math.HexOrDecimal256)(new(big.Int).Set(e.chainID))
+			}
+		}
+	} else if chainID.Cmp(e.chainID) != 0 {
+		return nil, fmt.Errorf("chainId=%s of typed data not match chain=%s", chainID, e.chainID)
+	}
+	return &typedData, nil
+}
+
+// recoverAddress returns the address signing hash, V of signature is 0, 1, 27 or 28
+func recoverAddress(hash, signature []byte) (string, error) {
+	signer, err := client.RecoverHash(hash, signature)
+	if err != nil {
+		return "", err
+	}
+	return signer.Hex(), nil
+}
@@ -0,0 +1,119 @@
+package eth
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// mailTypedData is the example of EIP-712
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [{"name": "name", "type": "string"}, {"name": "wallet", "type": "address"}],
+		"Mail": [{"name": "from", "type": "Person"}, {"name": "to", "type": "Person"}, {"name": "contents", "type": "string"}]
+	},
+	"primaryType": "Mail",
+	"domain": {"name": "Ether Mail", "version": "1", "chainId": 1, "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// mailArraysTypedData is the example of eth_signTypedData_v4 with the arrays of structs and addresses
+const mailArraysTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [{"name": "name", "type": "string"}, {"name": "wallets", "type": "address[]"}],
+		"Mail": [{"name": "from", "type": "Person"}, {"name": "to", "type": "Person[]"}, {"name": "contents", "type": "string"}],
+		"Group": [{"name": "name", "type": "string"}, {"name": "members", "type": "Person[]"}]
+	},
+	"primaryType": "Mail",
+	"domain": {"name": "Ether Mail", "version": "1", "chainId": 1, "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"},
+	"message": {
+		"from": {"name": "Cow", "wallets": ["0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "0xDeaDbeefdEAdbeefdEadbEEFdeadbeEFdEaDbeeF"]},
+		"to": [{"name": "Bob", "wallets": [
+			"0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB",
+			"0xB0BdaBea57B0BDABeA57b0bdABEA57b0BDabEa57",
+			"0xB0B0b0b0b0b0B000000000000000000000000000"
+		]}],
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func newMainnetClient(t *testing.T) *EthClient {
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{}, simulatedGasLimit)
+	t.Cleanup(func() { sim.Close() })
+	c, err := NewEthClientWithBackend(&client.ChainConfiguration{ChainID: big.NewInt(1)}, sim)
+	assert.Nil(t, err, "create client failed")
+	return c
+}
+
+func TestHashTypedData(t *testing.T) {
+	c := newMainnetClient(t)
+	cow := crypto.Keccak256([]byte("cow"))
+	key, _ := crypto.ToECDSA(cow)
+
+	hash, err := c.HashTypedData([]byte(mailTypedData))
+	assert.Nil(t, err, "hash typed data failed")
+	assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hexutil.Encode(hash))
+	signature, err := c.SignTypedData([]byte(mailTypedData), client.NewKeySigner(key))
+	assert.Nil(t, err, "sign typed data failed")
+	assert.Equal(t, "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d"+
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c", hexutil.Encode(signature))
+	signer, err := c.RecoverTypedData([]byte(mailTypedData), signature)
+	assert.Nil(t, err, "recover failed")
+	assert.Equal(t, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", signer)
+
+	hash, err = c.HashTypedData([]byte(mailArraysTypedData))
+	assert.Nil(t, err, "hash typed data failed")
+	assert.Equal(t, "0xa85c2e2b118698e88db68a8105b794a8cc7cec074e89ef991cb4f5f533819cc2", hexutil.Encode(hash))
+	signature, err = c.SignTypedData([]byte(mailArraysTypedData), client.NewKeySigner(key))
+	assert.Nil(t, err, "sign typed data failed")
+	assert.Equal(t, "0x65cbd956f2fae28a601bebc9b906cea0191744bd4c4247bcd27cd08f8eb6b71c"+
+		"78efdf7a31dc9abee78f492292721f362d296cf86b4538e07b51303b67f749061b", hexutil.Encode(signature))
+	ok, err := c.VerifyTypedData([]byte(mailArraysTypedData), signature, "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826")
+	assert.Nil(t, err, "verify failed")
+	assert.True(t, ok, "signature should match")
+	ok, err = c.VerifyTypedData([]byte(mailTypedData), signature, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	assert.Nil(t, err, "verify failed")
+	assert.False(t, ok, "signature of other data should not match")
+}
+
+func TestTypedDataDomain(t *testing.T) {
+	c := newMainnetClient(t)
+	domain := c.TypedDataDomain("Ether Mail", "1", "0xcccccccccccccccccccccccccccccccccccccccc")
+	assert.Equal(t, "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC", domain.VerifyingContract)
+	assert.Equal(t, big.NewInt(1), (*big.Int)(domain.ChainId))
+
+	// the missing chainId is filled by the client
+	hash, err := c.HashTypedData([]byte(strings.Replace(mailTypedData, `"chainId": 1, `, "", 1)))
+	assert.Nil(t, err, "hash typed data failed")
+	assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hexutil.Encode(hash))
+
+	// the typed data of other chains is rejected
+	_, err = c.HashTypedData([]byte(strings.Replace(mailTypedData, `"chainId": 1`, `"chainId": 56`, 1)))
+	assert.NotNil(t, err, "chainId not match should fail")
+	_, err = c.HashTypedData([]byte(strings.Replace(mailTypedData, `"type": "Person"}`, `"type": "Animal"}`, 1)))
+	assert.NotNil(t, err, "undefined type should fail")
+}
@@ -0,0 +1,304 @@
+// Package hdwallet derives the keys of BIP-44 paths from a BIP-39 mnemonic or seed, see
+// https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki and
+// https://github.com/bitcoin/bips/blob/master/bip-0044.mediawiki
+//
+// The public key of a derived key is turned into the address of a chain by AddressFromPublicKey of its client.
+// The extended public key (xpub) of an account derives the public keys of the addresses without any private key,
+// which is used by the watch-only services:
+//
+//	master, err := hdwallet.NewFromMnemonic(mnemonic, "")
+//	account, err := master.Derive(hdwallet.AccountPath(hdwallet.CoinTypeEVM, 0))
+//	xpub := account.Neuter().String()
+//	// on the watch-only service
+//	watch, err := hdwallet.ParseExtendedKey(xpub)
+//	key, err := watch.Derive("0/5")
+//	address, err := ethClient.AddressFromPublicKey(key.PublicKey())
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fbsobreira/gotron-sdk/pkg/common"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Coin types of BIP-44 registered in SLIP-44
+const (
+	CoinTypeEVM  uint32 = 60
+	CoinTypeTron uint32 = 195
+)
+
+// HardenedOffset is added to the index of a hardened child, written as 44' in the paths
+const HardenedOffset uint32 = 0x80000000
+
+var (
+	// versions of the serialized extended keys on mainnet
+	privateVersion = []byte{0x04, 0x88, 0xad, 0xe4}
+	publicVersion  = []byte{0x04, 0x88, 0xb2, 0x1e}
+
+	masterSecret = []byte("Bitcoin seed")
+)
+
+// Errors of deriving the keys
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrHardenedPublic  = errors.New("hardened child of public key")
+	ErrInvalidKey      = errors.New("invalid extended key")
+)
+
+// Key is an extended key of BIP-32, it's a private key or, after Neuter, a public key with the chain code
+type Key struct {
+	private   []byte // 32 bytes, nil for the public key
+	public    []byte // 33 bytes compressed
+	chainCode []byte
+	depth     uint8
+	parent    []byte // fingerprint of the parent key
+	index     uint32
+}
+
+// NewMnemonic generates a mnemonic of bits entropy, 128 bits for 12 words up to 256 bits for 24 words
+func NewMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", fmt.Errorf("generate entropy failed, err=%w", err)
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewFromMnemonic creates the master key of the english mnemonic and the optional passphrase
+func NewFromMnemonic(mnemonic, passphrase string) (*Key, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w, err=%s", ErrInvalidMnemonic, err)
+	}
+	return NewFromSeed(seed)
+}
+
+// NewFromSeed creates the master key of seed, which is 16 to 64 bytes
+func NewFromSeed(seed []byte) (*Key, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length=%d", len(seed))
+	}
+	mac := hmac.New(sha512.New, masterSecret)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	if !validScalar(sum[:32]) {
+		return nil, ErrInvalidKey
+	}
+	return newPrivate(sum[:32], sum[32:], 0, make([]byte, 4), 0), nil
+}
+
+// ParseExtendedKey parses the serialized xprv or xpub
+func ParseExtendedKey(key string) (*Key, error) {
+	data, err := common.Decode(key)
+	if err != nil || len(data) != 82 {
+		return nil, ErrInvalidKey
+	}
+	payload, checksum := data[:78], data[78:]
+	if !bytes.Equal(doubleSha256(payload)[:4], checksum) {
+		return nil, fmt.Errorf("%w, checksum not match", ErrInvalidKey)
+	}
+	version, keyData := payload[:4], payload[45:]
+	depth, parent, index, chainCode := payload[4], payload[5:9], binary.BigEndian.Uint32(payload[9:13]), payload[13:45]
+	switch {
+	case bytes.Equal(version, privateVersion) && keyData[0] == 0 && validScalar(keyData[1:]):
+		return newPrivate(keyData[1:], chainCode, depth, parent, index), nil
+	case bytes.Equal(version, publicVersion):
+		if _, err := crypto.DecompressPubkey(keyData); err != nil {
+			return nil, fmt.Errorf("%w, err=%s", ErrInvalidKey, err)
+		}
+		return &Key{public: keyData, chainCode: chainCode, depth: depth, parent: parent, index: index}, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// Path returns the BIP-44 path of the address at index of the account, m/44'/coinType'/account'/0/index
+func Path(coinType, account, index uint32) string {
+	return fmt.Sprintf("%s/0/%d", AccountPath(coinType, account), index)
+}
+
+// AccountPath returns the BIP-44 path of the account, m/44'/coinType'/account', its xpub derives the addresses
+func AccountPath(coinType, account uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'", coinType, account)
+}
+
+func newPrivate(private, chainCode []byte, depth uint8, parent []byte, index uint32) *Key {
+	key, _ := crypto.ToECDSA(private)
+	return &Key{
+		private:   private,
+		public:    crypto.CompressPubkey(&key.PublicKey),
+		chainCode: chainCode,
+		depth:     depth,
+		parent:    parent,
+		index:     index,
+	}
+}
+
+// Derive derives the key of path, which is absolute like m/44'/60'/0'/0/0 from the master key,
+// or relative to k like 0/5, the hardened index is marked by ' or h
+func (k *Key) Derive(path string) (*Key, error) {
+	path = strings.TrimSpace(path)
+	if path == "m" {
+		return k, nil
+	}
+	if strings.HasPrefix(path, "m/") {
+		if k.depth != 0 {
+			return nil, fmt.Errorf("absolute path=%s from the key of depth=%d", path, k.depth)
+		}
+		path = path[2:]
+	}
+	key := k
+	for _, field := range strings.Split(path, "/") {
+		offset := uint32(0)
+		if strings.HasSuffix(field, "'") || strings.HasSuffix(field, "h") {
+			field, offset = field[:len(field)-1], HardenedOffset
+		}
+		index, err := strconv.ParseUint(field, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, fmt.Errorf("invalid path=%s", path)
+		}
+		if key, err = key.Child(uint32(index) + offset); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Child derives the child key at index, the hardened children, index >= HardenedOffset, need the private key
+func (k *Key) Child(index uint32) (*Key, error) {
+	if k.depth == 255 {
+		return nil, fmt.Errorf("depth of key exceeds")
+	}
+	data := make([]byte, 0, 37)
+	if index >= HardenedOffset {
+		if k.private == nil {
+			return nil, ErrHardenedPublic
+		}
+		data = append(append(data, 0), k.private...)
+	} else {
+		data = append(data, k.public...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	il, chainCode := sum[:32], sum[32:]
+	// the index is skipped by the caller when il is out of range, which happens with the probability lower than 1 in 2^127
+	if !validScalar(il) {
+		return nil, fmt.Errorf("%w, index=%d", ErrInvalidKey, index)
+	}
+
+	if k.private != nil {
+		child := new(big.Int).Add(new(big.Int).SetBytes(il), new(big.Int).SetBytes(k.private))
+		child.Mod(child, crypto.S256().Params().N)
+		if child.Sign() == 0 {
+			return nil, fmt.Errorf("%w, index=%d", ErrInvalidKey, index)
+		}
+		return newPrivate(leftPad32(child.Bytes()), chainCode, k.depth+1, k.fingerprint(), index), nil
+	}
+
+	parent, err := crypto.DecompressPubkey(k.public)
+	if err != nil {
+		return nil, fmt.Errorf("%w, err=%s", ErrInvalidKey, err)
+	}
+	curve := crypto.S256()
+	x, y := curve.ScalarBaseMult(il)
+	x, y = curve.Add(x, y, parent.X, parent.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, fmt.Errorf("%w, index=%d", ErrInvalidKey, index)
+	}
+	public := crypto.CompressPubkey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+	return &Key{public: public, chainCode: chainCode, depth: k.depth + 1, parent: k.fingerprint(), index: index}, nil
+}
+
+// Neuter returns the public key of k, which derives the non-hardened children only
+func (k *Key) Neuter() *Key {
+	return &Key{public: k.public, chainCode: k.chainCode, depth: k.depth, parent: k.parent, index: k.index}
+}
+
+// IsPrivate returns whether k has the private key
+func (k *Key) IsPrivate() bool {
+	return k.private != nil
+}
+
+// Depth returns the number of derivations from the master key
+func (k *Key) Depth() uint8 {
+	return k.depth
+}
+
+// Index returns the index of k in its parent, the hardened index includes HardenedOffset
+func (k *Key) Index() uint32 {
+	return k.index
+}
+
+// PrivateKey returns the ecdsa private key, it fails for the public key
+func (k *Key) PrivateKey() (*ecdsa.PrivateKey, error) {
+	if k.private == nil {
+		return nil, fmt.Errorf("no private key in public key")
+	}
+	return crypto.ToECDSA(k.private)
+}
+
+// PublicKey returns the ecdsa public key, which is passed to AddressFromPublicKey of the clients
+func (k *Key) PublicKey() *ecdsa.PublicKey {
+	key, _ := crypto.DecompressPubkey(k.public)
+	return key
+}
+
+// String returns the serialized xprv of the private key or xpub of the public key
+func (k *Key) String() string {
+	payload := make([]byte, 0, 82)
+	if k.private != nil {
+		payload = append(payload, privateVersion...)
+	} else {
+		payload = append(payload, publicVersion...)
+	}
+	payload = append(payload, k.depth)
+	payload = append(payload, k.parent...)
+	payload = binary.BigEndian.AppendUint32(payload, k.index)
+	payload = append(payload, k.chainCode...)
+	if k.private != nil {
+		payload = append(append(payload, 0), k.private...)
+	} else {
+		payload = append(payload, k.public...)
+	}
+	return common.Encode(append(payload, doubleSha256(payload)[:4]...))
+}
+
+// fingerprint is the first 4 bytes of hash160 of the public key, which identifies the parent of the children
+func (k *Key) fingerprint() []byte {
+	sha := sha256.Sum256(k.public)
+	h := ripemd160.New()
+	h.Write(sha[:])
+	return h.Sum(nil)[:4]
+}
+
+// validScalar returns whether b is a valid private key, 0 < b < N
+func validScalar(b []byte) bool {
+	v := new(big.Int).SetBytes(b)
+	return v.Sign() > 0 && v.Cmp(crypto.S256().Params().N) < 0
+}
+
+func leftPad32(b []byte) []byte {
+	result := make([]byte, 32)
+	copy(result[32-len(b):], b)
+	return result
+}
+
+func doubleSha256(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
@@ -0,0 +1,87 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/eth"
+	"git.bipal.space/shared-lib/blockchain/tron"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// TestVector1 checks the test vector 1 of BIP-32
+func TestVector1(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewFromSeed(seed)
+	assert.Nil(t, err, "create master failed")
+	assert.Equal(t, "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		master.Neuter().String())
+
+	child, err := master.Derive("m/0'")
+	assert.Nil(t, err, "derive failed")
+	assert.Equal(t, "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		child.String())
+	assert.Equal(t, "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		child.Neuter().String())
+
+	parsed, err := ParseExtendedKey(child.String())
+	assert.Nil(t, err, "parse xprv failed")
+	assert.Equal(t, child, parsed)
+	_, err = ParseExtendedKey(child.String()[:len(child.String())-1] + "1")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestDeriveAddress(t *testing.T) {
+	master, err := NewFromMnemonic(testMnemonic, "")
+	assert.Nil(t, err, "create master failed")
+
+	key, err := master.Derive(Path(CoinTypeEVM, 0, 0))
+	assert.Nil(t, err, "derive failed")
+	address, err := (&eth.EthClient{}).AddressFromPublicKey(key.PublicKey())
+	assert.Nil(t, err, "get address failed")
+	assert.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", address)
+	private, err := key.PrivateKey()
+	assert.Nil(t, err, "get private key failed")
+	assert.Equal(t, key.PublicKey(), &private.PublicKey)
+
+	key, err = master.Derive(Path(CoinTypeTron, 0, 0))
+	assert.Nil(t, err, "derive failed")
+	address, err = (&tron.TronClient{}).AddressFromPublicKey(key.PublicKey())
+	assert.Nil(t, err, "get address failed")
+	assert.Equal(t, "TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH", address)
+
+	_, err = NewFromMnemonic("abandon abandon abandon", "")
+	assert.ErrorIs(t, err, ErrInvalidMnemonic)
+	_, err = master.Derive("m/44'/x")
+	assert.NotNil(t, err, "invalid path should fail")
+}
+
+func TestWatchOnly(t *testing.T) {
+	mnemonic, err := NewMnemonic(128)
+	assert.Nil(t, err, "generate mnemonic failed")
+	master, err := NewFromMnemonic(mnemonic, "passphrase")
+	assert.Nil(t, err, "create master failed")
+	account, err := master.Derive(AccountPath(CoinTypeEVM, 3))
+	assert.Nil(t, err, "derive account failed")
+
+	watch, err := ParseExtendedKey(account.Neuter().String())
+	assert.Nil(t, err, "parse xpub failed")
+	assert.False(t, watch.IsPrivate())
+	for _, index := range []uint32{0, 1, 1000} {
+		expected, err := master.Derive(Path(CoinTypeEVM, 3, index))
+		assert.Nil(t, err, "derive private failed")
+		key, err := watch.Derive(fmt.Sprintf("0/%d", index))
+		assert.Nil(t, err, "derive public failed")
+		assert.Equal(t, expected.PublicKey(), key.PublicKey())
+		assert.Equal(t, expected.Neuter().String(), key.String())
+	}
+
+	_, err = watch.Derive("0'")
+	assert.ErrorIs(t, err, ErrHardenedPublic)
+	_, err = watch.PrivateKey()
+	assert.NotNil(t, err, "public key has no private key")
+}
@@ -0,0 +1,225 @@
+// Package replay records the http traffic between the clients and the nodes into fixture files,
+// and replays it later, so the tests talking to public endpoints can run offline
+//
+// The transport is set by ChainConfiguration.Transport, it's used by both the json-rpc connection
+// of EthClient and the http client of TronClient:
+//
+//	transport, err := replay.New("testdata/TestBalanceOf.json", replay.ModeReplay)
+//	config.Transport = transport
+//	cli, err := eth.NewEthClient(&config)
+//
+// The tests use the helpers of the replaytest package, which record the fixtures with REPLAY_RECORD=1
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Mode decides whether the transport sends the requests to the endpoints or replays the fixture
+type Mode int
+
+const (
+	// ModeReplay answers the requests from the fixture, no request is sent
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests to the endpoints and keeps the responses for Save
+	ModeRecord
+)
+
+// Interaction is a request and response pair in the fixture
+type Interaction struct {
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Transport is a http.RoundTripper recording or replaying the interactions
+type Transport struct {
+	mode Mode
+	path string
+	base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// New creates the transport for the fixture at path, in ModeReplay the fixture must exist
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{mode: mode, path: path, base: http.DefaultTransport}
+	if mode == ModeRecord {
+		return t, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture failed, err=%w", err)
+	}
+	if err := json.Unmarshal(data, &t.interactions); err != nil {
+		return nil, fmt.Errorf("parse fixture=%s failed, err=%w", path, err)
+	}
+	t.used = make([]bool, len(t.interactions))
+	return t, nil
+}
+
+// Save writes the recorded interactions to the fixture file
+func (t *Transport) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixture failed, err=%w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("create fixture dir failed, err=%w", err)
+	}
+	return os.WriteFile(t.path, data, 0644)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("read request failed, err=%w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if t.mode == ModeRecord {
+		return t.record(req, body)
+	}
+	return t.replay(req, body)
+}
+
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed, err=%w", err)
+	}
+	t.mu.Lock()
+	t.interactions = append(t.interactions, &Interaction{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Request:  rawJSON(body),
+		Status:   resp.StatusCode,
+		Response: rawJSON(respBody),
+	})
+	t.mu.Unlock()
+	return newResponse(req, resp.StatusCode, respBody), nil
+}
+
+// replay answers with the first unused interaction matching the request, the json-rpc id is ignored
+// while matching and replaced in the response. The last matched interaction is reused once all are used,
+// so polling the same request works
+func (t *Transport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := normalize(body)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	matched := -1
+	for i, it := range t.interactions {
+		if it.Method != req.Method || it.URL != req.URL.String() || normalize(it.Request) != key {
+			continue
+		}
+		matched = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if matched < 0 {
+		return nil, fmt.Errorf("no interaction recorded for %s %s, body=%s", req.Method, req.URL, body)
+	}
+	t.used[matched] = true
+	it := t.interactions[matched]
+	return newResponse(req, it.Status, withIDs(it.Response, body)), nil
+}
+
+func newResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// rawJSON keeps the body as json in the fixture if possible, so the fixture is readable
+func rawJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// normalize removes the json-rpc id and formats the body, so the same request matches
+// whatever the id and spacing are
+func normalize(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	switch msg := v.(type) {
+	case map[string]interface{}:
+		delete(msg, "id")
+	case []interface{}:
+		for _, m := range msg {
+			if obj, ok := m.(map[string]interface{}); ok {
+				delete(obj, "id")
+			}
+		}
+	}
+	normalized, _ := json.Marshal(v)
+	return string(normalized)
+}
+
+// withIDs sets the json-rpc ids of the request into the recorded response
+func withIDs(response json.RawMessage, request []byte) []byte {
+	var req, resp interface{}
+	if json.Unmarshal(request, &req) != nil || json.Unmarshal(response, &resp) != nil {
+		return response
+	}
+	switch r := resp.(type) {
+	case map[string]interface{}:
+		if q, ok := req.(map[string]interface{}); ok && r["id"] != nil {
+			r["id"] = q["id"]
+		}
+	case []interface{}:
+		q, ok := req.([]interface{})
+		if !ok || len(q) != len(r) {
+			return response
+		}
+		for i := range r {
+			ro, rok := r[i].(map[string]interface{})
+			qo, qok := q[i].(map[string]interface{})
+			if rok && qok {
+				ro["id"] = qo["id"]
+			}
+		}
+	}
+	replaced, err := json.Marshal(resp)
+	if err != nil {
+		return response
+	}
+	return replaced
+}
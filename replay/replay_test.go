@@ -0,0 +1,57 @@
+package replay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func post(t *testing.T, c *http.Client, url, body string) map[string]interface{} {
+	resp, err := c.Post(url, "application/json", strings.NewReader(body))
+	assert.Nil(t, err, "post failed")
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	result := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(data, &result), "parse response failed")
+	return result
+}
+
+func TestRecordReplay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		req := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req["id"], "result": n})
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	recorder, err := New(path, ModeRecord)
+	assert.Nil(t, err, "create recorder failed")
+	c := &http.Client{Transport: recorder}
+	assert.Equal(t, float64(1), post(t, c, server.URL, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)["result"])
+	assert.Equal(t, float64(2), post(t, c, server.URL, `{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber"}`)["result"])
+	assert.Nil(t, recorder.Save(), "save failed")
+
+	player, err := New(path, ModeReplay)
+	assert.Nil(t, err, "create player failed")
+	c = &http.Client{Transport: player}
+	// the ids differ from the recorded ones, the responses are replayed in order and the last one is reused
+	resp := post(t, c, server.URL, `{"id":7, "jsonrpc":"2.0","method":"eth_blockNumber"}`)
+	assert.Equal(t, float64(1), resp["result"])
+	assert.Equal(t, float64(7), resp["id"])
+	assert.Equal(t, float64(2), post(t, c, server.URL, `{"jsonrpc":"2.0","id":8,"method":"eth_blockNumber"}`)["result"])
+	assert.Equal(t, float64(2), post(t, c, server.URL, `{"jsonrpc":"2.0","id":9,"method":"eth_blockNumber"}`)["result"])
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "replay should not reach the server")
+
+	_, err = c.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+	assert.NotNil(t, err, "unrecorded request should fail")
+}
@@ -0,0 +1,57 @@
+// Package replaytest provides the test helpers recording and replaying the traffic of the clients
+// with the replay package:
+//
+//	cli, err := eth.NewEthClient(replaytest.Config(t, config))
+//
+// Run the tests with REPLAY_RECORD=1 to record the fixtures against the real endpoints
+package replaytest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/replay"
+)
+
+// RecordEnv is the environment variable enabling the record mode of ForTest
+const RecordEnv = "REPLAY_RECORD"
+
+// ForTest creates the transport for a test, the fixture is recorded if REPLAY_RECORD is set and
+// saved when the test finishes, otherwise it's replayed
+// If the fixture is not recorded yet, nil is returned and the requests are sent to the endpoints
+func ForTest(tb testing.TB, path string) http.RoundTripper {
+	tb.Helper()
+	mode := replay.ModeReplay
+	if os.Getenv(RecordEnv) != "" {
+		mode = replay.ModeRecord
+	}
+	if mode == replay.ModeReplay {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			tb.Logf("fixture=%s not recorded, the requests are sent to the endpoints, run with %s=1 to record it", path, RecordEnv)
+			return nil
+		}
+	}
+	t, err := replay.New(path, mode)
+	if err != nil {
+		tb.Fatalf("create replay transport failed, err=%s", err)
+	}
+	if mode == replay.ModeRecord {
+		tb.Cleanup(func() {
+			if err := t.Save(); err != nil {
+				tb.Errorf("save fixture failed, err=%s", err)
+			}
+		})
+	}
+	return t
+}
+
+// Config returns a copy of config whose traffic is recorded or replayed with ForTest,
+// the fixture is testdata/<test name>.json
+func Config(tb testing.TB, config client.ChainConfiguration) *client.ChainConfiguration {
+	tb.Helper()
+	config.Transport = ForTest(tb, filepath.Join("testdata", tb.Name()+".json"))
+	return &config
+}
@@ -0,0 +1,21 @@
+package replaytest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/replay"
+)
+
+func TestForTest(t *testing.T) {
+	t.Setenv(RecordEnv, "")
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	assert.Nil(t, ForTest(t, path), "missing fixture should send the requests to the endpoints")
+
+	recorder, err := replay.New(path, replay.ModeRecord)
+	assert.Nil(t, err, "create recorder failed")
+	assert.Nil(t, recorder.Save(), "save failed")
+	assert.IsType(t, &replay.Transport{}, ForTest(t, path), "recorded fixture should be replayed")
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/fbsobreira/gotron-sdk/pkg/address"
 	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
 )
 
 func TestAddress(t *testing.T) {
@@ -35,3 +38,25 @@ func TestAddressConversion(t *testing.T) {
 	a := client.AddressToString(addrValue)
 	assert.Equal(t, addr, a, "address not equal")
 }
+
+func TestVerifyMessage(t *testing.T) {
+	tc, err := NewTronClient(&tConfig)
+	assert.Nil(t, err, "create client failed")
+	key, _ := crypto.GenerateKey()
+	addr, _ := tc.AddressFromPublicKey(&key.PublicKey)
+	message := []byte("Sign in to example.com\nNonce: 42")
+	signature, err := crypto.Sign(client.HashMessage(client.TronMessagePrefix, message), key)
+	assert.Nil(t, err, "sign message failed")
+	// TronLink returns V of 27 or 28
+	signature[crypto.RecoveryIDOffset] += 27
+
+	ok, err := tc.VerifyMessage(addr, message, signature)
+	assert.Nil(t, err, "verify message failed")
+	assert.True(t, ok, "signature should match")
+	other, _ := crypto.GenerateKey()
+	otherAddr, _ := tc.AddressFromPublicKey(&other.PublicKey)
+	ok, _ = tc.VerifyMessage(otherAddr, message, signature)
+	assert.False(t, ok, "signature of other address should not match")
+	_, err = tc.VerifyMessage("not an address", message, signature)
+	assert.NotNil(t, err, "invalid address should fail")
+}
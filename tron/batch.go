@@ -0,0 +1,135 @@
+package tron
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+const (
+	// batchConcurrency is the number of calls running at the same time in a batch
+	batchConcurrency = 5
+	// batchRate is the number of calls started per second, trongrid limits the requests per second
+	batchRate = 10
+)
+
+// runLimited calls fn for 0..n-1 in parallel, at most batchConcurrency calls at the same time
+// and batchRate calls started per second. The calls not started before ctx is done are skipped,
+// the number of calls started is returned after they all finish, the calls from it on are skipped
+func runLimited(ctx context.Context, n int, fn func(i int)) int {
+	ticker := time.NewTicker(time.Second / batchRate)
+	defer ticker.Stop()
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			return i
+		}
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return i
+			case <-ticker.C:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return i
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	return n
+}
+
+// BalancesOf reads the balances of the owners with parallel calls
+func (tc *TronClient) BalancesOf(pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	return tc.BalancesOfContext(context.Background(), pairs)
+}
+
+// BalancesOfContext reads the balances of the owners with parallel calls
+// the pairs not read before ctx is done have the error of ctx
+func (tc *TronClient) BalancesOfContext(ctx context.Context, pairs []client.OwnerToken) ([]client.BalanceResult, error) {
+	results := make([]client.BalanceResult, len(pairs))
+	for i := range pairs {
+		results[i].OwnerToken = pairs[i]
+	}
+	started := runLimited(ctx, len(pairs), func(i int) {
+		if pairs[i].Token == "" {
+			results[i].Balance, results[i].Err = tc.BalanceAtContext(ctx, pairs[i].Owner)
+		} else {
+			results[i].Balance, results[i].Err = tc.BalanceOfContext(ctx, pairs[i].Token, pairs[i].Owner)
+		}
+	})
+	for i := started; i < len(results); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results, nil
+}
+
+// Allowances reads the allowances with parallel calls
+func (tc *TronClient) Allowances(queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	return tc.AllowancesContext(context.Background(), queries)
+}
+
+// AllowancesContext reads the allowances with parallel calls
+// the queries not read before ctx is done have the error of ctx
+func (tc *TronClient) AllowancesContext(ctx context.Context, queries []client.AllowanceQuery) ([]client.AllowanceResult, error) {
+	results := make([]client.AllowanceResult, len(queries))
+	for i := range queries {
+		results[i].AllowanceQuery = queries[i]
+	}
+	started := runLimited(ctx, len(queries), func(i int) {
+		q := queries[i]
+		results[i].Allowance, results[i].Err = tc.AllowanceContext(ctx, q.Token, q.Owner, q.Spender)
+	})
+	for i := started; i < len(results); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results, nil
+}
+
+// TokenMetadata reads the symbol, decimals and total supply of the contracts with parallel calls
+func (tc *TronClient) TokenMetadata(contracts []string) ([]client.TokenMetadata, error) {
+	return tc.TokenMetadataContext(context.Background(), contracts)
+}
+
+// TokenMetadataContext reads the symbol, decimals and total supply of the contracts with parallel calls
+// the contracts not read before ctx is done have the error of ctx
+func (tc *TronClient) TokenMetadataContext(ctx context.Context, contracts []string) ([]client.TokenMetadata, error) {
+	results := make([]client.TokenMetadata, len(contracts))
+	errs := make([][3]error, len(contracts))
+	// every contract needs 3 calls, which are scheduled as separate calls
+	started := runLimited(ctx, len(contracts)*3, func(i int) {
+		m := &results[i/3]
+		switch i % 3 {
+		case 0:
+			m.Symbol, errs[i/3][0] = tc.SymbolOfContext(ctx, contracts[i/3])
+		case 1:
+			m.Decimals, errs[i/3][1] = tc.DecimalsOfContext(ctx, contracts[i/3])
+		case 2:
+			m.TotalSupply, errs[i/3][2] = tc.TotalSupplyOfContext(ctx, contracts[i/3])
+		}
+	})
+	for i := started; i < len(contracts)*3; i++ {
+		errs[i/3][i%3] = ctx.Err()
+	}
+	for i := range results {
+		results[i].Contract = contracts[i]
+		for _, err := range errs[i] {
+			if err != nil {
+				results[i].Err = err
+				break
+			}
+		}
+	}
+	return results, nil
+}
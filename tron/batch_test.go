@@ -0,0 +1,157 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ecommon "github.com/ethereum/go-ethereum/common"
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// testAddress is the base58 address ending with b
+func testAddress(b byte) string {
+	return address.Address(append([]byte{0x41}, ecommon.LeftPadBytes([]byte{b}, 20)...)).String()
+}
+
+// batchServer answers the balances with 100 trx, the trc20 reads with 40, "USDT" and 6, and the allowances with 40
+// eth_getBalance of limited is rate limited, the calls to reverted revert, every trc20 read takes latency
+type batchServer struct {
+	limited, reverted string
+	latency           time.Duration
+
+	inflight, maxInflight int32
+}
+
+func (s *batchServer) handler(t *testing.T) http.Handler {
+	word := func(v int64) string { return ecommon.Bytes2Hex(ecommon.LeftPadBytes(big.NewInt(v).Bytes(), 32)) }
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jsonrpc":
+			var req struct {
+				ID     json.RawMessage   `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req), "decode request failed")
+			limited, _ := address.Base58ToAddress(s.limited)
+			if req.Method == "eth_getBalance" && strings.EqualFold(string(req.Params[0]), `"`+limited.Hex()+`"`) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			result := "0x64"
+			if req.Method == "eth_call" {
+				result = "0x" + word(40)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		case "/wallet/triggerconstantcontract":
+			n := atomic.AddInt32(&s.inflight, 1)
+			defer atomic.AddInt32(&s.inflight, -1)
+			for max := atomic.LoadInt32(&s.maxInflight); n > max && !atomic.CompareAndSwapInt32(&s.maxInflight, max, n); {
+				max = atomic.LoadInt32(&s.maxInflight)
+			}
+			time.Sleep(s.latency)
+			var req walletRequest
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req), "decode request failed")
+			if req.ContractAddress == s.reverted {
+				w.Write([]byte(`{"result":{"code":"CONTRACT_EXE_ERROR","message":"5245564552540f6f70636f6465206578656375746564"}}`))
+				return
+			}
+			output := word(40)
+			switch req.FunctionSelector {
+			case "symbol()":
+				output = word(32) + word(4) + ecommon.Bytes2Hex(ecommon.RightPadBytes([]byte("USDT"), 32))
+			case "decimals()":
+				output = word(6)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]bool{"result": true}, "constant_result": []string{output}})
+		}
+	})
+}
+
+func newBatchClient(t *testing.T, s *batchServer) (*TronClient, func()) {
+	server := httptest.NewServer(s.handler(t))
+	tclient, err := NewTronClient(&client.ChainConfiguration{
+		ChainName: "Tron",
+		Endpoints: []string{server.URL + "/jsonrpc", server.URL, server.URL},
+	})
+	assert.Nil(t, err, "create client failed")
+	return tclient, server.Close
+}
+
+func TestBalancesOfBatch(t *testing.T) {
+	owner, token := testAddress(1), testAddress(2)
+	s := &batchServer{limited: testAddress(3), reverted: testAddress(4), latency: 600 * time.Millisecond}
+	tclient, closeServer := newBatchClient(t, s)
+	defer closeServer()
+
+	pairs := []client.OwnerToken{{Owner: owner}, {Owner: s.limited}, {Owner: owner, Token: s.reverted}}
+	for i := 0; i < 6; i++ {
+		pairs = append(pairs, client.OwnerToken{Owner: owner, Token: token})
+	}
+	start := time.Now()
+	results, err := tclient.BalancesOf(pairs)
+	assert.Nil(t, err, "batch failed")
+	// the calls are started at batchRate per second
+	assert.GreaterOrEqual(t, time.Since(start), time.Duration(len(pairs)-1)*time.Second/batchRate)
+	assert.Equal(t, int32(batchConcurrency), atomic.LoadInt32(&s.maxInflight), "calls in flight should be limited")
+
+	assert.Len(t, results, len(pairs))
+	assert.Equal(t, big.NewInt(100), results[0].Balance)
+	assert.ErrorIs(t, results[1].Err, client.ErrRateLimited)
+	assert.ErrorIs(t, results[2].Err, client.ErrReverted)
+	for _, r := range results[3:] {
+		assert.Nil(t, r.Err, "read balance failed")
+		assert.Equal(t, big.NewInt(40), r.Balance)
+		assert.Equal(t, token, r.Token)
+	}
+}
+
+func TestTokenMetadataBatch(t *testing.T) {
+	token := testAddress(2)
+	s := &batchServer{reverted: testAddress(4)}
+	tclient, closeServer := newBatchClient(t, s)
+	defer closeServer()
+
+	metadata, err := tclient.TokenMetadata([]string{token, s.reverted})
+	assert.Nil(t, err, "batch failed")
+	assert.Len(t, metadata, 2)
+	assert.Nil(t, metadata[0].Err, "read metadata failed")
+	assert.Equal(t, "USDT", metadata[0].Symbol)
+	assert.Equal(t, uint8(6), metadata[0].Decimals)
+	assert.Equal(t, big.NewInt(40), metadata[0].TotalSupply)
+	assert.Equal(t, s.reverted, metadata[1].Contract)
+	assert.ErrorIs(t, metadata[1].Err, client.ErrReverted)
+}
+
+func TestAllowancesBatchCancel(t *testing.T) {
+	owner, token := testAddress(1), testAddress(2)
+	tclient, closeServer := newBatchClient(t, &batchServer{})
+	defer closeServer()
+
+	queries := make([]client.AllowanceQuery, 5)
+	for i := range queries {
+		queries[i] = client.AllowanceQuery{Token: token, Owner: owner, Spender: testAddress(byte(10 + i))}
+	}
+	// the first call is started at once, the others wait for the rate limit until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second/batchRate/2)
+	defer cancel()
+	results, err := tclient.AllowancesContext(ctx, queries)
+	assert.Nil(t, err, "batch failed")
+	assert.Len(t, results, len(queries))
+	assert.Nil(t, results[0].Err, "read allowance failed")
+	assert.Equal(t, big.NewInt(40), results[0].Allowance)
+	for _, r := range results[1:] {
+		assert.ErrorIs(t, r.Err, context.DeadlineExceeded)
+		assert.Equal(t, token, r.Token, "query should be kept")
+	}
+}
@@ -0,0 +1,106 @@
+package tron
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	eABI "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+// walletErrorKinds maps the error codes returned by wallet apis to the error kinds
+var walletErrorKinds = map[string]error{
+	"BANDWITH_ERROR":                  client.ErrInsufficientFunds,
+	"SERVER_BUSY":                     client.ErrRateLimited,
+	"NOT_ENOUGH_EFFECTIVE_CONNECTION": client.ErrTransport,
+}
+
+// walletMessageKinds maps the error messages returned by wallet apis to the error kinds
+var walletMessageKinds = []struct {
+	message string
+	kind    error
+}{
+	{"balance is not sufficient", client.ErrInsufficientFunds},
+	{"account resource insufficient", client.ErrInsufficientFunds},
+	{"not enough energy", client.ErrInsufficientFunds},
+	{"does not exist", client.ErrNotFound},
+}
+
+// transportError marks the error of sending http request as transport error
+// the error caused by canceling the context is returned as it is
+func transportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return client.NewError(client.ErrTransport, err)
+}
+
+// statusError checks the status code of the http response
+func statusError(url string, status int, body []byte) error {
+	err := fmt.Errorf("call url=%s failed, status=%d, body=%s", url, status, body)
+	switch {
+	case status == http.StatusTooManyRequests:
+		return client.NewError(client.ErrRateLimited, err)
+	case status == http.StatusForbidden && strings.Contains(strings.ToLower(string(body)), "limit"):
+		// trongrid returns 403 when the api key exceeds the frequency limit
+		return client.NewError(client.ErrRateLimited, err)
+	case status >= http.StatusInternalServerError:
+		return client.NewError(client.ErrTransport, err)
+	}
+	return nil
+}
+
+// decodeMessage decodes the message of wallet apis, which is hexed sometimes
+func decodeMessage(message string) string {
+	if decoded, err := hex.DecodeString(message); err == nil && len(decoded) > 0 {
+		return string(decoded)
+	}
+	return message
+}
+
+// walletError converts the code and message returned by wallet apis into errors defined in client package
+func walletError(code, message string, constantResult []string) error {
+	message = decodeMessage(message)
+	err := fmt.Errorf("code=%s, message=%s", code, message)
+	if kind, ok := walletErrorKinds[code]; ok {
+		return client.NewError(kind, err)
+	}
+	lower := strings.ToLower(message)
+	for _, e := range walletMessageKinds {
+		if strings.Contains(lower, e.message) {
+			return client.NewError(e.kind, err)
+		}
+	}
+	if code == "CONTRACT_EXE_ERROR" || strings.Contains(lower, "revert") {
+		revertErr := &client.RevertError{}
+		if len(constantResult) > 0 {
+			revertErr.Data, _ = hex.DecodeString(constantResult[0])
+		}
+		revertErr.Reason, _ = eABI.UnpackRevert(revertErr.Data)
+		return revertErr
+	}
+	return err
+}
+
+// jsonRPCError converts the error of tron json-rpc into errors defined in client package
+func jsonRPCError(code int, message string, data string) error {
+	err := fmt.Errorf("code=%d, message=%s", code, message)
+	lower := strings.ToLower(message)
+	if strings.Contains(lower, "revert") {
+		revertErr := &client.RevertError{}
+		revertErr.Data, _ = hexutil.Decode(data)
+		revertErr.Reason, _ = eABI.UnpackRevert(revertErr.Data)
+		return revertErr
+	}
+	for _, e := range walletMessageKinds {
+		if strings.Contains(lower, e.message) {
+			return client.NewError(e.kind, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.bipal.space/shared-lib/blockchain/client"
+)
+
+func TestErrorMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jsonrpc":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/wallet/triggerconstantcontract":
+			// Error(string) with reason "not allowed"
+			w.Write([]byte(`{"result":{"code":"CONTRACT_EXE_ERROR","message":"5245564552540f6f70636f6465206578656375746564"},` +
+				`"constant_result":["08c379a00000000000000000000000000000000000000000000000000000000000000020` +
+				`000000000000000000000000000000000000000000000000000000000000000b6e6f7420616c6c6f776564000000000000000000000000000000000000000000"]}`))
+		case "/wallet/broadcasttransaction":
+			w.Write([]byte(`{"result":false,"code":"BANDWITH_ERROR","message":"4163636f756e74207265736f7572636520696e73756666696369656e74"}`))
+		case "/wallet/gettransactionbyid", "/wallet/gettransactioninfobyid":
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	tclient, err := NewTronClient(&client.ChainConfiguration{
+		ChainName: "Tron",
+		Endpoints: []string{server.URL + "/jsonrpc", server.URL, server.URL},
+	})
+	assert.Nil(t, err, "create client failed")
+	ctx := context.Background()
+
+	_, err = tclient.BalanceAtContext(ctx, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.ErrorIs(t, err, client.ErrRateLimited, "429 should be rate limited")
+
+	_, err = tclient.c.triggerConstantContract(ctx, "", "decimals()", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", emptyAddressBase58)
+	assert.ErrorIs(t, err, client.ErrReverted, "call should be reverted")
+	var revertErr *client.RevertError
+	assert.True(t, errors.As(err, &revertErr), "revert error expected")
+	assert.Equal(t, "not allowed", revertErr.Reason, "revert reason not match")
+	_, err = tclient.TotalSupplyOfContext(ctx, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.ErrorIs(t, err, client.ErrReverted, "total supply should be reverted")
+
+	err = tclient.c.BroadCastTransactionContext(ctx, &TronTransaction{})
+	assert.ErrorIs(t, err, client.ErrInsufficientFunds, "bandwidth error should be insufficient funds")
+
+	_, err = tclient.GetTransactionByHashContext(ctx, "0x1234")
+	assert.ErrorIs(t, err, client.ErrNotFound, "transaction should not be found")
+}
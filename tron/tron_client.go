@@ -2,6 +2,7 @@ package tron
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	eABI "github.com/ethereum/go-ethereum/accounts/abi"
 	ecommon "github.com/ethereum/go-ethereum/common"
@@ -26,6 +28,15 @@ const (
 	addressPrefix      = byte(0x41)
 	emptyAddressHex    = "410000000000000000000000000000000000000000"
 	transactionSuccess = "SUCCESS"
+	// solidifiedConfirmations is the number of blocks, produced by 2/3 of the 27 super representatives,
+	// after which a block is solidified
+	solidifiedConfirmations = 19
+	// transactionDropAfter is how long a missing transaction is waited for, transactions expire 60s
+	// after they are created and a margin is kept for the clock drift
+	transactionDropAfter = 90 * time.Second
+
+	// EndpointCount is the number of endpoints of a tron chain: the json-rpc, the full node and the trongrid api
+	EndpointCount = 3
 )
 
 var (
@@ -35,6 +46,12 @@ var (
 	trc20Function      = map[string]string{"transferFrom": "transferFrom(address,address,uint256)", "balanceOf": "balanceOf(address)"}
 )
 
+var (
+	_ client.BlockChainClient    = (*TronClient)(nil)
+	_ client.BlockChainClientCtx = (*TronClient)(nil)
+	_ client.MessageVerifier     = (*TronClient)(nil)
+)
+
 // TronClient implements BlockChainClient Interface
 type TronClient struct {
 	c      *HTTPClient
@@ -43,20 +60,39 @@ type TronClient struct {
 	chainID *big.Int
 }
 
-// NewTronClient creates the client
+// NewTronClient creates the client, config.Endpoints are the json-rpc, the full node and the trongrid api
 func NewTronClient(config *client.ChainConfiguration) (*TronClient, error) {
+	if len(config.Endpoints) < EndpointCount {
+		return nil, fmt.Errorf("tron needs %d endpoints, got=%d", EndpointCount, len(config.Endpoints))
+	}
 	c := TronClient{}
 	c.abiMap = sync.Map{}
 	if err := c.RegisterABI(trc20ABIName, trc20Abi); err != nil {
-		return nil, fmt.Errorf("register trc20 abi failed, err=%s", err)
+		return nil, fmt.Errorf("register trc20 abi failed, err=%w", err)
 	}
 
 	c.c = NewHTTPClient(config.Endpoints[0], config.Endpoints[1], config.Endpoints[2])
 	c.chainID = config.ChainID
 	c.c.APIKey = config.APIKey
+	c.c.client.Timeout = config.Timeout
+	if config.Transport != nil {
+		c.c.client.Transport = config.Transport
+	}
 	return &c, nil
 }
 
+// Finality returns the finality rule used by client.TxTracker
+// a block is solidified after 19 confirmations, a transaction expires 60s after it's created
+func (tc *TronClient) Finality() client.Finality {
+	return client.Finality{Confirmations: solidifiedConfirmations, DropAfter: transactionDropAfter}
+}
+
+// Close closes the connections to the endpoints
+func (tc *TronClient) Close() error {
+	tc.c.Close()
+	return nil
+}
+
 // RegisterABI registe the abi with a name
 func (tc *TronClient) RegisterABI(name, abiStr string) error {
 	compiled, err := eABI.JSON(strings.NewReader(abiStr))
@@ -69,20 +105,30 @@ func (tc *TronClient) RegisterABI(name, abiStr string) error {
 
 // BalanceAt returns the amount of trx
 func (tc *TronClient) BalanceAt(address string) (*big.Int, error) {
+	return tc.BalanceAtContext(context.Background(), address)
+}
+
+// BalanceAtContext returns the amount of trx
+func (tc *TronClient) BalanceAtContext(ctx context.Context, address string) (*big.Int, error) {
 	//以太坊地址 -> Tron地址
 	if ecommon.IsHexAddress(address) {
 		address = tc.c.convertETHAddress(address)
 	}
 
-	balance, err := tc.c.BalaceAt(address)
+	balance, err := tc.c.BalaceAtContext(ctx, address)
 	if err != nil {
-		return nil, fmt.Errorf("http call failed, err=%s", err)
+		return nil, fmt.Errorf("http call failed, err=%w", err)
 	}
 	return balance, nil
 }
 
 // BalanceOf returns the amount of a token
 func (tc *TronClient) BalanceOf(contract, from string) (*big.Int, error) {
+	return tc.BalanceOfContext(context.Background(), contract, from)
+}
+
+// BalanceOfContext returns the amount of a token
+func (tc *TronClient) BalanceOfContext(ctx context.Context, contract, from string) (*big.Int, error) {
 	//以太坊地址 -> Tron地址
 	if ecommon.IsHexAddress(from) {
 		from = tc.c.convertETHAddress(from)
@@ -90,29 +136,47 @@ func (tc *TronClient) BalanceOf(contract, from string) (*big.Int, error) {
 
 	params, err := tc.generateParams("balanceOf", trc20ABIName, from)
 	if err != nil {
-		return nil, fmt.Errorf("generate request from abi failed, err=%s", err)
+		return nil, fmt.Errorf("generate request from abi failed, err=%w", err)
 	}
 	parameter, err := abi.GetPaddedParam(params)
 	if err != nil {
-		return nil, fmt.Errorf("pack request failed, err=%s", err)
+		return nil, fmt.Errorf("pack request failed, err=%w", err)
 	}
-	return tc.c.BalanceOf(contract, from, common.BytesToHexString(parameter))
+	return tc.c.BalanceOfContext(ctx, contract, from, common.BytesToHexString(parameter))
 }
 
 // DecimalsOf returns the decimals of an contract
 func (tc *TronClient) DecimalsOf(contract string) (uint8, error) {
-	decimals, err := tc.c.DecimalsOf(contract)
-	return uint8(decimals.Uint64()), err
+	return tc.DecimalsOfContext(context.Background(), contract)
+}
+
+// DecimalsOfContext returns the decimals of an contract
+func (tc *TronClient) DecimalsOfContext(ctx context.Context, contract string) (uint8, error) {
+	decimals, err := tc.c.DecimalsOfContext(ctx, contract)
+	if err != nil {
+		return 0, err
+	}
+	return uint8(decimals.Uint64()), nil
 }
 
 // TotalSupplyOf returns the total supply of a contract
 func (tc *TronClient) TotalSupplyOf(contract string) (*big.Int, error) {
-	return tc.c.TotalSupplyOf(contract)
+	return tc.TotalSupplyOfContext(context.Background(), contract)
+}
+
+// TotalSupplyOfContext returns the total supply of a contract
+func (tc *TronClient) TotalSupplyOfContext(ctx context.Context, contract string) (*big.Int, error) {
+	return tc.c.TotalSupplyOfContext(ctx, contract)
 }
 
 // SymbolOf returns the symbol of a contract
 func (tc *TronClient) SymbolOf(contract string) (string, error) {
-	return tc.c.SymbolOf(contract)
+	return tc.SymbolOfContext(context.Background(), contract)
+}
+
+// SymbolOfContext returns the symbol of a contract
+func (tc *TronClient) SymbolOfContext(ctx context.Context, contract string) (string, error) {
+	return tc.c.SymbolOfContext(ctx, contract)
 }
 
 func (tc *TronClient) TransferData(to string, value *big.Int) ([]byte, error) {
@@ -126,18 +190,22 @@ func (tc *TronClient) ApproveData(contract, owner, spender string, amount *big.I
 }
 
 func (tc *TronClient) Allowance(contract, owner, spender string) (*big.Int, error) {
+	return tc.AllowanceContext(context.Background(), contract, owner, spender)
+}
+
+func (tc *TronClient) AllowanceContext(ctx context.Context, contract, owner, spender string) (*big.Int, error) {
 	method := "allowance"
 	data, err := tc.GetTransactionDataByABI(method, trc20ABIName, owner, spender)
 	if err != nil {
-		return nil, fmt.Errorf("get transaction data failed, err=%s", err)
+		return nil, fmt.Errorf("get transaction data failed, err=%w", err)
 	}
-	result, err := tc.c.EthCall(owner, contract, big.NewInt(0), data)
+	result, err := tc.c.EthCallContext(ctx, owner, contract, big.NewInt(0), data)
 	if err != nil {
-		return nil, fmt.Errorf("http call failed, err=%s", err)
+		return nil, fmt.Errorf("http call failed, err=%w", err)
 	}
 	fields, err := tc.UnpackByABI(method, trc20ABIName, result)
 	if err != nil {
-		return nil, fmt.Errorf("unpack failed, err=%s", err)
+		return nil, fmt.Errorf("unpack failed, err=%w", err)
 	}
 	if len(fields) != 1 {
 		return nil, fmt.Errorf("unpack result failed, fields=%d", len(fields))
@@ -149,7 +217,7 @@ func (tc *TronClient) generateParams(method string, abiName string, args ...inte
 	data []abi.Param, err error) {
 	compiled, err := tc.GetABIByName(abiName)
 	if err != nil {
-		return nil, fmt.Errorf("get abi failed, err=%s", err)
+		return nil, fmt.Errorf("get abi failed, err=%w", err)
 	}
 	return tc.generateFromAbi(method, compiled, args...)
 }
@@ -178,15 +246,15 @@ func (tc *TronClient) generateFromAbi(method string, compiledAbi *eABI.ABI, args
 func (tc *TronClient) GetTransactionData(method string, abiStr string, args ...interface{}) ([]byte, error) {
 	compiled, err := eABI.JSON(strings.NewReader(abiStr))
 	if err != nil {
-		return nil, fmt.Errorf("parse abi failed, err=%s", err)
+		return nil, fmt.Errorf("parse abi failed, err=%w", err)
 	}
 	params, err := tc.generateFromAbi(method, &compiled, args...)
 	if err != nil {
-		return nil, fmt.Errorf("generate params failed, err=%s", err)
+		return nil, fmt.Errorf("generate params failed, err=%w", err)
 	}
 	data, err := abi.GetPaddedParam(params)
 	if err != nil {
-		return nil, fmt.Errorf("pack failed, err=%s", err)
+		return nil, fmt.Errorf("pack failed, err=%w", err)
 	}
 	return data, nil
 }
@@ -195,7 +263,7 @@ func (tc *TronClient) GetTransactionData(method string, abiStr string, args ...i
 func (tc *TronClient) GetTransactionDataByABI(method, abiName string, args ...interface{}) (data []byte, err error) {
 	compiled, err := tc.GetABIByName(abiName)
 	if err != nil {
-		return nil, fmt.Errorf("get abi failed, err=%s", err)
+		return nil, fmt.Errorf("get abi failed, err=%w", err)
 	}
 
 	methodAbi, ok := compiled.Methods[method]
@@ -212,7 +280,7 @@ func (tc *TronClient) GetTransactionDataByABI(method, abiName string, args ...in
 			if v.Kind() == reflect.String {
 				addr, err := address.Base58ToAddress(args[i].(string))
 				if err != nil {
-					return nil, fmt.Errorf("parse address failed, err=%s", err)
+					return nil, fmt.Errorf("parse address failed, err=%w", err)
 				}
 				requests = append(requests, ecommon.HexToAddress(addr.Hex()))
 			} else {
@@ -228,7 +296,7 @@ func (tc *TronClient) GetTransactionDataByABI(method, abiName string, args ...in
 func (tc *TronClient) GetFunctionSelectorByData(abiName string, data []byte) (sig string, err error) {
 	compiled, err := tc.GetABIByName(abiName)
 	if err != nil {
-		return "", fmt.Errorf("get abi failed, err=%s", err)
+		return "", fmt.Errorf("get abi failed, err=%w", err)
 	}
 
 	if len(data) < 4 {
@@ -238,7 +306,7 @@ func (tc *TronClient) GetFunctionSelectorByData(abiName string, data []byte) (si
 	sigData := data[:4]
 	method, err := compiled.MethodById(sigData)
 	if err != nil {
-		return "", fmt.Errorf("method not found, err=%s", err)
+		return "", fmt.Errorf("method not found, err=%w", err)
 	}
 	return method.Sig, nil
 }
@@ -258,20 +326,25 @@ func (tc *TronClient) getFunctionSelector(abiName, method string) string {
 
 // GetTransaction returns the unsigned transaction and the hash value
 func (tc *TronClient) GetTransaction(td *client.Transaction) ([]byte, []byte, error) {
+	return tc.GetTransactionContext(context.Background(), td)
+}
+
+// GetTransactionContext returns the unsigned transaction and the hash value
+func (tc *TronClient) GetTransactionContext(ctx context.Context, td *client.Transaction) ([]byte, []byte, error) {
 	var tx *TransactionExtention
 	var err error
 	if len(td.Data) == 0 {
-		tx, err = tc.c.TriggerTransfer(td.From, td.To, td.Amount)
+		tx, err = tc.c.TriggerTransferContext(ctx, td.From, td.To, td.Amount)
 	} else {
 		energyLimit := big.NewInt(0)
 		if td.Fee != nil && td.Fee.Gas != nil && td.Fee.GasFeeCap != nil {
 			energyLimit = big.NewInt(1).Mul(td.Fee.Gas, td.Fee.GasFeeCap)
 			energyLimit = energyLimit.Add(energyLimit, big.NewInt(int64(len(td.Data)/2)))
 		}
-		tx, err = tc.c.TriggerSmartContract(td.To, td.From, td.Data, energyLimit)
+		tx, err = tc.c.TriggerSmartContractContext(ctx, td.To, td.From, td.Data, energyLimit)
 	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("triggersmartcontract failed, err=%s", err)
+		return nil, nil, fmt.Errorf("triggersmartcontract failed, err=%w", err)
 	}
 	return tc.getTransactionExtentionData(tx)
 }
@@ -281,32 +354,42 @@ func (tc *TronClient) GetTransaction(td *client.Transaction) ([]byte, []byte, er
 // to change the sign functions to make it work
 func (tc *TronClient) DeployContract(contractAbi, contractBin string, td *client.Transaction) (
 	[]byte, []byte, string, error) {
-	tx, addr, err := tc.c.DeployContract(contractAbi, contractBin, td.From, "Migrations")
+	return tc.DeployContractContext(context.Background(), contractAbi, contractBin, td)
+}
+
+func (tc *TronClient) DeployContractContext(ctx context.Context, contractAbi, contractBin string, td *client.Transaction) (
+	[]byte, []byte, string, error) {
+	tx, addr, err := tc.c.DeployContractContext(ctx, contractAbi, contractBin, td.From, "Migrations")
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("try deploy failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("try deploy failed, err=%w", err)
 	}
 	message, err := json.Marshal(tx)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("encode transaction failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("encode transaction failed, err=%w", err)
 	}
 	hash, err := hex.DecodeString(tx.Txid)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("decode txid to hash failed, err=%s", err)
+		return nil, nil, "", fmt.Errorf("decode txid to hash failed, err=%w", err)
 	}
 	return message, hash, addr, nil
 }
 
 // BroadcastTransaction broadcasts the transaction to chain
 func (tc *TronClient) BroadcastTransaction(trans []byte, signature []byte) ([]byte, error) {
+	return tc.BroadcastTransactionContext(context.Background(), trans, signature)
+}
+
+// BroadcastTransactionContext broadcasts the transaction to chain
+func (tc *TronClient) BroadcastTransactionContext(ctx context.Context, trans []byte, signature []byte) ([]byte, error) {
 	tx := TransactionExtention{}
 	d := json.NewDecoder(bytes.NewReader(trans))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("transaction format is incorrect, err=%s", err)
+		return nil, fmt.Errorf("transaction format is incorrect, err=%w", err)
 	}
 	txid, err := hex.DecodeString(tx.Txid)
 	if err != nil {
-		return nil, fmt.Errorf("decode txid failed, err=%s", err)
+		return nil, fmt.Errorf("decode txid failed, err=%w", err)
 	}
 	sig := hex.EncodeToString(signature)
 	transaction := TronTransaction{}
@@ -316,28 +399,58 @@ func (tc *TronClient) BroadcastTransaction(trans []byte, signature []byte) ([]by
 	transaction.ContractAddress = tx.Transaction.ContractAddress
 	transaction.Visible = tx.Transaction.Visible
 	transaction.Txid = string(tx.Txid)
-	return txid, tc.c.BroadCastTransaction(&transaction)
+	return txid, tc.c.BroadCastTransactionContext(ctx, &transaction)
+}
+
+// SignAndSend generates the transaction of td, signs it by signer and broadcasts it
+func (tc *TronClient) SignAndSend(td *client.Transaction, signer client.Signer, opts ...client.SendOption) ([]byte, error) {
+	return tc.SignAndSendContext(context.Background(), td, signer, opts...)
+}
+
+// SignAndSendContext generates the transaction of td, signs it by signer and broadcasts it, the txid is returned
+// td.From is set to the base58 address of signer if it's empty, the fee is suggested if td.Fee is nil
+func (tc *TronClient) SignAndSendContext(ctx context.Context, td *client.Transaction, signer client.Signer, opts ...client.SendOption) ([]byte, error) {
+	from := tc.AddressToString(signer.Address())
+	if td.From == "" {
+		td.From = from
+	} else if tc.NormalizeAddress(td.From) != from {
+		return nil, fmt.Errorf("signer=%s is not the sender=%s", from, td.From)
+	}
+	return client.SignAndSendContext(ctx, tc, td, signer, opts...)
 }
 
 // GetNonce is not implemented for Tron
 // And Tron is not used by Tron
 func (tc *TronClient) GetNonce(address string) (uint64, error) {
+	return tc.GetNonceContext(context.Background(), address)
+}
+
+func (tc *TronClient) GetNonceContext(ctx context.Context, address string) (uint64, error) {
 	return 0, nil
 }
 
 func (tc *TronClient) GetNonceByNumber(address string, blockNumber *big.Int) (uint64, error) {
+	return tc.GetNonceByNumberContext(context.Background(), address, blockNumber)
+}
+
+func (tc *TronClient) GetNonceByNumberContext(ctx context.Context, address string, blockNumber *big.Int) (uint64, error) {
 	return 0, nil
 }
 
 // GetSuggestFee returns the estimated fee for a transaction
 func (tc *TronClient) GetSuggestFee(td *client.Transaction) (*client.FeeLimit, error) {
-	gas, err := tc.EstimateGas(td)
+	return tc.GetSuggestFeeContext(context.Background(), td)
+}
+
+// GetSuggestFeeContext returns the estimated fee for a transaction
+func (tc *TronClient) GetSuggestFeeContext(ctx context.Context, td *client.Transaction) (*client.FeeLimit, error) {
+	gas, err := tc.EstimateGasContext(ctx, td)
 	if err != nil {
-		return nil, fmt.Errorf("estimate gas failed, err=%s", err)
+		return nil, fmt.Errorf("estimate gas failed, err=%w", err)
 	}
-	gasPrice, _, err := tc.GetGasPrice()
+	gasPrice, _, err := tc.GetGasPriceContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("get gas price failed, err=%s", err)
+		return nil, fmt.Errorf("get gas price failed, err=%w", err)
 	}
 	fee := client.FeeLimit{}
 	fee.Gas = new(big.Int).SetUint64(gas)
@@ -347,26 +460,35 @@ func (tc *TronClient) GetSuggestFee(td *client.Transaction) (*client.FeeLimit, e
 }
 
 func (tc *TronClient) EstimateGas(td *client.Transaction) (uint64, error) {
+	return tc.EstimateGasContext(context.Background(), td)
+}
+
+func (tc *TronClient) EstimateGasContext(ctx context.Context, td *client.Transaction) (uint64, error) {
 	if len(td.Data) <= 0 {
 		return 0, nil
 	}
 
-	gasLimit, err := tc.c.EstimateGas(td.From, td.To, "0x"+td.Amount.Text(16), td.Data)
+	gasLimit, err := tc.c.EstimateGasContext(ctx, td.From, td.To, "0x"+td.Amount.Text(16), td.Data)
 	if err != nil {
-		return 0, fmt.Errorf("eth estimategas failed, err=%s", err)
+		return 0, fmt.Errorf("eth estimategas failed, err=%w", err)
 	}
 	return gasLimit.Uint64(), nil
 }
 
 // CallContract call eth_call
 func (tc *TronClient) CallContract(td *client.Transaction) ([]byte, error) {
-	return tc.c.EthCall(td.From, td.To, td.Amount, td.Data)
+	return tc.CallContractContext(context.Background(), td)
+}
+
+// CallContractContext call eth_call
+func (tc *TronClient) CallContractContext(ctx context.Context, td *client.Transaction) ([]byte, error) {
+	return tc.c.EthCallContext(ctx, td.From, td.To, td.Amount, td.Data)
 }
 
 func (tc *TronClient) UnpackByABI(method, name string, data []byte) ([]interface{}, error) {
 	compiled, err := tc.GetABIByName(name)
 	if err != nil {
-		return nil, fmt.Errorf("get abi by name failed, err=%s", err)
+		return nil, fmt.Errorf("get abi by name failed, err=%w", err)
 	}
 	return compiled.Unpack(method, data)
 }
@@ -413,7 +535,7 @@ func (tc *TronClient) AddressFromPrivateKey(privateKey string) (string, error) {
 	}
 	key, err := ecrypto.HexToECDSA(privateKey)
 	if err != nil {
-		return "", fmt.Errorf("wrong private key=%s, err=%s", privateKey, err)
+		return "", fmt.Errorf("wrong private key=%s, err=%w", privateKey, err)
 	}
 	return tc.AddressFromPublicKey(&key.PublicKey)
 }
@@ -439,10 +561,18 @@ func hexToBase58(hexAddr string) string {
 }
 
 func (tc *TronClient) GetLatestBlockNumber() (*big.Int, error) {
-	return tc.c.GetBlockByLastNumber()
+	return tc.GetLatestBlockNumberContext(context.Background())
+}
+
+func (tc *TronClient) GetLatestBlockNumberContext(ctx context.Context) (*big.Int, error) {
+	return tc.c.GetBlockByLastNumberContext(ctx)
 }
 
 func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.TransactionInfo, error) {
+	return tc.GetTransactionByHashContext(context.Background(), transactionHash)
+}
+
+func (tc *TronClient) GetTransactionByHashContext(ctx context.Context, transactionHash string) (*client.TransactionInfo, error) {
 	if strings.HasPrefix(transactionHash, "0x") {
 		transactionHash = transactionHash[2:]
 	}
@@ -450,16 +580,20 @@ func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.Tran
 	tx := client.Transaction{}
 	info.Tx = &tx
 
-	txInfo, err := tc.c.GetTransactionInfoByID(transactionHash)
+	txInfo, err := tc.c.GetTransactionInfoByIDContext(ctx, transactionHash)
 	if err != nil {
-		return nil, fmt.Errorf("get transaction info failed, err=%s", err)
+		return nil, fmt.Errorf("get transaction info failed, err=%w", err)
 	}
-	transaction, err := tc.c.GetTransactionByID(transactionHash)
+	transaction, err := tc.c.GetTransactionByIDContext(ctx, transactionHash)
 	if err != nil {
-		return nil, fmt.Errorf("get transaction failed, err=%s", err)
+		return nil, fmt.Errorf("get transaction failed, err=%w", err)
+	}
+	if transaction.Txid == "" {
+		return nil, client.NewError(client.ErrNotFound, fmt.Errorf("transaction %s not found", transactionHash))
 	}
 	if transaction.RawData == nil || len(transaction.Ret) == 0 {
-		// maybe not finished, but we don't know
+		// the transaction is known but not executed yet
+		info.IsPending = true
 		return &info, nil
 	}
 	if len(transaction.RawData.Contract) == 0 {
@@ -487,7 +621,7 @@ func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.Tran
 		tx.Data, err = hex.DecodeString(callDataStr)
 		if err != nil {
 			info.Status, info.Error = client.TransactionStatusInvalid, "call_data_decode_failed"
-			return nil, fmt.Errorf("transaction data decode failed, err=%s", err)
+			return nil, fmt.Errorf("transaction data decode failed, err=%w", err)
 		}
 	}
 	info.IsPending = true
@@ -502,15 +636,16 @@ func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.Tran
 	tx.ChainID = tc.chainID
 	if txInfo.BlockNumber != nil {
 		info.IsPending = false
+		info.BlockNumber = txInfo.BlockNumber
 	}
 	if strings.EqualFold(transaction.Ret[0].ContractRet, "SUCCESS") {
 		info.Status = client.TransactionStatusSuccess
 	} else {
 		info.Status = client.TransactionStatusFailed
 	}
-	logs, err := tc.c.GetTransactionEventsByID(transactionHash)
+	logs, err := tc.c.GetTransactionEventsByIDContext(ctx, transactionHash)
 	if err != nil {
-		return nil, fmt.Errorf("get event logs failed, err=%s", err)
+		return nil, fmt.Errorf("get event logs failed, err=%w", err)
 	}
 	for i := range logs.Data {
 		if logs.Data[i] == nil {
@@ -518,7 +653,7 @@ func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.Tran
 		}
 		tronEvent, err := json.Marshal(logs.Data[i].Results)
 		if err != nil {
-			return nil, fmt.Errorf("encode json failed, err=%s", err)
+			return nil, fmt.Errorf("encode json failed, err=%w", err)
 		}
 		logData := logs.Data[i]
 		topic := []byte(logData.EventName)
@@ -535,7 +670,7 @@ func (tc *TronClient) GetTransactionByHash(transactionHash string) (*client.Tran
 func (tc *TronClient) ParseEventLog(abiName string, eventLog *client.EventLog) ([]interface{}, error) {
 	event := TronEvent{}
 	if err := json.Unmarshal(eventLog.Data, &event); err != nil {
-		return nil, fmt.Errorf("decode event failed, err=%s", err)
+		return nil, fmt.Errorf("decode event failed, err=%w", err)
 	}
 	results := make([]interface{}, 0, len(event.Results))
 	for i := 0; i < len(event.Results); i++ {
@@ -566,7 +701,7 @@ func (tc *TronClient) AddressFromString(addr string) (ecommon.Address, error) {
 
 	value, err := address.Base58ToAddress(addr)
 	if err != nil {
-		return ecommon.Address{}, fmt.Errorf("invalid address, err=%s", err)
+		return ecommon.Address{}, fmt.Errorf("invalid address, err=%w", err)
 	}
 	return ecommon.HexToAddress(value.Hex()), nil
 }
@@ -580,9 +715,13 @@ func (tc *TronClient) AddressToString(addr ecommon.Address) string {
 }
 
 func (tc *TronClient) ContractAddress(addr ecommon.Address) (bool, error) {
-	code, err := tc.c.GetCode(addr)
+	return tc.ContractAddressContext(context.Background(), addr)
+}
+
+func (tc *TronClient) ContractAddressContext(ctx context.Context, addr ecommon.Address) (bool, error) {
+	code, err := tc.c.GetCodeContext(ctx, addr)
 	if err != nil {
-		return false, fmt.Errorf("get code failed, err=%s", err)
+		return false, fmt.Errorf("get code failed, err=%w", err)
 	}
 	return len(code) > 0, nil
 }
@@ -616,12 +755,20 @@ func (tc *TronClient) NormalizeAddress(addr string) string {
 }
 
 func (tc *TronClient) GetGasPrice() (*big.Int, *big.Int, error) {
-	gasPrice, err := tc.c.GetGasPrice()
+	return tc.GetGasPriceContext(context.Background())
+}
+
+func (tc *TronClient) GetGasPriceContext(ctx context.Context) (*big.Int, *big.Int, error) {
+	gasPrice, err := tc.c.GetGasPriceContext(ctx)
 	return gasPrice, big.NewInt(0), err
 }
 
 func (tc *TronClient) GetSuggestGasPrice() (*big.Int, *big.Int, *big.Int, error) {
-	gasPrice, err := tc.c.GetGasPrice()
+	return tc.GetSuggestGasPriceContext(context.Background())
+}
+
+func (tc *TronClient) GetSuggestGasPriceContext(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
+	gasPrice, err := tc.c.GetGasPriceContext(ctx)
 	return big.NewInt(0), big.NewInt(0), gasPrice, err
 }
 
@@ -632,17 +779,35 @@ func (tc *TronClient) NativeAssetAddress() string {
 func (tc *TronClient) PublicKeyHexToAddress(key string) (string, error) {
 	buffer, err := hex.DecodeString(key)
 	if err != nil {
-		return "", fmt.Errorf("decode public key failed, err=%s", err)
+		return "", fmt.Errorf("decode public key failed, err=%w", err)
 	}
 	pubKey, err := ecrypto.UnmarshalPubkey(buffer)
 	if err != nil {
-		return "", fmt.Errorf("unmarshal public key failed, err=%s", err)
+		return "", fmt.Errorf("unmarshal public key failed, err=%w", err)
 	}
 	return address.PubkeyToAddress(*pubKey).String(), nil
 }
 
+// VerifyMessage checks the message is signed by address with signMessageV2 of TronWeb, which prefixes the message
+// with "\x19TRON Signed Message:\n" and its length, address is in base58 or hex
+func (tc *TronClient) VerifyMessage(address string, message, signature []byte) (bool, error) {
+	addr, err := tc.AddressFromString(address)
+	if err != nil {
+		return false, err
+	}
+	signer, err := client.RecoverHash(client.HashMessage(client.TronMessagePrefix, message), signature)
+	if err != nil {
+		return false, err
+	}
+	return signer == addr, nil
+}
+
 func (tc *TronClient) GetLackedGas(address string, gas uint64, gasPrice *big.Int, txSize uint64) (*big.Int, error) {
-	_, _, err := tc.c.GetAccountResource(address)
+	return tc.GetLackedGasContext(context.Background(), address, gas, gasPrice, txSize)
+}
+
+func (tc *TronClient) GetLackedGasContext(ctx context.Context, address string, gas uint64, gasPrice *big.Int, txSize uint64) (*big.Int, error) {
+	_, _, err := tc.c.GetAccountResourceContext(ctx, address)
 	return nil, err
 }
 
@@ -652,7 +817,12 @@ func (tc *TronClient) NativeAssetDecimals() uint8 {
 
 func (tc *TronClient) GenerateStackTransactionData(from string, resource string, amount *big.Int) ([]byte,
 	[]byte, error) {
-	tx, err := tc.c.TriggerStack(from, resource, amount)
+	return tc.GenerateStackTransactionDataContext(context.Background(), from, resource, amount)
+}
+
+func (tc *TronClient) GenerateStackTransactionDataContext(ctx context.Context, from string, resource string, amount *big.Int) ([]byte,
+	[]byte, error) {
+	tx, err := tc.c.TriggerStackContext(ctx, from, resource, amount)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -660,7 +830,11 @@ func (tc *TronClient) GenerateStackTransactionData(from string, resource string,
 }
 
 func (tc *TronClient) GenerateUnStackTransactionData(from, resource string, amount *big.Int) ([]byte, []byte, error) {
-	tx, err := tc.c.TriggerUnStack(from, resource, amount)
+	return tc.GenerateUnStackTransactionDataContext(context.Background(), from, resource, amount)
+}
+
+func (tc *TronClient) GenerateUnStackTransactionDataContext(ctx context.Context, from, resource string, amount *big.Int) ([]byte, []byte, error) {
+	tx, err := tc.c.TriggerUnStackContext(ctx, from, resource, amount)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -668,7 +842,11 @@ func (tc *TronClient) GenerateUnStackTransactionData(from, resource string, amou
 }
 
 func (tc *TronClient) GetWithdrawUnStackData(from string) ([]byte, []byte, error) {
-	tx, err := tc.c.TriggerWithdrawUnStack(from)
+	return tc.GetWithdrawUnStackDataContext(context.Background(), from)
+}
+
+func (tc *TronClient) GetWithdrawUnStackDataContext(ctx context.Context, from string) ([]byte, []byte, error) {
+	tx, err := tc.c.TriggerWithdrawUnStackContext(ctx, from)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -678,18 +856,23 @@ func (tc *TronClient) GetWithdrawUnStackData(from string) ([]byte, []byte, error
 func (tc *TronClient) getTransactionExtentionData(tx *TransactionExtention) ([]byte, []byte, error) {
 	data, err := json.Marshal(tx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("encode rawdata failed, err=%s", err)
+		return nil, nil, fmt.Errorf("encode rawdata failed, err=%w", err)
 	}
 	hash, err := hex.DecodeString(tx.Txid)
 	if err != nil {
-		return nil, nil, fmt.Errorf("decode txid to hash failed, err=%s", err)
+		return nil, nil, fmt.Errorf("decode txid to hash failed, err=%w", err)
 	}
 	return data, hash, nil
 }
 
 func (tc *TronClient) GenerateDelegateResourceTransactionData(from, to, resource string, amount *big.Int) ([]byte, []byte,
 	error) {
-	tx, err := tc.c.TriggerDelegateResource(from, to, resource, amount)
+	return tc.GenerateDelegateResourceTransactionDataContext(context.Background(), from, to, resource, amount)
+}
+
+func (tc *TronClient) GenerateDelegateResourceTransactionDataContext(ctx context.Context, from, to, resource string, amount *big.Int) ([]byte, []byte,
+	error) {
+	tx, err := tc.c.TriggerDelegateResourceContext(ctx, from, to, resource, amount)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -2,6 +2,7 @@ package tron
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -10,10 +11,13 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"git.bipal.space/shared-lib/blockchain/client"
+	"git.bipal.space/shared-lib/blockchain/replay/replaytest"
 	"github.com/ethereum/go-ethereum/common"
 	ecrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/fbsobreira/gotron-sdk/pkg/address"
@@ -66,6 +70,13 @@ func setup() {
 	json.Unmarshal(content, &dockerInfo)
 }
 
+func TestNewTronClientEndpoints(t *testing.T) {
+	c := tConfig
+	c.Endpoints = c.Endpoints[:1]
+	_, err := NewTronClient(&c)
+	assert.NotNil(t, err, "one endpoint should fail")
+}
+
 func TestPubkeyToAddress(t *testing.T) {
 	pubKey := "0404B604296010A55D40000B798EE8454ECCC1F8900E70B1ADF47C9887625D8BAE3866351A6FA0B5370623268410D33D345F63344121455849C9C28F9389ED9731"
 	pubValue, err := hex.DecodeString(pubKey)
@@ -82,7 +93,7 @@ func TestPubkeyToAddress(t *testing.T) {
 }
 
 func TestDecimalsOf(t *testing.T) {
-	client, err := NewTronClient(&tConfig)
+	client, err := NewTronClient(replaytest.Config(t, tConfig))
 	assert.Nil(t, err, "create client failed")
 	// USDT
 	decimal, err := client.DecimalsOf("TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
@@ -91,7 +102,7 @@ func TestDecimalsOf(t *testing.T) {
 }
 
 func TestBalanceAt(t *testing.T) {
-	client, err := NewTronClient(&tConfig)
+	client, err := NewTronClient(replaytest.Config(t, tConfig))
 	assert.Nil(t, err, "create client failed")
 	// address is magic's address
 	//address := "TR2giB1C897abNrR1bJsuhREcz4oUoGhEG"
@@ -103,7 +114,7 @@ func TestBalanceAt(t *testing.T) {
 }
 
 func TestBalanceOf(t *testing.T) {
-	client, err := NewTronClient(&config)
+	client, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	// address is magic's address
 	address := "TR2giB1C897abNrR1bJsuhREcz4oUoGhEG"
@@ -114,7 +125,7 @@ func TestBalanceOf(t *testing.T) {
 }
 
 func TestSymbolOf(t *testing.T) {
-	client, err := NewTronClient(&config)
+	client, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	// address is magic's address
 	contract := "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
@@ -124,7 +135,7 @@ func TestSymbolOf(t *testing.T) {
 }
 
 func TestTotalSupply(t *testing.T) {
-	client, err := NewTronClient(&config)
+	client, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	// address is magic's address
 	contract := "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
@@ -134,7 +145,7 @@ func TestTotalSupply(t *testing.T) {
 }
 
 func TestSuggestFee(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	to := "TY3ba19r36hcvQVotETd3tH4HDC14Ucha6"
 	td := client.Transaction{
@@ -172,7 +183,7 @@ func TestSignature(t *testing.T) {
 }
 
 func TestCallContract(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	data, err := tclient.GetTransactionDataByABI("totalSupply", trc20ABIName)
 	assert.Nil(t, err, "generate data failed")
@@ -189,7 +200,7 @@ func TestCallContract(t *testing.T) {
 }
 
 func TestCallContractWithData(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	ownerAddr := "TYg7Uh7fG8ZQxRvWRpFziHzWc8YJLX8JtJ"
 	data, err := tclient.GetTransactionDataByABI("balanceOf", trc20ABIName, ownerAddr)
@@ -206,7 +217,7 @@ func TestCallContractWithData(t *testing.T) {
 }
 
 func TestGetGasPrice(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	gasPrice, tipPrice, err := tclient.GetGasPrice()
 	assert.Nil(t, err, "get gas price failed")
@@ -216,7 +227,7 @@ func TestGetGasPrice(t *testing.T) {
 }
 
 func TestABiCall(t *testing.T) {
-	tclient, err := NewTronClient(&tConfig)
+	tclient, err := NewTronClient(replaytest.Config(t, tConfig))
 	assert.Nil(t, err, "create client failed")
 	swapABI, err := ioutil.ReadFile("./swap.abi")
 	assert.Nil(t, err, "read abi failed")
@@ -344,8 +355,8 @@ func TestTransaction(t *testing.T) {
 }
 
 func TestGetOnChainTransaction(t *testing.T) {
-	tclient, err := NewTronClient(&tConfig)
-	//tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, tConfig))
+	//tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	info, err := tclient.GetTransactionByHash("22f671e62356915fdb9df097d8338ae854f9334bf02edc61147f5c991086aba6")
 	assert.Nil(t, err, "get transaction failed")
@@ -359,7 +370,7 @@ func TestGetOnChainTransaction(t *testing.T) {
 }
 
 func TestTransferLog(t *testing.T) {
-	cli, err := NewTronClient(&config)
+	cli, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	info, err := cli.GetTransactionByHash("e3f747b265c39125b91c319526a640e49310b19b072515c39e1616364393a0b1")
 	assert.Equal(t, 1, int(len(info.Logs)), "logs should be 1")
@@ -404,7 +415,7 @@ func TestFunctionSelector(t *testing.T) {
 }
 
 func TestAllowance(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	value, err := tclient.Allowance("TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "TYg7Uh7fG8ZQxRvWRpFziHzWc8YJLX8JtJ", "TYp5ZMYJNSPw8JRmqXRjy8tMVMK1hvDuPe")
 	assert.Nil(t, err, "allowance failed")
@@ -420,7 +431,43 @@ func TestPublicKeyHexToAddress(t *testing.T) {
 }
 
 func TestGetLackedGas(t *testing.T) {
-	tclient, err := NewTronClient(&config)
+	tclient, err := NewTronClient(replaytest.Config(t, config))
 	assert.Nil(t, err, "create client failed")
 	tclient.GetLackedGas("TSFbrBgDwnU41oLowse5cEZsyQM2fU2mAB", 1000, big.NewInt(420), 1024)
 }
+
+func TestContextCancel(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	defer close(block)
+	tclient, err := NewTronClient(&client.ChainConfiguration{
+		ChainName: "Tron",
+		Endpoints: []string{server.URL + "/jsonrpc", server.URL, server.URL},
+	})
+	assert.Nil(t, err, "create client failed")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = tclient.BalanceAtContext(ctx, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.NotNil(t, err, "canceled call should fail")
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error(), "deadline should be propagated")
+}
+
+func TestClientTimeout(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	defer close(block)
+	tclient, err := NewTronClient(&client.ChainConfiguration{
+		ChainName: "Tron",
+		Endpoints: []string{server.URL + "/jsonrpc", server.URL, server.URL},
+		Timeout:   100 * time.Millisecond,
+	})
+	assert.Nil(t, err, "create client failed")
+	_, err = tclient.GetLatestBlockNumber()
+	assert.NotNil(t, err, "call should time out")
+}
@@ -2,6 +2,7 @@ package tron
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -99,6 +100,7 @@ type jsonRPCReponse struct {
 	Error   struct {
 		Code    int    `json:"code,omitempty"`
 		Message string `json:"message,omitempty"`
+		Data    string `json:"data,omitempty"`
 	} `json:"error,omitempty"`
 }
 
@@ -169,82 +171,94 @@ type HTTPClient struct {
 // NewHTTPClient creates the client
 // Endpoint is the node address for http apis
 func NewHTTPClient(Endpoint, FullNode, TronGrid string) *HTTPClient {
-	c := http.Client{}
+	// every client owns its transport, so closing one client doesn't affect the others
+	c := http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
 	return &HTTPClient{client: &c, endPoint: Endpoint, fullnode: FullNode, trongrid: TronGrid}
 }
 
+// Close closes the idle connections kept by the client
+func (c *HTTPClient) Close() {
+	c.client.CloseIdleConnections()
+}
+
 // rpcGet used for json-rpc
-func (c *HTTPClient) rpcGet() ([]byte, error) {
+func (c *HTTPClient) rpcGet(ctx context.Context) ([]byte, error) {
 	url := c.endPoint
-	return c.get(url)
+	return c.get(ctx, url)
 }
 
-func (c *HTTPClient) fullnodeGet(path string) ([]byte, error) {
+func (c *HTTPClient) fullnodeGet(ctx context.Context, path string) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.fullnode, path)
-	return c.get(url)
+	return c.get(ctx, url)
 }
 
-func (c *HTTPClient) gridGet(path string) ([]byte, error) {
+func (c *HTTPClient) gridGet(ctx context.Context, path string) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.trongrid, path)
-	return c.get(url)
+	return c.get(ctx, url)
 }
 
-func (c *HTTPClient) get(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *HTTPClient) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request failed, err=%s", err)
+		return nil, fmt.Errorf("create request failed, err=%w", err)
 	}
 	req.Header.Set("TRON-PRO-API-KEY", c.APIKey)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("call url=%s failed, err=%s", url, err)
+		return nil, transportError(ctx, fmt.Errorf("call url=%s failed, err=%w", url, err))
 	}
 	defer resp.Body.Close()
 	res, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response failed, err=%s", err)
+		return nil, fmt.Errorf("read response failed, err=%w", err)
+	}
+	if err := statusError(url, resp.StatusCode, res); err != nil {
+		return nil, err
 	}
 	return res, nil
 }
 
-func (c *HTTPClient) rpcPost(body interface{}) ([]byte, error) {
+func (c *HTTPClient) rpcPost(ctx context.Context, body interface{}) ([]byte, error) {
 	url := c.endPoint
-	return c.post(url, body)
+	return c.post(ctx, url, body)
 }
 
-func (c *HTTPClient) gridPost(path string, body interface{}) ([]byte, error) {
+func (c *HTTPClient) gridPost(ctx context.Context, path string, body interface{}) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.trongrid, path)
-	return c.post(url, body)
+	return c.post(ctx, url, body)
 }
 
-func (c *HTTPClient) fullnodePost(path string, body interface{}) ([]byte, error) {
+func (c *HTTPClient) fullnodePost(ctx context.Context, path string, body interface{}) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.fullnode, path)
-	return c.post(url, body)
+	return c.post(ctx, url, body)
 }
 
-func (c *HTTPClient) post(url string, body interface{}) ([]byte, error) {
+func (c *HTTPClient) post(ctx context.Context, url string, body interface{}) ([]byte, error) {
 	js, err := json.Marshal(body)
 	if err != nil {
-		return nil, fmt.Errorf("encode json failed, err=%s", err)
+		return nil, fmt.Errorf("encode json failed, err=%w", err)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewReader(js))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(js))
 	if err != nil {
-		return nil, fmt.Errorf("create request failed, err=%s", err)
+		return nil, fmt.Errorf("create request failed, err=%w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("TRON-PRO-API-KEY", c.APIKey)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("call url=%s failed, req=%s, err=%s", url, js, err)
+		return nil, transportError(ctx, fmt.Errorf("call url=%s failed, req=%s, err=%w", url, js, err))
 	}
 	defer resp.Body.Close()
 	res, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response failed, url=%s, req=%s, err=%s", url, js, err)
+		return nil, fmt.Errorf("read response failed, url=%s, req=%s, err=%w", url, js, err)
 	}
 
 	//打印请求相应参数的日志
 	log.Printf("post_request: url=%s, req=%s, res=%s \n", url, js, res)
+	if err := statusError(url, resp.StatusCode, res); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
@@ -256,6 +270,10 @@ type transferJsonRequest struct {
 }
 
 func (c *HTTPClient) TriggerTransfer(from, to string, amount *big.Int) (*TransactionExtention, error) {
+	return c.TriggerTransferContext(context.Background(), from, to, amount)
+}
+
+func (c *HTTPClient) TriggerTransferContext(ctx context.Context, from, to string, amount *big.Int) (*TransactionExtention, error) {
 	//以太坊地址 -> Tron地址
 	if ecommon.IsHexAddress(from) {
 		from = c.convertETHAddress(from)
@@ -273,12 +291,12 @@ func (c *HTTPClient) TriggerTransfer(from, to string, amount *big.Int) (*Transac
 	//地址校验
 	fromAddr, err := address.Base58ToAddress(from)
 	if err != nil {
-		return nil, fmt.Errorf("from address invalid , err=%s", err)
+		return nil, fmt.Errorf("from address invalid , err=%w", err)
 	}
 
 	toAddr, err := address.Base58ToAddress(to)
 	if err != nil {
-		return nil, fmt.Errorf("to address invalid, err=%s", err)
+		return nil, fmt.Errorf("to address invalid, err=%w", err)
 	}
 
 	//若amount为0，则报错返回
@@ -292,9 +310,9 @@ func (c *HTTPClient) TriggerTransfer(from, to string, amount *big.Int) (*Transac
 		Amount:  amount,
 		Visible: true,
 	}
-	response, err := c.fullnodePost("wallet/createtransaction", req)
+	response, err := c.fullnodePost(ctx, "wallet/createtransaction", req)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(response))
@@ -313,10 +331,14 @@ func (c *HTTPClient) TriggerTransfer(from, to string, amount *big.Int) (*Transac
 }
 
 func (c *HTTPClient) GetBlockByLastNumber() (*big.Int, error) {
+	return c.GetBlockByLastNumberContext(context.Background())
+}
+
+func (c *HTTPClient) GetBlockByLastNumberContext(ctx context.Context) (*big.Int, error) {
 	url := "wallet/getblockbylatestnum?num=1"
-	response, err := c.fullnodeGet(url)
+	response, err := c.fullnodeGet(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("get request failed, err=%s", err)
+		return nil, fmt.Errorf("get request failed, err=%w", err)
 	}
 	info := struct {
 		Block []struct {
@@ -330,7 +352,7 @@ func (c *HTTPClient) GetBlockByLastNumber() (*big.Int, error) {
 		} `json:"block"`
 	}{}
 	if err := json.Unmarshal(response, &info); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	if len(info.Block) == 0 {
 		return nil, fmt.Errorf("parse result failed, js=%s", string(response))
@@ -339,13 +361,17 @@ func (c *HTTPClient) GetBlockByLastNumber() (*big.Int, error) {
 }
 
 func (c *HTTPClient) EthCall(from, to string, value *big.Int, data []byte) ([]byte, error) {
+	return c.EthCallContext(context.Background(), from, to, value, data)
+}
+
+func (c *HTTPClient) EthCallContext(ctx context.Context, from, to string, value *big.Int, data []byte) ([]byte, error) {
 	fromAddr, err := address.Base58ToAddress(from)
 	if err != nil {
 		fromAddr = address.Address{}
 	}
 	toAddr, err := address.Base58ToAddress(to)
 	if err != nil {
-		return nil, fmt.Errorf("wrong to address, err=%s", err)
+		return nil, fmt.Errorf("wrong to address, err=%w", err)
 	}
 	strval := "0"
 	if value != nil {
@@ -355,16 +381,16 @@ func (c *HTTPClient) EthCall(from, to string, value *big.Int, data []byte) ([]by
 	jrpc := jsonRPCRequest{}
 	initJsonRequest("eth_call", &jrpc)
 	jrpc.Params = []interface{}{request, "latest"}
-	result, err := c.rpcPost(&jrpc)
+	result, err := c.rpcPost(ctx, &jrpc)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	resp := jsonRPCReponse{}
 	if err := json.Unmarshal(result, &resp); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	if resp.Error.Code != 0 || resp.Error.Message != "" {
-		return nil, fmt.Errorf("eth_call failed, code=%d, err=%s", resp.Error.Code, resp.Error.Message)
+		return nil, fmt.Errorf("eth_call failed, err=%w", jsonRPCError(resp.Error.Code, resp.Error.Message, resp.Error.Data))
 	}
 	if strings.HasPrefix(resp.Result, "0x") {
 		return hex.DecodeString(resp.Result[2:])
@@ -376,15 +402,22 @@ func (c *HTTPClient) EthCall(from, to string, value *big.Int, data []byte) ([]by
 // energy price in sun
 // Can use GetGasPrice method, since the price is the same
 func (c *HTTPClient) GetEnergyPrice() (uint64, error) {
-	response, err := c.fullnodeGet("wallet/getenergyprices")
+	return c.GetEnergyPriceContext(context.Background())
+}
+
+// GetEnergyPriceContext calls https://api.shasta.trongrid.io/wallet/getenergyprices and gets the latest
+// energy price in sun
+// Can use GetGasPrice method, since the price is the same
+func (c *HTTPClient) GetEnergyPriceContext(ctx context.Context) (uint64, error) {
+	response, err := c.fullnodeGet(ctx, "wallet/getenergyprices")
 	if err != nil {
-		return 0, fmt.Errorf("get request failed, err=%s", err)
+		return 0, fmt.Errorf("get request failed, err=%w", err)
 	}
 	info := struct {
 		Prices string `json:"prices"`
 	}{}
 	if err := json.Unmarshal(response, &info); err != nil {
-		return 0, fmt.Errorf("parse json failed, err=%s", err)
+		return 0, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	// prices are in the format of "timestamp:price,timestamp:price,..."
 	prices := strings.Split(info.Prices, ",")
@@ -411,6 +444,11 @@ func (c *HTTPClient) GetEnergyPrice() (uint64, error) {
 
 // DeployContract will call deploycontract api, this api will generate the unsigned transaction
 func (c *HTTPClient) DeployContract(strABI, strBIN, owner, name string) (*TransactionExtention, string, error) {
+	return c.DeployContractContext(context.Background(), strABI, strBIN, owner, name)
+}
+
+// DeployContractContext will call deploycontract api, this api will generate the unsigned transaction
+func (c *HTTPClient) DeployContractContext(ctx context.Context, strABI, strBIN, owner, name string) (*TransactionExtention, string, error) {
 	ownerAddr, err := address.Base58ToAddress(owner)
 	if err != nil {
 		return nil, "", fmt.Errorf("owner address is not base58")
@@ -426,15 +464,15 @@ func (c *HTTPClient) DeployContract(strABI, strBIN, owner, name string) (*Transa
 		Parameter:               "",
 		OriginEnergyLimit:       1000000000,
 	}
-	response, err := c.fullnodePost("wallet/deploycontract", req)
+	response, err := c.fullnodePost(ctx, "wallet/deploycontract", req)
 	if err != nil {
-		return nil, "", fmt.Errorf("http request failed, err=%s", err)
+		return nil, "", fmt.Errorf("http request failed, err=%w", err)
 	}
 	resp := deployResponse{}
 	d := json.NewDecoder(bytes.NewReader(response))
 	d.UseNumber()
 	if err := d.Decode(&resp); err != nil {
-		return nil, "", fmt.Errorf("parse json failed, err=%s", err)
+		return nil, "", fmt.Errorf("parse json failed, err=%w", err)
 	}
 	trans := TronTransaction{
 		RawData:         resp.RawData,
@@ -455,7 +493,7 @@ func (c *HTTPClient) convertETHAddress(addr string) string {
 	return address.HexToAddress(s).String()
 }
 
-func (c *HTTPClient) triggerConstantContractResult(parameter, selector, contract, from string) (rest *walletResult, err error) {
+func (c *HTTPClient) triggerConstantContractResult(ctx context.Context, parameter, selector, contract, from string) (rest *walletResult, err error) {
 	if common.Has0xPrefix(parameter) {
 		parameter = parameter[2:]
 	}
@@ -484,24 +522,25 @@ func (c *HTTPClient) triggerConstantContractResult(parameter, selector, contract
 		Parameter:        parameter,
 		Visible:          true,
 	}
-	response, err := c.fullnodePost("wallet/triggerconstantcontract", req)
+	response, err := c.fullnodePost(ctx, "wallet/triggerconstantcontract", req)
 	if err != nil {
-		return nil, fmt.Errorf("call wallet/triggerconstantcontract failed, err=%s", err)
+		return nil, fmt.Errorf("call wallet/triggerconstantcontract failed, err=%w", err)
 	}
 
 	result := &walletResult{}
 	if err := json.Unmarshal(response, &result); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 
 	if !result.Result.Ok {
-		return nil, fmt.Errorf("call wallet/triggerconstantcontract failed, result[%+v]", result)
+		err := walletError(result.Result.Code, result.Result.Message, result.ConstantResult)
+		return nil, fmt.Errorf("call wallet/triggerconstantcontract failed, err=%w", err)
 	}
 	return result, nil
 }
 
-func (c *HTTPClient) triggerConstantContract(parameter, selector, contract, from string) (string, error) {
-	result, err := c.triggerConstantContractResult(parameter, selector, contract, from)
+func (c *HTTPClient) triggerConstantContract(ctx context.Context, parameter, selector, contract, from string) (string, error) {
+	result, err := c.triggerConstantContractResult(ctx, parameter, selector, contract, from)
 	if err != nil {
 		return "", err
 	}
@@ -514,6 +553,11 @@ func (c *HTTPClient) triggerConstantContract(parameter, selector, contract, from
 
 // GetAccountResource returns the resource of this account
 func (c *HTTPClient) GetAccountResource(address string) (*big.Int, *big.Int, error) {
+	return c.GetAccountResourceContext(context.Background(), address)
+}
+
+// GetAccountResourceContext returns the resource of this account
+func (c *HTTPClient) GetAccountResourceContext(ctx context.Context, address string) (*big.Int, *big.Int, error) {
 	req := struct {
 		Address string `json:"address"`
 		Visible bool   `json:"visible"`
@@ -521,9 +565,9 @@ func (c *HTTPClient) GetAccountResource(address string) (*big.Int, *big.Int, err
 		Address: address,
 		Visible: true,
 	}
-	response, err := c.fullnodePost("wallet/getaccountresource", req)
+	response, err := c.fullnodePost(ctx, "wallet/getaccountresource", req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	resp := struct {
 		FreeNetUsed  uint64 `json:"freeNetUsed"`
@@ -532,7 +576,7 @@ func (c *HTTPClient) GetAccountResource(address string) (*big.Int, *big.Int, err
 		EnergyLimit  uint64 `json:"EnergyLimit"`
 	}{}
 	if err := json.Unmarshal(response, &resp); err != nil {
-		return nil, nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	netLeft := big.NewInt(int64(resp.FreeNetLimit - resp.FreeNetUsed))
 	if netLeft.Cmp(big.NewInt(0)) < 0 {
@@ -545,7 +589,7 @@ func (c *HTTPClient) GetAccountResource(address string) (*big.Int, *big.Int, err
 	return netLeft, energyLeft, nil
 }
 
-func (c *HTTPClient) triggerSmartContract(data []byte, selector, contract, from string, feeLimit *big.Int) ([]byte, error) {
+func (c *HTTPClient) triggerSmartContract(ctx context.Context, data []byte, selector, contract, from string, feeLimit *big.Int) ([]byte, error) {
 	if len(data) > 4 {
 		data = data[4:]
 	}
@@ -576,21 +620,28 @@ func (c *HTTPClient) triggerSmartContract(data []byte, selector, contract, from
 		Visible:          true,
 		FeeLimit:         feeLimit,
 	}
-	return c.fullnodePost("wallet/triggersmartcontract", req)
+	return c.fullnodePost(ctx, "wallet/triggersmartcontract", req)
 }
 
 // TriggerSmartContract calls TriggerSmartContract
 // the details of this api can be found here: https://developers.tron.network/reference/triggersmartcontract
 // This api will not run the contract, it just returns the transactions generated, but unsigned
 func (c *HTTPClient) TriggerSmartContract(contract, from string, data []byte, feeLimit *big.Int) (*TransactionExtention, error) {
+	return c.TriggerSmartContractContext(context.Background(), contract, from, data, feeLimit)
+}
+
+// TriggerSmartContractContext calls TriggerSmartContract
+// the details of this api can be found here: https://developers.tron.network/reference/triggersmartcontract
+// This api will not run the contract, it just returns the transactions generated, but unsigned
+func (c *HTTPClient) TriggerSmartContractContext(ctx context.Context, contract, from string, data []byte, feeLimit *big.Int) (*TransactionExtention, error) {
 	method, err := ethevent.GetMethodByData(data)
 	if err != nil {
-		return nil, fmt.Errorf("get method by data failed, err=%s", err)
+		return nil, fmt.Errorf("get method by data failed, err=%w", err)
 	}
 
-	response, err := c.triggerSmartContract(data, method.Sig, contract, from, feeLimit)
+	response, err := c.triggerSmartContract(ctx, data, method.Sig, contract, from, feeLimit)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	tx := TransactionExtention{}
 	d := json.NewDecoder(bytes.NewReader(response))
@@ -607,60 +658,80 @@ func (c *HTTPClient) TriggerSmartContract(contract, from string, data []byte, fe
 
 // GetTransactionByID returns the transaction information, such as from, to, calldata
 func (c *HTTPClient) GetTransactionByID(txHash string) (*TronTransaction, error) {
+	return c.GetTransactionByIDContext(context.Background(), txHash)
+}
+
+// GetTransactionByIDContext returns the transaction information, such as from, to, calldata
+func (c *HTTPClient) GetTransactionByIDContext(ctx context.Context, txHash string) (*TronTransaction, error) {
 	req := walletTransactionRequest{
 		Value: txHash,
 	}
-	response, err := c.fullnodePost("wallet/gettransactionbyid", req)
+	response, err := c.fullnodePost(ctx, "wallet/gettransactionbyid", req)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(response))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	return &tx, nil
 }
 
 // GetTransactionInfo returns the transaction receipt and status
 func (c *HTTPClient) GetTransactionInfoByID(txHash string) (*TransactionInfo, error) {
+	return c.GetTransactionInfoByIDContext(context.Background(), txHash)
+}
+
+// GetTransactionInfo returns the transaction receipt and status
+func (c *HTTPClient) GetTransactionInfoByIDContext(ctx context.Context, txHash string) (*TransactionInfo, error) {
 	req := walletTransactionRequest{
 		Value: txHash,
 	}
-	response, err := c.fullnodePost("wallet/gettransactioninfobyid", req)
+	response, err := c.fullnodePost(ctx, "wallet/gettransactioninfobyid", req)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	tx := TransactionInfo{}
 	d := json.NewDecoder(bytes.NewReader(response))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	return &tx, nil
 }
 
 // GetTransactionEventsByID returns the events log generated by a transaction
 func (c *HTTPClient) GetTransactionEventsByID(txHash string) (*EventLogs, error) {
+	return c.GetTransactionEventsByIDContext(context.Background(), txHash)
+}
+
+// GetTransactionEventsByIDContext returns the events log generated by a transaction
+func (c *HTTPClient) GetTransactionEventsByIDContext(ctx context.Context, txHash string) (*EventLogs, error) {
 	url := fmt.Sprintf("v1/transactions/%s/events", txHash)
-	response, err := c.gridGet(url)
+	response, err := c.gridGet(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get events, tx=%s, err=%s", txHash, err)
+		return nil, fmt.Errorf("failed to get events, tx=%s, err=%w", txHash, err)
 	}
 	logs := EventLogs{}
 	if err := json.Unmarshal(response, &logs); err != nil {
-		return nil, fmt.Errorf("failed to parse events, err=%s", err)
+		return nil, fmt.Errorf("failed to parse events, err=%w", err)
 	}
 	return &logs, nil
 }
 
 // TotalSupplyOf implements totalSupply of an TRC20 contract
 func (c *HTTPClient) TotalSupplyOf(contract string) (*big.Int, error) {
+	return c.TotalSupplyOfContext(context.Background(), contract)
+}
+
+// TotalSupplyOfContext implements totalSupply of an TRC20 contract
+func (c *HTTPClient) TotalSupplyOfContext(ctx context.Context, contract string) (*big.Int, error) {
 	selector := "totalSupply()"
-	response, err := c.triggerConstantContract("", selector, contract, emptyAddressBase58)
+	response, err := c.triggerConstantContract(ctx, "", selector, contract, emptyAddressBase58)
 	if err != nil {
-		return nil, fmt.Errorf("triggerconstantcontract failed, contract=%s, selecotr=%s, err=%s",
+		return nil, fmt.Errorf("triggerconstantcontract failed, contract=%s, selector=%s, err=%w",
 			contract, selector, err)
 	}
 	return extractNumber(response)
@@ -668,60 +739,87 @@ func (c *HTTPClient) TotalSupplyOf(contract string) (*big.Int, error) {
 
 // DecimalsOf calls decimals of TRC20
 func (c *HTTPClient) DecimalsOf(contract string) (*big.Int, error) {
+	return c.DecimalsOfContext(context.Background(), contract)
+}
+
+// DecimalsOfContext calls decimals of TRC20
+func (c *HTTPClient) DecimalsOfContext(ctx context.Context, contract string) (*big.Int, error) {
 	selector := "decimals()"
-	response, err := c.triggerConstantContract("", selector, contract, emptyAddressBase58)
+	response, err := c.triggerConstantContract(ctx, "", selector, contract, emptyAddressBase58)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	return extractNumber(response)
 }
 
 // SymbolOf calls symbol of TRC20
 func (c *HTTPClient) SymbolOf(contract string) (string, error) {
+	return c.SymbolOfContext(context.Background(), contract)
+}
+
+// SymbolOfContext calls symbol of TRC20
+func (c *HTTPClient) SymbolOfContext(ctx context.Context, contract string) (string, error) {
 	selector := "symbol()"
-	response, err := c.triggerConstantContract("", selector, contract, emptyAddressBase58)
+	response, err := c.triggerConstantContract(ctx, "", selector, contract, emptyAddressBase58)
 	if err != nil {
-		return "", fmt.Errorf("http request failed, err=%s", err)
+		return "", fmt.Errorf("http request failed, err=%w", err)
 	}
 	return extractString(response)
 }
 
 // BroadCastTransaction broads the signed transaction to tron
 func (c *HTTPClient) BroadCastTransaction(transaction *TronTransaction) error {
-	r, err := c.fullnodePost("wallet/broadcasttransaction", transaction)
+	return c.BroadCastTransactionContext(context.Background(), transaction)
+}
+
+// BroadCastTransactionContext broads the signed transaction to tron
+func (c *HTTPClient) BroadCastTransactionContext(ctx context.Context, transaction *TronTransaction) error {
+	r, err := c.fullnodePost(ctx, "wallet/broadcasttransaction", transaction)
 	if err != nil {
-		return fmt.Errorf("http request failed, err=%s", err)
+		return fmt.Errorf("http request failed, err=%w", err)
 	}
 	type broadcastResult struct {
-		Result bool   `json:"result"`
-		Txid   string `json:"txid"`
+		Result  bool   `json:"result"`
+		Txid    string `json:"txid"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
 	}
 	var result broadcastResult
 	if err := json.Unmarshal(r, &result); err != nil {
-		return fmt.Errorf("parse json result failed, json=%s, err=%s", string(r), err)
+		return fmt.Errorf("parse json result failed, json=%s, err=%w", string(r), err)
 	}
 	if !result.Result {
-		return fmt.Errorf("result failed, json=%s", string(r))
+		return fmt.Errorf("result failed, json=%s, err=%w", string(r), walletError(result.Code, result.Message, nil))
 	}
 	return nil
 }
 
 // BalanceOf calls balanceOf of TRC20
 func (c *HTTPClient) BalanceOf(contract, addr, body string) (*big.Int, error) {
+	return c.BalanceOfContext(context.Background(), contract, addr, body)
+}
+
+// BalanceOfContext calls balanceOf of TRC20
+func (c *HTTPClient) BalanceOfContext(ctx context.Context, contract, addr, body string) (*big.Int, error) {
 	selector := "balanceOf(address)"
-	response, err := c.triggerConstantContract(body, selector, contract, addr)
+	response, err := c.triggerConstantContract(ctx, body, selector, contract, addr)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	balance, err := hex2UInt(response)
 	if err != nil {
-		return nil, fmt.Errorf("parse balance failed, err=%s", err)
+		return nil, fmt.Errorf("parse balance failed, err=%w", err)
 	}
 	return new(big.Int).SetUint64(balance), nil
 }
 
 // BalanceAt returns the trx of an address
 func (c *HTTPClient) BalaceAt(addr string) (*big.Int, error) {
+	return c.BalaceAtContext(context.Background(), addr)
+}
+
+// BalanceAt returns the trx of an address
+func (c *HTTPClient) BalaceAtContext(ctx context.Context, addr string) (*big.Int, error) {
 	addrHex, err := address.Base58ToAddress(addr)
 	if err != nil {
 		return nil, fmt.Errorf("addr is not base58")
@@ -730,20 +828,20 @@ func (c *HTTPClient) BalaceAt(addr string) (*big.Int, error) {
 	initJsonRequest("eth_getBalance", &jrpc)
 	jrpc.Params = []interface{}{addrHex.Hex(), "latest"}
 
-	body, err := c.rpcPost(&jrpc)
+	body, err := c.rpcPost(ctx, &jrpc)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	type balanceResult struct {
 		Result string `json:"result"`
 	}
 	result := balanceResult{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	balance, err := hex2UInt(result.Result)
 	if err != nil {
-		return nil, fmt.Errorf("parse balance failed, err=%s", err)
+		return nil, fmt.Errorf("parse balance failed, err=%w", err)
 	}
 	return new(big.Int).SetUint64(balance), nil
 }
@@ -751,6 +849,12 @@ func (c *HTTPClient) BalaceAt(addr string) (*big.Int, error) {
 // EstimateGas calls eth_estimateGas api
 // This api is not used for now, we just use TriggerConstantContract to get the estimated energy
 func (c *HTTPClient) EstimateGas(from, to string, hexValue string, data []byte) (*big.Int, error) {
+	return c.EstimateGasContext(context.Background(), from, to, hexValue, data)
+}
+
+// EstimateGasContext calls eth_estimateGas api
+// This api is not used for now, we just use TriggerConstantContract to get the estimated energy
+func (c *HTTPClient) EstimateGasContext(ctx context.Context, from, to string, hexValue string, data []byte) (*big.Int, error) {
 	fromAddr, err := address.Base58ToAddress(from)
 	if err != nil {
 		return nil, fmt.Errorf("from address not base58")
@@ -763,9 +867,9 @@ func (c *HTTPClient) EstimateGas(from, to string, hexValue string, data []byte)
 	jrpc := jsonRPCRequest{}
 	initJsonRequest("eth_estimateGas", &jrpc)
 	jrpc.Params = []interface{}{request}
-	body, err := c.rpcPost(&jrpc)
+	body, err := c.rpcPost(ctx, &jrpc)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	type gasResult struct {
 		Result  string `json:"result"`
@@ -774,33 +878,37 @@ func (c *HTTPClient) EstimateGas(from, to string, hexValue string, data []byte)
 	}
 	response := gasResult{}
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	gas, err := hex2UInt(response.Result)
 	if err != nil {
-		return nil, fmt.Errorf("parse gas failed, err=%s", err)
+		return nil, fmt.Errorf("parse gas failed, err=%w", err)
 	}
 	return new(big.Int).SetUint64(gas), nil
 }
 
 func (c *HTTPClient) GetGasPrice() (*big.Int, error) {
+	return c.GetGasPriceContext(context.Background())
+}
+
+func (c *HTTPClient) GetGasPriceContext(ctx context.Context) (*big.Int, error) {
 	jrpc := jsonRPCRequest{}
 	initJsonRequest("eth_gasPrice", &jrpc)
 	jrpc.Params = []interface{}{}
-	body, err := c.rpcPost(&jrpc)
+	body, err := c.rpcPost(ctx, &jrpc)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	type gasResult struct {
 		Result string `json:"result"`
 	}
 	response := gasResult{}
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 	gas, err := hex2UInt(response.Result)
 	if err != nil {
-		return nil, fmt.Errorf("parse gas failed, err=%s", err)
+		return nil, fmt.Errorf("parse gas failed, err=%w", err)
 	}
 	return new(big.Int).SetUint64(gas), nil
 }
@@ -821,28 +929,37 @@ type jsonrpcMessage struct {
 }
 
 func (c *HTTPClient) GetCode(addr ecommon.Address) (hexutil.Bytes, error) {
+	return c.GetCodeContext(context.Background(), addr)
+}
+
+func (c *HTTPClient) GetCodeContext(ctx context.Context, addr ecommon.Address) (hexutil.Bytes, error) {
 	jrpc := jsonRPCRequest{}
 	initJsonRequest("eth_getCode", &jrpc)
 	jrpc.Params = []interface{}{addr.Hex(), "latest"}
-	body, err := c.rpcPost(&jrpc)
+	body, err := c.rpcPost(ctx, &jrpc)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed, err=%s", err)
+		return nil, fmt.Errorf("http request failed, err=%w", err)
 	}
 	response := jsonrpcMessage{}
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("parse json failed, err=%s", err)
+		return nil, fmt.Errorf("parse json failed, err=%w", err)
 	}
 
 	var result hexutil.Bytes
 	err = json.Unmarshal(response.Result, &result)
 	if err != nil {
-		return nil, fmt.Errorf("parse result failed, err=%s", err)
+		return nil, fmt.Errorf("parse result failed, err=%w", err)
 	}
 	return result, nil
 }
 
 // TriggerStack generate a transaction to freeze trx
 func (c *HTTPClient) TriggerStack(from string, resource string, amount *big.Int) (*TransactionExtention, error) {
+	return c.TriggerStackContext(context.Background(), from, resource, amount)
+}
+
+// TriggerStackContext generate a transaction to freeze trx
+func (c *HTTPClient) TriggerStackContext(ctx context.Context, from string, resource string, amount *big.Int) (*TransactionExtention, error) {
 	type jsonRequest struct {
 		From     string   `json:"owner_address"`
 		Amount   *big.Int `json:"frozen_balance"`
@@ -853,15 +970,15 @@ func (c *HTTPClient) TriggerStack(from string, resource string, amount *big.Int)
 		return nil, fmt.Errorf("from address not base58")
 	}
 	req := jsonRequest{From: fromAddr.Hex()[2:], Amount: amount, Resource: resource}
-	resp, err := c.post("wallet/freezebalancev2", req)
+	resp, err := c.post(ctx, "wallet/freezebalancev2", req)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(resp))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("decode json failed, err=%s", err)
+		return nil, fmt.Errorf("decode json failed, err=%w", err)
 	}
 	if tx.Txid == "" {
 		return nil, fmt.Errorf("parse result failed, js=%s", string(resp))
@@ -873,6 +990,11 @@ func (c *HTTPClient) TriggerStack(from string, resource string, amount *big.Int)
 
 // TriggerUnStack generate a transaction to unfreeze trx
 func (c *HTTPClient) TriggerUnStack(from string, resource string, amount *big.Int) (*TransactionExtention, error) {
+	return c.TriggerUnStackContext(context.Background(), from, resource, amount)
+}
+
+// TriggerUnStackContext generate a transaction to unfreeze trx
+func (c *HTTPClient) TriggerUnStackContext(ctx context.Context, from string, resource string, amount *big.Int) (*TransactionExtention, error) {
 	type jsonRequest struct {
 		From     string   `json:"owner_address"`
 		Amount   *big.Int `json:"unfreeze_balance"`
@@ -883,15 +1005,15 @@ func (c *HTTPClient) TriggerUnStack(from string, resource string, amount *big.In
 		return nil, fmt.Errorf("from address not base58")
 	}
 	req := jsonRequest{From: fromAddr.Hex()[2:], Amount: amount, Resource: resource}
-	resp, err := c.post("wallet/unfreezebalancev2", req)
+	resp, err := c.post(ctx, "wallet/unfreezebalancev2", req)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(resp))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("decode json failed, err=%s", err)
+		return nil, fmt.Errorf("decode json failed, err=%w", err)
 	}
 	if tx.Txid == "" {
 		return nil, fmt.Errorf("parse result failed, js=%s", string(resp))
@@ -903,6 +1025,11 @@ func (c *HTTPClient) TriggerUnStack(from string, resource string, amount *big.In
 
 // TriggerWithdrawUnStack generate a transaction to withdraw unfrozen trx
 func (c *HTTPClient) TriggerWithdrawUnStack(from string) (*TransactionExtention, error) {
+	return c.TriggerWithdrawUnStackContext(context.Background(), from)
+}
+
+// TriggerWithdrawUnStackContext generate a transaction to withdraw unfrozen trx
+func (c *HTTPClient) TriggerWithdrawUnStackContext(ctx context.Context, from string) (*TransactionExtention, error) {
 	type jsonRequest struct {
 		From string `json:"owner_address"`
 	}
@@ -911,15 +1038,15 @@ func (c *HTTPClient) TriggerWithdrawUnStack(from string) (*TransactionExtention,
 		return nil, fmt.Errorf("from address not base58")
 	}
 	req := jsonRequest{From: fromAddr.Hex()[2:]}
-	resp, err := c.post("wallet/withdrawexpireunfreeze", req)
+	resp, err := c.post(ctx, "wallet/withdrawexpireunfreeze", req)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(resp))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("decode json failed, err=%s", err)
+		return nil, fmt.Errorf("decode json failed, err=%w", err)
 	}
 	if tx.Txid == "" {
 		return nil, fmt.Errorf("parse result failed, js=%s", string(resp))
@@ -930,6 +1057,10 @@ func (c *HTTPClient) TriggerWithdrawUnStack(from string) (*TransactionExtention,
 }
 
 func (c *HTTPClient) TriggerDelegateResource(from string, to string, resource string, amount *big.Int) (*TransactionExtention, error) {
+	return c.TriggerDelegateResourceContext(context.Background(), from, to, resource, amount)
+}
+
+func (c *HTTPClient) TriggerDelegateResourceContext(ctx context.Context, from string, to string, resource string, amount *big.Int) (*TransactionExtention, error) {
 	type jsonRequest struct {
 		From     string   `json:"owner_address"`
 		To       string   `json:"receiver_address"`
@@ -945,15 +1076,15 @@ func (c *HTTPClient) TriggerDelegateResource(from string, to string, resource st
 		return nil, fmt.Errorf("to address not base58")
 	}
 	req := jsonRequest{From: fromAddr.Hex()[2:], To: toAddr.Hex()[2:], Amount: amount, Resource: resource}
-	resp, err := c.post("wallet/delegateresource", req)
+	resp, err := c.post(ctx, "wallet/delegateresource", req)
 	if err != nil {
-		return nil, fmt.Errorf("post request failed, err=%s", err)
+		return nil, fmt.Errorf("post request failed, err=%w", err)
 	}
 	tx := TronTransaction{}
 	d := json.NewDecoder(bytes.NewReader(resp))
 	d.UseNumber()
 	if err := d.Decode(&tx); err != nil {
-		return nil, fmt.Errorf("decode json failed, err=%s", err)
+		return nil, fmt.Errorf("decode json failed, err=%w", err)
 	}
 	if tx.Txid == "" {
 		return nil, fmt.Errorf("parse result failed, js=%s", string(resp))